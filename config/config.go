@@ -0,0 +1,161 @@
+// Package config loads the configuration of sync-bot.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Clone configures how a repository is cloned.
+type Clone struct {
+	// Mode is one of "full", "blobless" or "shallow", default is "full".
+	Mode string `yaml:"mode"`
+	// Depth is the initial depth of a shallow clone.
+	Depth int `yaml:"depth"`
+}
+
+// Transport configures how repositories are accessed.
+type Transport struct {
+	// Protocol is "https" or "ssh", default is "https".
+	Protocol string `yaml:"protocol"`
+	// Key is the path of the file containing the private SSH key.
+	Key string `yaml:"key"`
+	// KnownHosts is the path of the known_hosts file, SSH is refused without
+	// it unless TrustOnFirstUse.
+	KnownHosts string `yaml:"known_hosts"`
+	// TrustOnFirstUse trusts the host keys on first use if KnownHosts is
+	// empty, the first connection is open to man-in-the-middle attacks.
+	TrustOnFirstUse bool `yaml:"trust_on_first_use"`
+}
+
+// Repo configures a repository, or all repositories of an owner.
+type Repo struct {
+	Clone Clone `yaml:"clone"`
+	// Transport overrides the transport of the host.
+	Transport Transport `yaml:"transport"`
+	// Language is the language of the replies of sync-bot, "en" or "zh",
+	// default is "zh".
+	Language string `yaml:"language"`
+	// RepoTemplates enables the templates in the .sync-bot/templates
+	// directory of the repository, which override the templates of sync-bot.
+	RepoTemplates bool `yaml:"repo_templates"`
+	// Macros are the rpm macros defined before parsing spec files, such as
+	// the dist tag "dist".
+	Macros map[string]string `yaml:"macros"`
+	// Spec is the path of the spec file in the repository, like
+	// "SPECS/foo.spec". The spec file is searched for if it is empty.
+	Spec string `yaml:"spec"`
+	// UnionSync synchronizes to the branches of all /sync commands on a pull
+	// request, rather than the ones of the last command.
+	UnionSync bool `yaml:"union_sync"`
+	// Aliases are named lists of branches or branch patterns, which are
+	// referred to by "@name" in /sync commands.
+	Aliases map[string][]string `yaml:"aliases"`
+}
+
+// Host configures a git host.
+type Host struct {
+	Transport Transport `yaml:"transport"`
+}
+
+// Config is the configuration of sync-bot.
+type Config struct {
+	// Hosts is keyed by the git host, such as "gitee.com".
+	Hosts map[string]Host `yaml:"hosts"`
+	// Repos is keyed by "owner/repo", or by "owner" for all repos of the owner.
+	Repos map[string]Repo `yaml:"repos"`
+}
+
+// Load reads the configuration from a YAML file.
+// An empty configuration is returned if the file does not exist.
+func Load(path string) (*Config, error) {
+	c := &Config{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validate rejects unknown values, which would otherwise fall back to the
+// defaults silently.
+func (c *Config) validate() error {
+	for name, h := range c.Hosts {
+		if err := h.Transport.validate(); err != nil {
+			return fmt.Errorf("hosts %s: %w", name, err)
+		}
+	}
+	for name, r := range c.Repos {
+		switch r.Clone.Mode {
+		case "", "full", "blobless", "shallow":
+		default:
+			return fmt.Errorf("repos %s: unknown clone mode %q", name, r.Clone.Mode)
+		}
+		if err := r.Transport.validate(); err != nil {
+			return fmt.Errorf("repos %s: %w", name, err)
+		}
+		switch r.Language {
+		case "", "en", "zh":
+		default:
+			return fmt.Errorf("repos %s: unknown language %q", name, r.Language)
+		}
+	}
+	return nil
+}
+
+func (t Transport) validate() error {
+	switch t.Protocol {
+	case "", "https", "ssh":
+		return nil
+	default:
+		return fmt.Errorf("unknown transport protocol %q", t.Protocol)
+	}
+}
+
+// Repo returns the configuration of owner/repo, which falls back to the
+// configuration of owner.
+func (c *Config) Repo(owner, repo string) Repo {
+	if r, ok := c.Repos[owner+"/"+repo]; ok {
+		return r
+	}
+	return c.Repos[owner]
+}
+
+// Transport returns the transport of owner/repo on host. The transport of a
+// repo, or of its owner, overrides the transport of the host.
+func (c *Config) Transport(host, owner, repo string) Transport {
+	if t := c.Repo(owner, repo).Transport; t.Protocol != "" {
+		return t
+	}
+	return c.Hosts[host].Transport
+}
+
+// Secrets returns the paths of the secrets referred to by the configuration.
+func (c *Config) Secrets() []string {
+	var paths []string
+	add := func(t Transport) {
+		if t.Key != "" {
+			paths = append(paths, t.Key)
+		}
+	}
+	for _, h := range c.Hosts {
+		add(h.Transport)
+	}
+	for _, r := range c.Repos {
+		add(r.Transport)
+	}
+	sort.Strings(paths)
+	return paths
+}
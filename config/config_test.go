@@ -0,0 +1,126 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-bot.yaml")
+	data := `
+repos:
+  openeuler:
+    clone:
+      mode: blobless
+  openeuler/kernel:
+    clone:
+      mode: shallow
+      depth: 100
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		owner string
+		repo  string
+		want  Repo
+	}{
+		{"repo", "openeuler", "kernel", Repo{Clone: Clone{Mode: "shallow", Depth: 100}}},
+		{"owner", "openeuler", "docs", Repo{Clone: Clone{Mode: "blobless"}}},
+		{"not configured", "src-openeuler", "gcc", Repo{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Repo(tt.owner, tt.repo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Repo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync-bot.yaml")
+	data := `
+hosts:
+  gitee.com:
+    transport:
+      protocol: ssh
+      key: /etc/sync-bot/id_rsa
+      known_hosts: /etc/sync-bot/known_hosts
+repos:
+  openeuler/kernel:
+    transport:
+      protocol: https
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	ssh := Transport{Protocol: "ssh", Key: "/etc/sync-bot/id_rsa", KnownHosts: "/etc/sync-bot/known_hosts"}
+	tests := []struct {
+		name  string
+		host  string
+		owner string
+		repo  string
+		want  Transport
+	}{
+		{"host", "gitee.com", "openeuler", "docs", ssh},
+		{"repo", "gitee.com", "openeuler", "kernel", Transport{Protocol: "https"}},
+		{"not configured", "github.com", "openeuler", "docs", Transport{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Transport(tt.host, tt.owner, tt.repo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Transport() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got, want := c.Secrets(), []string{"/etc/sync-bot/id_rsa"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Secrets() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"clone mode", "repos:\n  openeuler:\n    clone:\n      mode: partial\n"},
+		{"repo protocol", "repos:\n  openeuler:\n    transport:\n      protocol: git\n"},
+		{"host protocol", "hosts:\n  gitee.com:\n    transport:\n      protocol: SSH\n"},
+		{"language", "repos:\n  openeuler/kernel:\n    language: cn\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sync-bot.yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Errorf("Load() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadNotExist(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "not-exist.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := c.Repo("owner", "repo"); !reflect.DeepEqual(got, Repo{}) {
+		t.Errorf("Repo() = %v, want empty", got)
+	}
+}
@@ -0,0 +1,285 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// evictGrace protects repos used recently from being evicted, since a Repo
+// returned by Clone is still in use after the repo lock has been released.
+const evictGrace = 10 * time.Minute
+
+// CachedRepo describes a repository in the local cache.
+type CachedRepo struct {
+	// Name is the full name of the repo: "owner/repo".
+	Name string `json:"name"`
+	// Size is the disk usage of the clone in bytes.
+	Size int64 `json:"size"`
+	// LastUsed is the last time the repo was cloned or fetched.
+	LastUsed time.Time `json:"last_used"`
+}
+
+// SetCacheSize sets the disk budget of the repo cache in bytes.
+// Least recently used repos are evicted once the budget is exceeded,
+// zero or negative means no limit.
+func (c *Client) SetCacheSize(size int64) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	c.cacheSize = size
+}
+
+// corrupted reports whether the clone in dir is broken and can only be
+// recovered by cloning it again. Failures of other git commands are not
+// enough, they may be caused by the network or a locked index.
+func (c *Client) corrupted(dir string) bool {
+	b, err := runCmd(dir, c.git, "--git-dir", filepath.Join(dir, ".git"), "fsck", "--connectivity-only")
+	if err != nil {
+		logrus.WithError(err).Warnf("Check of %s failed, output: %s", dir, string(b))
+		return true
+	}
+	return false
+}
+
+// dirSize returns the disk usage of a directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// touch records fullName as used now. The modification time of the repo
+// directory is used as last used time, so the LRU order survives restarts.
+// The disk usage is not refreshed here, walking big repos on every clone is
+// too expensive, it is computed once when listed and refreshed by Maintain.
+func (c *Client) touch(fullName string) {
+	dir := filepath.Join(c.dir, fullName)
+	now := time.Now()
+	if err := os.Chtimes(dir, now, now); err != nil {
+		logrus.WithError(err).Warnf("Touch cached repo %s failed.", fullName)
+	}
+}
+
+// updateSize recomputes the disk usage of fullName. The caller must hold the
+// repo lock.
+func (c *Client) updateSize(fullName string) {
+	size, err := dirSize(filepath.Join(c.dir, fullName))
+	if err != nil {
+		logrus.WithError(err).Warnf("Compute size of cached repo %s failed.", fullName)
+		return
+	}
+	c.cacheLock.Lock()
+	c.sizes[fullName] = size
+	c.cacheLock.Unlock()
+}
+
+// CachedRepos lists the repos in the local cache, least recently used first.
+func (c *Client) CachedRepos() ([]CachedRepo, error) {
+	owners, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var repos []CachedRepo
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+		infos, err := ioutil.ReadDir(filepath.Join(c.dir, owner.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !info.IsDir() {
+				continue
+			}
+			fullName := owner.Name() + "/" + info.Name()
+			c.cacheLock.Lock()
+			size, ok := c.sizes[fullName]
+			c.cacheLock.Unlock()
+			if !ok {
+				size, err = dirSize(filepath.Join(c.dir, fullName))
+				if err != nil {
+					return nil, err
+				}
+				c.cacheLock.Lock()
+				c.sizes[fullName] = size
+				c.cacheLock.Unlock()
+			}
+			repos = append(repos, CachedRepo{
+				Name:     fullName,
+				Size:     size,
+				LastUsed: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].LastUsed.Before(repos[j].LastUsed)
+	})
+	return repos, nil
+}
+
+// remove deletes the clone of fullName. The caller must hold the repo lock.
+func (c *Client) remove(fullName string) error {
+	if err := os.RemoveAll(filepath.Join(c.dir, fullName)); err != nil {
+		return err
+	}
+	c.cacheLock.Lock()
+	delete(c.sizes, fullName)
+	c.cacheLock.Unlock()
+	return nil
+}
+
+// Purge removes owner/repo from the local cache. A Repo returned by Clone is
+// still in use after the repo lock has been released, so repos used within
+// evictGrace are refused with ErrRepoInUse unless force is set.
+func (c *Client) Purge(owner, repo string, force bool) error {
+	fullName := owner + "/" + repo
+	c.lockRepo(fullName)
+	defer c.unlockRepo(fullName)
+	dir := filepath.Join(c.dir, fullName)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("repo %s not cached: %w", fullName, err)
+	}
+	if !force && time.Since(info.ModTime()) < evictGrace {
+		return fmt.Errorf("%w: %s was used at %s", ErrRepoInUse, fullName, info.ModTime().Format(time.RFC3339))
+	}
+	logrus.Infof("Purging cached repo %s.", fullName)
+	return c.remove(fullName)
+}
+
+// PurgeAll removes all repos from the local cache. Repos used within
+// evictGrace are skipped unless force is set.
+func (c *Client) PurgeAll(force bool) error {
+	repos, err := c.CachedRepos()
+	if err != nil {
+		return err
+	}
+	for _, r := range repos {
+		parts := strings.SplitN(r.Name, "/", 2)
+		err := c.Purge(parts[0], parts[1], force)
+		if errors.Is(err, ErrRepoInUse) {
+			logrus.Infof("Skip purging cached repo %s: %v", r.Name, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evict removes least recently used repos until the cache fits in its budget.
+// Repos used within evictGrace and keep are never evicted.
+func (c *Client) evict(keep string) {
+	c.cacheLock.Lock()
+	budget := c.cacheSize
+	c.cacheLock.Unlock()
+	if budget <= 0 {
+		return
+	}
+	repos, err := c.CachedRepos()
+	if err != nil {
+		logrus.WithError(err).Warnln("List cached repos failed.")
+		return
+	}
+	var total int64
+	for _, r := range repos {
+		total += r.Size
+	}
+	for _, r := range repos {
+		if total <= budget {
+			return
+		}
+		if r.Name == keep || time.Since(r.LastUsed) < evictGrace {
+			continue
+		}
+		c.lockRepo(r.Name)
+		logrus.Infof("Evicting cached repo %s (%d bytes).", r.Name, r.Size)
+		err := c.remove(r.Name)
+		c.unlockRepo(r.Name)
+		if err != nil {
+			logrus.WithError(err).Warnf("Evict cached repo %s failed.", r.Name)
+			continue
+		}
+		total -= r.Size
+	}
+	if total > budget {
+		logrus.Warnf("Repo cache uses %d bytes, exceeds budget %d bytes.", total, budget)
+	}
+}
+
+// Maintain runs housekeeping on every cached repo: git maintenance (or
+// git gc on older git), recovery of corrupted clones, refreshing the disk
+// usage and eviction.
+func (c *Client) Maintain() {
+	repos, err := c.CachedRepos()
+	if err != nil {
+		logrus.WithError(err).Warnln("List cached repos failed.")
+		return
+	}
+	for _, r := range repos {
+		c.lockRepo(r.Name)
+		dir := filepath.Join(c.dir, r.Name)
+		logrus.Infof("Running maintenance on %s.", r.Name)
+		b, err := runCmd(dir, c.git, "maintenance", "run", "--auto")
+		if err != nil && strings.Contains(string(b), "is not a git command") {
+			b, err = runCmd(dir, c.git, "gc", "--auto", "--quiet")
+		}
+		if err != nil {
+			logrus.WithError(err).Warnf("Maintenance of %s failed, output: %s", r.Name, string(b))
+			if c.corrupted(dir) {
+				logrus.Warnf("Cached repo %s is corrupted, removing it.", r.Name)
+				_ = c.remove(r.Name)
+				c.unlockRepo(r.Name)
+				continue
+			}
+		}
+		c.updateSize(r.Name)
+		c.unlockRepo(r.Name)
+	}
+	c.evict("")
+}
+
+// StartMaintenance runs Maintain every interval until stop is closed.
+func (c *Client) StartMaintenance(interval time.Duration, stop <-chan struct{}) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.Maintain()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func runCmd(dir, cmd string, arg ...string) ([]byte, error) {
+	c := exec.Command(cmd, arg...)
+	c.Dir = dir
+	return c.CombinedOutput()
+}
@@ -0,0 +1,163 @@
+package git
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_corrupted(t *testing.T) {
+	c, remote := newLocalClient(t)
+	initBareRepo(t, remote, "owner", "repo")
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	// a locked index fails other git commands, but the clone is healthy
+	if err := ioutil.WriteFile(filepath.Join(r.Directory(), ".git", "index.lock"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c.corrupted(r.Directory()) {
+		t.Errorf("corrupted() = true for a healthy clone, want false")
+	}
+
+	if err := os.Remove(filepath.Join(r.Directory(), ".git", "HEAD")); err != nil {
+		t.Fatalf("Remove HEAD failed: %v", err)
+	}
+	if !c.corrupted(r.Directory()) {
+		t.Errorf("corrupted() = false for a clone without HEAD, want true")
+	}
+}
+
+func TestCloneRecoverCorrupted(t *testing.T) {
+	c, remote := newLocalClient(t)
+	initBareRepo(t, remote, "owner", "repo")
+
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	// break the clone
+	if err := os.Remove(filepath.Join(r.Directory(), ".git", "HEAD")); err != nil {
+		t.Fatalf("Remove HEAD failed: %v", err)
+	}
+
+	r, err = c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone corrupted repo failed: %v", err)
+	}
+	if _, err := r.Status(); err != nil {
+		t.Errorf("Status after recovery failed: %v", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, remote := newLocalClient(t)
+	for _, repo := range []string{"a", "b", "c"} {
+		initBareRepo(t, remote, "owner", repo)
+		if _, err := c.Clone("owner", repo); err != nil {
+			t.Fatalf("Clone %s failed: %v", repo, err)
+		}
+	}
+
+	repos, err := c.CachedRepos()
+	if err != nil {
+		t.Fatalf("CachedRepos failed: %v", err)
+	}
+	if len(repos) != 3 {
+		t.Fatalf("CachedRepos() = %v, want 3 repos", repos)
+	}
+
+	// make a and b old enough to be evicted, a is the least recently used
+	old := time.Now().Add(-2 * evictGrace)
+	_ = os.Chtimes(filepath.Join(c.dir, "owner", "a"), old.Add(-time.Minute), old.Add(-time.Minute))
+	_ = os.Chtimes(filepath.Join(c.dir, "owner", "b"), old, old)
+
+	c.SetCacheSize(repos[0].Size + repos[1].Size + 1)
+	c.evict("")
+
+	infos, _ := ioutil.ReadDir(filepath.Join(c.dir, "owner"))
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	if len(names) != 2 || names[0] != "b" || names[1] != "c" {
+		t.Errorf("cached repos after eviction = %v, want [b c]", names)
+	}
+
+	if err := c.PurgeAll(true); err != nil {
+		t.Fatalf("PurgeAll failed: %v", err)
+	}
+	repos, _ = c.CachedRepos()
+	if len(repos) != 0 {
+		t.Errorf("CachedRepos() after PurgeAll = %v, want none", repos)
+	}
+}
+
+func TestPurgeInUse(t *testing.T) {
+	c, remote := newLocalClient(t)
+	for _, repo := range []string{"a", "b"} {
+		initBareRepo(t, remote, "owner", repo)
+		if _, err := c.Clone("owner", repo); err != nil {
+			t.Fatalf("Clone %s failed: %v", repo, err)
+		}
+	}
+
+	if err := c.Purge("owner", "a", false); !errors.Is(err, ErrRepoInUse) {
+		t.Errorf("Purge() of a repo just used = %v, want ErrRepoInUse", err)
+	}
+
+	old := time.Now().Add(-2 * evictGrace)
+	_ = os.Chtimes(filepath.Join(c.dir, "owner", "b"), old, old)
+	if err := c.PurgeAll(false); err != nil {
+		t.Fatalf("PurgeAll failed: %v", err)
+	}
+	repos, _ := c.CachedRepos()
+	if len(repos) != 1 || repos[0].Name != "owner/a" {
+		t.Errorf("CachedRepos() after PurgeAll = %v, want [owner/a]", repos)
+	}
+
+	if err := c.Purge("owner", "a", true); err != nil {
+		t.Errorf("Purge() forced failed: %v", err)
+	}
+	repos, _ = c.CachedRepos()
+	if len(repos) != 0 {
+		t.Errorf("CachedRepos() after forced Purge = %v, want none", repos)
+	}
+}
+
+func TestCacheSizeRefreshedByMaintain(t *testing.T) {
+	c, remote := newLocalClient(t)
+	initBareRepo(t, remote, "owner", "repo")
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	size := func() int64 {
+		repos, err := c.CachedRepos()
+		if err != nil || len(repos) != 1 {
+			t.Fatalf("CachedRepos() = %v, %v, want one repo", repos, err)
+		}
+		return repos[0].Size
+	}
+	before := size()
+
+	if err := ioutil.WriteFile(filepath.Join(r.Directory(), "big"), make([]byte, 1<<16), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a cache hit does not walk the repo
+	if _, err := c.Clone("owner", "repo"); err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if got := size(); got != before {
+		t.Errorf("size after cache hit = %d, want %d", got, before)
+	}
+
+	c.Maintain()
+	if got := size(); got < before+1<<16 {
+		t.Errorf("size after Maintain = %d, want at least %d", got, before+1<<16)
+	}
+}
@@ -0,0 +1,49 @@
+package git
+
+import (
+	"net/url"
+	"os"
+)
+
+// Environment variables read by credentialHelper.
+const (
+	userEnv     = "SYNC_BOT_GIT_USER"
+	tokenEnv    = "SYNC_BOT_GIT_TOKEN"
+	protocolEnv = "SYNC_BOT_GIT_PROTOCOL"
+	hostEnv     = "SYNC_BOT_GIT_HOST"
+)
+
+// credentialHelper is an inline git credential helper. It answers "get"
+// requests from the environment of the git process, so credentials never
+// appear in remote URLs, process arguments or .git/config, and a rotated
+// token is used by the next command. The request is read from stdin, only
+// requests for the protocol and host of the git server are answered, so the
+// token is never sent to another host like a submodule or a redirect.
+const credentialHelper = `!f() { test "$1" = get || return 0; p=; h=; ` +
+	`while IFS== read -r k v; do case "$k" in protocol) p=$v;; host) h=$v;; "") break;; esac; done; ` +
+	`test "$p" = "${` + protocolEnv + `}" && test "$h" = "${` + hostEnv + `}" || return 0; ` +
+	`echo "username=${` + userEnv + `}"; echo "password=${` + tokenEnv + `}"; }; f`
+
+// authorize returns the arguments and environment which supply credentials of
+// the git server at base to the git command with arguments arg. arg is
+// returned unchanged if there are no credentials.
+func authorize(getCredentials func() (string, string), base string, arg []string) ([]string, []string) {
+	user, token := getCredentials()
+	if user == "" || token == "" {
+		return arg, nil
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return arg, nil
+	}
+	// reset helpers from the git config, then use the inline helper only
+	args := append([]string{"-c", "credential.helper=", "-c", "credential.helper=" + credentialHelper}, arg...)
+	env := append(os.Environ(),
+		userEnv+"="+user,
+		tokenEnv+"="+token,
+		protocolEnv+"="+u.Scheme,
+		hostEnv+"="+u.Host,
+		"GIT_TERMINAL_PROMPT=0",
+	)
+	return args, env
+}
@@ -4,8 +4,11 @@ package git
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -25,18 +28,118 @@ const (
 	Theirs StrategyOption = "theirs"
 )
 
+// CloneMode how a repository is cloned
+type CloneMode string
+
+// CloneMode enum
+const (
+	CloneFull     CloneMode = "full"
+	CloneBlobless CloneMode = "blobless"
+	CloneShallow  CloneMode = "shallow"
+)
+
+// CloneOptions options for cloning a repository
+type CloneOptions struct {
+	Mode CloneMode
+	// Depth is the initial depth of a shallow clone.
+	Depth int
+}
+
+// MergeDriver resolves the conflicts of a file in a three-way merge, it
+// returns an error if the conflicts can not be resolved.
+type MergeDriver func(base, ours, theirs []byte) ([]byte, error)
+
+// MergeDrivers are keyed by the patterns of file names, like "*.spec".
+type MergeDrivers map[string]MergeDriver
+
+// Lookup returns the merge driver of file, nil if not found.
+func (d MergeDrivers) Lookup(file string) MergeDriver {
+	for pattern, driver := range d {
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return driver
+		}
+	}
+	return nil
+}
+
 // MergeOption merge option
 type MergeOption string
 
 // MergeOption enum
 const (
-	MergeFF MergeOption = "--ff"
+	MergeFF     MergeOption = "--ff"
+	MergeNoFF   MergeOption = "--no-ff"
+	MergeSquash MergeOption = "--squash"
 )
 
+// PullRequestRef is the default pattern of the refs of pull request heads,
+// used by Gitee, GitHub and Gitea.
+const PullRequestRef = "refs/pull/%d/head"
+
 const repoPath = "repos"
 const gitee = "gitee.com"
 
-// Client can clone repos. It keeps a local cache, so successive clones of the
+// history of shallow clones is deepened by deepenStep commits at a time,
+// at most maxDeepen times before fetching the complete history.
+const (
+	deepenStep = 50
+	maxDeepen  = 10
+)
+
+// errCorrupted indicates a cached repo which has to be cloned again.
+var errCorrupted = errors.New("repository corrupted")
+
+// ErrRepoInUse indicates a cached repo which was used too recently to purge.
+var ErrRepoInUse = errors.New("repository in use")
+
+// Upstream interface for syncing a fork of a big repository with its upstream
+type Upstream interface {
+	ListRemote() (bool, error)
+	AddRemote(remotePath string) error
+	FetchUpstream(branch string) error
+	MergeUpstream(branch string) error
+	PushUpstreamToOrigin(branch string) error
+	CreateBranchAndPushToOrigin(branch, upstream string) error
+}
+
+// Repository interface for operations on a cloned repository
+type Repository interface {
+	Upstream
+	Directory() string
+	Status() (string, error)
+	Clean() error
+	Checkout(commitLike string) error
+	CheckoutNewBranch(branch string, force bool) error
+	FetchPullRequest(number int) error
+	CherryPick(first, last string, strategyOption StrategyOption) error
+	CherryPickAbort() error
+	// SetMergeDriver resolves the conflicts of files matching pattern in
+	// cherry-picks with driver.
+	SetMergeDriver(pattern string, driver MergeDriver)
+	Merge(ref string, option MergeOption) error
+	Push(branch string, force bool) error
+	RemoteBranchExists(branch string) bool
+	DeleteRemoteBranch(branch string) error
+}
+
+// Cache interface for managing the local repository cache
+type Cache interface {
+	CachedRepos() ([]CachedRepo, error)
+	// Purge and PurgeAll refuse repos which may still be in use unless force
+	// is set.
+	Purge(owner, repo string, force bool) error
+	PurgeAll(force bool) error
+}
+
+// Backend interface for git operations
+type Backend interface {
+	Clone(owner, repo string) (Repository, error)
+	Cache
+}
+
+var _ Backend = (*Client)(nil)
+
+// Client is the Backend which runs the git binary. It can clone repos. It keeps a local cache, so successive clones of the
 // same repo should be quick. Create with NewClient. Be sure to clean it up.
 type Client struct {
 	credLock sync.RWMutex
@@ -59,6 +162,24 @@ type Client struct {
 	// Lock with Client.lockRepo, unlock with Client.unlockRepo.
 	rlm       sync.Mutex
 	repoLocks map[string]*sync.Mutex
+
+	// cacheLock protects cacheSize and sizes.
+	cacheLock sync.Mutex
+	// cacheSize is the disk budget of the repo cache in bytes.
+	cacheSize int64
+	// sizes records the disk usage of cached repos.
+	sizes map[string]int64
+
+	// cloneOptions returns the clone options of a repo.
+	cloneOptions func(owner, repo string) CloneOptions
+	// transport returns the transport of a repo.
+	transport func(owner, repo string) Transport
+	// ssh is the ssh command.
+	ssh string
+	// sshDir keeps SSH keys and known hosts, created on first use.
+	sshDir string
+	// pullRequestRef is the pattern of the refs of pull request heads.
+	pullRequestRef string
 }
 
 // NewClient returns a client
@@ -68,18 +189,42 @@ func NewClient() (*Client, error) {
 
 // NewClientWithHost creates a client with specified host.
 func NewClientWithHost(host string) (*Client, error) {
+	return NewClientWithURL("https://" + host)
+}
+
+// NewClientWithURL creates a client of the git server at base, such as
+// "https://gitee.com" or "http://git.example.com:8080".
+func NewClientWithURL(base string) (*Client, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in git server URL %q", base)
+	}
+	host := u.Host
 	g, err := exec.LookPath("git")
 	if err != nil {
 		return nil, err
 	}
 
+	// keep the cache of gitee.com where it was, other hosts have their own
+	dir := repoPath
+	if host != gitee {
+		dir = repoPath + "-" + host
+	}
 	return &Client{
 		tokenGenerator: func() []byte { return nil },
-		dir:            repoPath,
+		dir:            dir,
 		git:            g,
-		base:           fmt.Sprintf("https://%s", host),
+		base:           strings.TrimSuffix(base, "/"),
 		host:           host,
 		repoLocks:      make(map[string]*sync.Mutex),
+		sizes:          make(map[string]int64),
+		cloneOptions:   func(owner, repo string) CloneOptions { return CloneOptions{Mode: CloneFull} },
+		transport:      func(owner, repo string) Transport { return Transport{Protocol: HTTPS} },
+		ssh:            "ssh",
+		pullRequestRef: PullRequestRef,
 	}, nil
 }
 
@@ -92,6 +237,36 @@ func (c *Client) SetCredentials(user string, tokenGenerator func() []byte) {
 	c.tokenGenerator = tokenGenerator
 }
 
+// SetPullRequestRef sets the pattern of the refs of pull request heads, like
+// "refs/merge-requests/%d/head" of GitLab, default is PullRequestRef.
+func (c *Client) SetPullRequestRef(pattern string) {
+	c.pullRequestRef = pattern
+}
+
+// SetCloneOptions sets the function deciding how each repository is cloned.
+// Big repositories can be cloned blobless or shallow, missing history is
+// fetched on demand.
+func (c *Client) SetCloneOptions(cloneOptions func(owner, repo string) CloneOptions) {
+	c.cloneOptions = cloneOptions
+}
+
+// cloneArgs returns the arguments of git clone for the clone options.
+func cloneArgs(opt CloneOptions) []string {
+	switch opt.Mode {
+	case CloneBlobless:
+		return []string{"--filter=blob:none"}
+	case CloneShallow:
+		depth := opt.Depth
+		if depth <= 0 {
+			depth = 1
+		}
+		// fetch all branches, a shallow clone implies --single-branch
+		return []string{fmt.Sprintf("--depth=%d", depth), "--no-single-branch"}
+	default:
+		return nil
+	}
+}
+
 func (c *Client) getCredentials() (string, string) {
 	c.credLock.RLock()
 	defer c.credLock.RUnlock()
@@ -115,65 +290,98 @@ func (c *Client) unlockRepo(repo string) {
 }
 
 // Clone clones a repository.
-func (c *Client) Clone(owner, repo string) (*Repo, error) {
+func (c *Client) Clone(owner, repo string) (Repository, error) {
+	r, err := c.clone(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	// evict outside of the repo lock, it takes the locks of other repos
+	c.evict(owner + "/" + repo)
+	return r, nil
+}
+
+func (c *Client) clone(owner, repo string) (*Repo, error) {
 	fullName := owner + "/" + repo
 	c.lockRepo(fullName)
 	defer c.unlockRepo(fullName)
-	base := c.base
-	user, pass := c.getCredentials()
-	if user != "" && pass != "" {
-		base = fmt.Sprintf("https://%s:%s@%s", user, pass, c.host)
-	}
 	dir := filepath.Join(c.dir, fullName)
+	rm, err := c.remote(owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		err = c.refresh(rm, owner, repo, dir)
+		if errors.Is(err, errCorrupted) {
+			logrus.WithError(err).Warnf("Cached repo %s is corrupted, cloning it again.", fullName)
+			if err2 := c.remove(fullName); err2 != nil {
+				return nil, err2
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
 		logrus.Infof("Cloning %s.", fullName)
 		if err2 := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err2 != nil && !os.IsExist(err2) {
 			return nil, err2
 		}
 
-		// special for big size repos
-		if owner == "openeuler" && repo == "kernel" {
-			fullName = "openeuler-sync-bot" + "/" + repo
-		}
-
-		remote := fmt.Sprintf("%s/%s.git", base, fullName)
-		if b, err2 := retryCmd("", c.git, "clone", remote, dir); err2 != nil {
+		args := append([]string{"clone"}, cloneArgs(c.cloneOptions(owner, repo))...)
+		args = append(args, rm.origin(owner, repo), dir)
+		if b, err2 := retryCmd("", rm.authorize, c.git, args...); err2 != nil {
+			// do not leave a partial clone behind
+			_ = os.RemoveAll(dir)
 			return nil, fmt.Errorf("git dir clone error: %v. output: %s", err2, string(b))
 		}
-	} else if err != nil {
-		return nil, err
-	} else {
+	}
+	c.touch(fullName)
 
-		if owner == "openeuler" && repo == "kernel" {
-			return &Repo{
-				dir:   dir,
-				git:   c.git,
-				host:  c.host,
-				base:  base,
-				owner: owner,
-				repo:  repo,
-				user:  user,
-				pass:  pass,
-			}, nil
-		}
+	return &Repo{
+		dir:    dir,
+		git:    c.git,
+		host:   c.host,
+		remote: rm,
+		owner:  owner,
+		repo:   repo,
+
+		pullRequestRef: c.pullRequestRef,
+	}, nil
+}
 
-		// Cache hit. Do a git fetch to keep updated.
-		logrus.Infof("Fetching %s.", fullName)
-		if b, err := retryCmd(dir, c.git, "fetch"); err != nil {
-			return nil, fmt.Errorf("git fetch error: %v. output: %s", err, string(b))
+// refresh keeps a cached repo updated. It returns errCorrupted if the clone
+// is broken and has to be cloned again.
+func (c *Client) refresh(rm *remote, owner, repo, dir string) error {
+	fullName := owner + "/" + repo
+	if b, err := runCmd(dir, c.git, "status", "--porcelain"); err != nil {
+		if c.corrupted(dir) {
+			return fmt.Errorf("%w: git status error: %v. output: %s", errCorrupted, err, string(b))
 		}
+		return fmt.Errorf("git status error: %v. output: %s", err, string(b))
 	}
 
-	return &Repo{
-		dir:   dir,
-		git:   c.git,
-		host:  c.host,
-		base:  base,
-		owner: owner,
-		repo:  repo,
-		user:  user,
-		pass:  pass,
-	}, nil
+	// clones of older versions have credentials in the URL of origin,
+	// and the transport of the repo may have changed
+	if b, err := runCmd(dir, c.git, "remote", "set-url", "origin", rm.origin(owner, repo)); err != nil {
+		return fmt.Errorf("git remote set-url error: %v. output: %s", err, string(b))
+	}
+
+	// big size repos are updated from upstream on demand
+	if owner == "openeuler" && repo == "kernel" {
+		return nil
+	}
+
+	// Cache hit. Do a git fetch to keep updated.
+	logrus.Infof("Fetching %s.", fullName)
+	if b, err := retryCmd(dir, rm.authorize, c.git, "fetch"); err != nil {
+		if c.corrupted(dir) {
+			return fmt.Errorf("%w: git fetch error: %v. output: %s", errCorrupted, err, string(b))
+		}
+		return fmt.Errorf("git fetch error: %v. output: %s", err, string(b))
+	}
+	return nil
 }
 
 // Repo is a clone of a git repository. Create with Client.Clone.
@@ -184,16 +392,19 @@ type Repo struct {
 	git string
 	// host is the git host.
 	host string
-	// base is the base path for remote git fetch calls.
-	base string
+	// remote is how the repo talks to its remotes.
+	remote *remote
 	// owner is the organization name: "owner" in "owner/repo".
 	owner string
 	// repo is the repository name: "repo" in "owner/repo".
 	repo string
-	// user is used for pushing to the remote repo.
-	user string
-	// pass is used for pushing to the remote repo.
-	pass string
+	// pullRequestRef is the pattern of the refs of pull request heads.
+	pullRequestRef string
+	// pullRefs are the refspecs of fetched pull requests, which are deepened
+	// together with origin in shallow clones.
+	pullRefs []string
+	// drivers resolve conflicts of cherry-picks
+	drivers MergeDrivers
 }
 
 // Directory exposes the location of the git repo
@@ -207,8 +418,10 @@ func (r *Repo) Destroy() error {
 }
 
 func (r *Repo) gitCommand(arg ...string) *exec.Cmd {
-	cmd := exec.Command(r.git, arg...)
+	args, env := r.remote.authorize(arg)
+	cmd := exec.Command(r.git, args...)
 	cmd.Dir = r.dir
+	cmd.Env = env
 
 	// hide secret in command arguments
 	for i, a := range arg {
@@ -382,11 +595,62 @@ func (r *Repo) CheckoutNewBranch(branch string, force bool) error {
 	return nil
 }
 
+// isShallow returns true if the repo is a shallow clone.
+func (r *Repo) isShallow() bool {
+	b, err := r.gitCommand("rev-parse", "--is-shallow-repository").Output()
+	return err == nil && strings.TrimSpace(string(b)) == "true"
+}
+
+// hasCommit returns true if rev names a commit which exists locally.
+func (r *Repo) hasCommit(rev string) bool {
+	return r.gitCommand("rev-parse", "--verify", "--quiet", rev+"^{commit}").Run() == nil
+}
+
+// deepen fetches more history of a shallow clone until found returns true.
+func (r *Repo) deepen(found func() bool) error {
+	if found() || !r.isShallow() {
+		return nil
+	}
+	for i := 0; i <= maxDeepen; i++ {
+		deepen := fmt.Sprintf("--deepen=%d", deepenStep)
+		if i == maxDeepen {
+			logrus.Warnf("History still not found, unshallow %s/%s.", r.owner, r.repo)
+			deepen = "--unshallow"
+		}
+		logrus.Infof("Deepening %s/%s: %s.", r.owner, r.repo, deepen)
+		if b, err := retryCmd(r.dir, r.remote.authorize, r.git, "fetch", deepen, "origin"); err != nil {
+			return fmt.Errorf("git fetch %s failed: %v. output: %s", deepen, err, string(b))
+		}
+		for _, ref := range r.pullRefs {
+			if b, err := retryCmd(r.dir, r.remote.authorize, r.git, "fetch", deepen, r.remote.url(r.owner, r.repo), ref); err != nil {
+				return fmt.Errorf("git fetch %s %s failed: %v. output: %s", deepen, ref, err, string(b))
+			}
+		}
+		if found() {
+			return nil
+		}
+		if !r.isShallow() {
+			break
+		}
+	}
+	return errors.New("required history not found")
+}
+
 // CherryPick cherry-pick from commits with strategyOption
 func (r *Repo) CherryPick(first, last string, strategyOption StrategyOption) error {
 	logrus.Infof("Cherry Pick from %s to %s.", first, last)
+	// the parent of first is the base of the cherry-pick
+	err := r.deepen(func() bool { return r.hasCommit(first + "^") })
+	if err != nil {
+		return fmt.Errorf("cherry pick failed, deepen history of %s: %v", first, err)
+	}
 	co := r.gitCommand("cherry-pick", "-x", fmt.Sprintf("%s^..%s", first, last))
 	out, err := co.CombinedOutput()
+	// resolve conflicts and continue until all commits are picked
+	for err != nil && r.resolveConflicts() {
+		co = r.gitCommand("-c", "core.editor=true", "cherry-pick", "--continue")
+		out, err = co.CombinedOutput()
+	}
 	if err != nil {
 		logrus.Errorf("Cherry pick failed with error: %v and output: %q", err, string(out))
 		return fmt.Errorf("cherry pick failed, output: %q, error: %v", string(out), err)
@@ -394,6 +658,58 @@ func (r *Repo) CherryPick(first, last string, strategyOption StrategyOption) err
 	return nil
 }
 
+// SetMergeDriver resolves the conflicts of files matching pattern in
+// cherry-picks with driver.
+func (r *Repo) SetMergeDriver(pattern string, driver MergeDriver) {
+	if r.drivers == nil {
+		r.drivers = make(MergeDrivers)
+	}
+	r.drivers[pattern] = driver
+}
+
+// resolveConflicts resolves the conflicted files with the merge drivers, it
+// returns false if there is no conflict or any conflict is not resolved.
+func (r *Repo) resolveConflicts() bool {
+	b, err := r.gitCommand("diff", "--name-only", "--diff-filter=U", "-z").Output()
+	if err != nil {
+		return false
+	}
+	files := strings.Split(strings.TrimRight(string(b), "\x00"), "\x00")
+	if len(files) == 0 || files[0] == "" {
+		return false
+	}
+	for _, file := range files {
+		driver := r.drivers.Lookup(file)
+		if driver == nil {
+			logrus.Warnf("No merge driver for conflicted file %s.", file)
+			return false
+		}
+		// stages 1, 2 and 3 are the base, ours and theirs versions
+		var versions [3][]byte
+		for i := range versions {
+			if versions[i], err = r.gitCommand("show", fmt.Sprintf(":%d:%s", i+1, file)).Output(); err != nil {
+				logrus.Warnf("Conflicted file %s is not changed on both sides: %v.", file, err)
+				return false
+			}
+		}
+		merged, err := driver(versions[0], versions[1], versions[2])
+		if err != nil {
+			logrus.Warnf("Resolve conflicts of %s failed: %v.", file, err)
+			return false
+		}
+		if err := ioutil.WriteFile(filepath.Join(r.dir, file), merged, 0644); err != nil {
+			logrus.Warnf("Write resolved %s failed: %v.", file, err)
+			return false
+		}
+		if b, err := r.gitCommand("add", "--", file).CombinedOutput(); err != nil {
+			logrus.Warnf("Add resolved %s failed: %v, output: %s.", file, err, string(b))
+			return false
+		}
+		logrus.Infof("Resolved conflicts of %s.", file)
+	}
+	return true
+}
+
 // CherryPickAbort abort cherry-pick
 func (r *Repo) CherryPickAbort() error {
 	logrus.Infof("Cherry pick abort.")
@@ -404,18 +720,14 @@ func (r *Repo) CherryPickAbort() error {
 	return nil
 }
 
-// Push pushes over https to the provided owner/repo#branch using a password for basic auth.
+// Push pushes to the provided owner/repo#branch over the transport of the repo.
 func (r *Repo) Push(branch string, force bool) error {
-	if r.user == "" || r.pass == "" {
-		return errors.New("cannot push without credentials - configure your git client")
+	if err := r.remote.canPush(); err != nil {
+		return err
 	}
 	logrus.Infof("Pushing to '%s/%s (branch: %s)'.", r.owner, r.repo, branch)
-	remote := fmt.Sprintf("https://%s:%s@%s/%s/%s", r.user, r.pass, r.host, r.owner, r.repo)
-
-	// check if repo is one of the big repos
-	if r.owner == "openeuler" && r.repo == "kernel" {
-		remote = fmt.Sprintf("https://%s:%s@%s/%s/%s", r.user, r.pass, r.host, "openeuler-sync-bot", r.repo)
-	}
+	// big repos are pushed to their fork
+	remote := r.remote.origin(r.owner, r.repo)
 
 	var co *exec.Cmd
 	if force {
@@ -449,11 +761,11 @@ func (r *Repo) DeleteBranch(branch string, force bool) error {
 
 // DeleteRemoteBranch delete remote branch
 func (r *Repo) DeleteRemoteBranch(branch string) error {
-	if r.user == "" || r.pass == "" {
-		return errors.New("cannot push without credentials - configure your git client")
+	if err := r.remote.canPush(); err != nil {
+		return err
 	}
 	logrus.Infof("Delete remote branch '%s/%s (branch: %s)'.", r.owner, r.repo, branch)
-	remote := fmt.Sprintf("https://%s:%s@%s/%s/%s", r.user, r.pass, r.host, r.owner, r.repo)
+	remote := r.remote.url(r.owner, r.repo)
 	co := r.gitCommand("push", remote, "--delete", branch)
 	out, err := co.CombinedOutput()
 	if err != nil {
@@ -463,13 +775,19 @@ func (r *Repo) DeleteRemoteBranch(branch string) error {
 	return nil
 }
 
-// FetchPullRequest just fetch
+// FetchPullRequest fetches the head of a pull request to origin/pull/<number>.
 func (r *Repo) FetchPullRequest(number int) error {
 	logrus.Infof("Fetching %s/%s#%d.", r.owner, r.repo, number)
-	if b, err := retryCmd(r.dir, r.git, "fetch", r.base+"/"+r.owner+"/"+r.repo,
-		fmt.Sprintf("+refs/pull/%d/head:refs/remotes/origin/pull/%d", number, number)); err != nil {
+	ref := fmt.Sprintf("+%s:refs/remotes/origin/pull/%d", fmt.Sprintf(r.pullRequestRef, number), number)
+	args := []string{"fetch", r.remote.url(r.owner, r.repo), ref}
+	if r.isShallow() {
+		// do not fetch the complete history of the pull request
+		args = []string{"fetch", fmt.Sprintf("--depth=%d", deepenStep), r.remote.url(r.owner, r.repo), ref}
+	}
+	if b, err := retryCmd(r.dir, r.remote.authorize, r.git, args...); err != nil {
 		return fmt.Errorf("git fetch failed for PR %d: %v. output: %s", number, err, string(b))
 	}
+	r.pullRefs = append(r.pullRefs, ref)
 	return nil
 }
 
@@ -485,6 +803,12 @@ func (r *Repo) Config(key, value string) error {
 // Merge incorporates changes from other branch
 func (r *Repo) Merge(ref string, option MergeOption) error {
 	logrus.Infof("Running git merge %v %s", option, ref)
+	err := r.deepen(func() bool {
+		return r.gitCommand("merge-base", "HEAD", ref).Run() == nil
+	})
+	if err != nil {
+		return fmt.Errorf("git merge %s %s failed, deepen history: %v", option, ref, err)
+	}
 	if b, err := r.gitCommand("merge", string(option), ref).CombinedOutput(); err != nil {
 		return fmt.Errorf("git merge %s %s failed: %v. output: %s", option, ref, err, string(b))
 	}
@@ -493,13 +817,15 @@ func (r *Repo) Merge(ref string, option MergeOption) error {
 
 // retryCmd will retry the command a few times with backoff. Use this for any
 // commands that will be talking to GitHub, such as clones or fetches.
-func retryCmd(dir, cmd string, arg ...string) ([]byte, error) {
+func retryCmd(dir string, authorize func(arg []string) ([]string, []string), cmd string, arg ...string) ([]byte, error) {
 	var b []byte
 	var err error
 	sleepyTime := time.Second
 	for i := 0; i < 3; i++ {
-		c := exec.Command(cmd, arg...)
+		args, env := authorize(arg)
+		c := exec.Command(cmd, args...)
 		c.Dir = dir
+		c.Env = env
 		b, err = c.CombinedOutput()
 		if err != nil {
 			err = fmt.Errorf("running %q %v returned error %w with output %q", cmd, arg, err, string(b))
@@ -2,10 +2,62 @@ package git
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
 
+// newLocalClient creates a client which clones from bare repos under a local
+// directory instead of a remote host.
+func newLocalClient(t *testing.T) (*Client, string) {
+	t.Helper()
+	c, err := NewClient()
+	if err != nil {
+		t.Fatalf("New Client failed: %v", err)
+	}
+	root := t.TempDir()
+	c.dir = filepath.Join(root, "cache")
+	c.base = "file://" + filepath.Join(root, "remote")
+	return c, filepath.Join(root, "remote")
+}
+
+// runGit runs git in dir and returns the trimmed output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	b, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v, output: %s", args, err, string(b))
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// commitFile writes content to file in the work tree dir and commits it.
+func commitFile(t *testing.T, dir, file, content string) string {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", file)
+	runGit(t, dir, "commit", "-q", "-m", "update "+file)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+// initBareRepo creates a bare repo owner/repo.git with one commit on master,
+// and returns a work tree which pushes to it.
+func initBareRepo(t *testing.T, remote, owner, repo string) string {
+	t.Helper()
+	work := t.TempDir()
+	bare := filepath.Join(remote, owner, repo+".git")
+	runGit(t, work, "init", "-q", "-b", "master")
+	commitFile(t, work, "README", "init\n")
+	runGit(t, work, "clone", "-q", "--bare", work, bare)
+	runGit(t, work, "remote", "add", "origin", bare)
+	return work
+}
+
 func TestCherryPick(t *testing.T) {
 
 	c, err := NewClient()
@@ -66,3 +118,134 @@ func TestCherryPick(t *testing.T) {
 	//	t.Fatalf("Fetch pull request %v failed: %v", pr, err)
 	//}
 }
+
+func TestShallowCloneCherryPick(t *testing.T) {
+	c, remote := newLocalClient(t)
+	work := initBareRepo(t, remote, "owner", "repo")
+	for i := 0; i < 5; i++ {
+		commitFile(t, work, "history", fmt.Sprintf("%d\n", i))
+	}
+	runGit(t, work, "branch", "branch1")
+	first := commitFile(t, work, "a", "a\n")
+	last := commitFile(t, work, "b", "b\n")
+	runGit(t, work, "push", "-q", "origin", "master", "branch1", "HEAD:refs/pull/1/head")
+
+	c.SetCloneOptions(func(owner, repo string) CloneOptions {
+		return CloneOptions{Mode: CloneShallow, Depth: 1}
+	})
+	cloned, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	r := cloned.(*Repo)
+	if !r.isShallow() {
+		t.Fatalf("Clone is not shallow")
+	}
+	_ = r.Config("user.name", "test")
+	_ = r.Config("user.email", "test@example.com")
+	if r.hasCommit("origin/master~3") {
+		t.Fatalf("Shallow clone has too much history")
+	}
+
+	if err := r.deepen(func() bool { return r.hasCommit("origin/master~3") }); err != nil {
+		t.Fatalf("deepen failed: %v", err)
+	}
+
+	if err := r.Checkout("origin/branch1"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if err := r.CheckoutNewBranch("sync", true); err != nil {
+		t.Fatalf("CheckoutNewBranch failed: %v", err)
+	}
+	if err := r.FetchPullRequest(1); err != nil {
+		t.Fatalf("FetchPullRequest failed: %v", err)
+	}
+	if err := r.CherryPick(first, last, Theirs); err != nil {
+		t.Fatalf("CherryPick failed: %v", err)
+	}
+	if got := runGit(t, r.Directory(), "log", "--format=%s", "-2"); got != "update b\nupdate a" {
+		t.Errorf("log after cherry-pick = %q", got)
+	}
+}
+
+func TestCherryPickMergeDriver(t *testing.T) {
+	c, remote := newLocalClient(t)
+	work := initBareRepo(t, remote, "owner", "repo")
+	commitFile(t, work, "a.txt", "base\n")
+	runGit(t, work, "checkout", "-q", "-b", "branch1")
+	commitFile(t, work, "a.txt", "ours\n")
+	runGit(t, work, "checkout", "-q", "master")
+	first := commitFile(t, work, "a.txt", "theirs\n")
+	last := commitFile(t, work, "b.txt", "b\n")
+	runGit(t, work, "push", "-q", "origin", "master", "branch1", "HEAD:refs/pull/1/head")
+
+	tests := []struct {
+		name    string
+		driver  MergeDriver
+		wantErr bool
+	}{
+		{"no driver", nil, true},
+		{"driver failed", func(base, ours, theirs []byte) ([]byte, error) {
+			return nil, fmt.Errorf("unresolved")
+		}, true},
+		{"resolved", func(base, ours, theirs []byte) ([]byte, error) {
+			return []byte(fmt.Sprintf("%s%s%s", base, ours, theirs)), nil
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloned, err := c.Clone("owner", "repo")
+			if err != nil {
+				t.Fatalf("Clone failed: %v", err)
+			}
+			r := cloned.(*Repo)
+			_ = r.Config("user.name", "test")
+			_ = r.Config("user.email", "test@example.com")
+			_ = r.CherryPickAbort()
+			if err := r.Checkout("origin/branch1"); err != nil {
+				t.Fatalf("Checkout failed: %v", err)
+			}
+			if err := r.CheckoutNewBranch("sync", true); err != nil {
+				t.Fatalf("CheckoutNewBranch failed: %v", err)
+			}
+			if err := r.FetchPullRequest(1); err != nil {
+				t.Fatalf("FetchPullRequest failed: %v", err)
+			}
+			if tt.driver != nil {
+				r.SetMergeDriver("*.txt", tt.driver)
+			}
+			err = r.CherryPick(first, last, Theirs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CherryPick() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := runGit(t, r.Directory(), "log", "--format=%s", "-3"); got != "update b.txt\nupdate a.txt\nupdate a.txt" {
+				t.Errorf("log after cherry-pick = %q", got)
+			}
+			if got := runGit(t, r.Directory(), "show", "HEAD:a.txt"); got != "base\nours\ntheirs" {
+				t.Errorf("content of a.txt = %q", got)
+			}
+		})
+	}
+}
+
+func TestFetchPullRequestRef(t *testing.T) {
+	c, remote := newLocalClient(t)
+	work := initBareRepo(t, remote, "owner", "repo")
+	commitFile(t, work, "a", "a\n")
+	runGit(t, work, "push", "-q", "origin", "HEAD:refs/merge-requests/3/head")
+	c.SetPullRequestRef("refs/merge-requests/%d/head")
+
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := r.FetchPullRequest(3); err != nil {
+		t.Fatalf("FetchPullRequest failed: %v", err)
+	}
+	if got, want := runGit(t, r.Directory(), "show", "origin/pull/3:a"), "a"; got != want {
+		t.Errorf("content of a = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,735 @@
+// Package gogit provides an in-process git.Backend built on go-git.
+//
+// Repositories are cloned into memory, so no git binary and no disk cache are
+// needed. Remotes given as file:// URLs are served in-process as well, which
+// allows tests to run against local bare repositories without network.
+package gogit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/git"
+)
+
+const upstream = "upstream"
+
+func init() {
+	// serve file:// remotes in-process instead of running git-upload-pack
+	client.InstallProtocol("file", server.DefaultServer)
+}
+
+var _ git.Backend = (*Client)(nil)
+
+// Client is the git.Backend which keeps clones in memory. Create with NewClient.
+type Client struct {
+	credLock sync.RWMutex
+	// user is used when pushing or pulling code if specified.
+	user string
+	// needed to generate the token.
+	tokenGenerator func() []byte
+
+	// base is the base URL of remote repos, like "https://gitee.com" or "file:///path/to/repos".
+	base string
+	// pullRequestRef is the pattern of the refs of pull request heads.
+	pullRequestRef string
+
+	// lock protects repos
+	lock  sync.Mutex
+	repos map[string]*Repo
+}
+
+// NewClient returns a client which clones "<base>/<owner>/<repo>.git".
+func NewClient(base string) *Client {
+	return &Client{
+		tokenGenerator: func() []byte { return nil },
+		base:           strings.TrimSuffix(base, "/"),
+		pullRequestRef: git.PullRequestRef,
+		repos:          make(map[string]*Repo),
+	}
+}
+
+// SetPullRequestRef sets the pattern of the refs of pull request heads, like
+// "refs/merge-requests/%d/head" of GitLab, default is git.PullRequestRef.
+func (c *Client) SetPullRequestRef(pattern string) {
+	c.pullRequestRef = pattern
+}
+
+// SetCredentials sets credentials in the client to be used for pushing to
+// or pulling from remote repositories.
+func (c *Client) SetCredentials(user string, tokenGenerator func() []byte) {
+	c.credLock.Lock()
+	defer c.credLock.Unlock()
+	c.user = user
+	c.tokenGenerator = tokenGenerator
+}
+
+func (c *Client) auth() transport.AuthMethod {
+	c.credLock.RLock()
+	defer c.credLock.RUnlock()
+	pass := string(c.tokenGenerator())
+	if c.user == "" || pass == "" {
+		return nil
+	}
+	return &http.BasicAuth{Username: c.user, Password: pass}
+}
+
+// Clone clones a repository into memory, or fetches it if already cloned.
+func (c *Client) Clone(owner, repo string) (git.Repository, error) {
+	fullName := owner + "/" + repo
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if r, ok := c.repos[fullName]; ok {
+		logrus.Infof("Fetching %s.", fullName)
+		err := r.repo.Fetch(&gogit.FetchOptions{Auth: c.auth(), Force: true})
+		if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+			return nil, fmt.Errorf("git fetch error: %v", err)
+		}
+		r.lastUsed = time.Now()
+		return r, nil
+	}
+
+	logrus.Infof("Cloning %s.", fullName)
+	url := fmt.Sprintf("%s/%s.git", c.base, fullName)
+	repository, err := gogit.Clone(memory.NewStorage(), memfs.New(), &gogit.CloneOptions{
+		URL:  url,
+		Auth: c.auth(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("git clone %s error: %v", fullName, err)
+	}
+	r := &Repo{
+		client:   c,
+		repo:     repository,
+		url:      url,
+		owner:    owner,
+		name:     repo,
+		lastUsed: time.Now(),
+	}
+	c.repos[fullName] = r
+	return r, nil
+}
+
+// CachedRepos lists the repos in memory, least recently used first.
+func (c *Client) CachedRepos() ([]git.CachedRepo, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var repos []git.CachedRepo
+	for name, r := range c.repos {
+		repos = append(repos, git.CachedRepo{Name: name, LastUsed: r.lastUsed})
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].LastUsed.Before(repos[j].LastUsed)
+	})
+	return repos, nil
+}
+
+// Purge removes owner/repo from memory. A Repo in use keeps its storage, so
+// force makes no difference.
+func (c *Client) Purge(owner, repo string, force bool) error {
+	fullName := owner + "/" + repo
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.repos[fullName]; !ok {
+		return fmt.Errorf("repo %s not cached", fullName)
+	}
+	delete(c.repos, fullName)
+	return nil
+}
+
+// PurgeAll removes all repos from memory, force makes no difference.
+func (c *Client) PurgeAll(force bool) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.repos = make(map[string]*Repo)
+	return nil
+}
+
+var _ git.Repository = (*Repo)(nil)
+
+// Repo is an in-memory clone of a git repository. Create with Client.Clone.
+type Repo struct {
+	client *Client
+	repo   *gogit.Repository
+	// url is the URL of origin.
+	url string
+	// owner is the organization name: "owner" in "owner/repo".
+	owner string
+	// name is the repository name: "repo" in "owner/repo".
+	name     string
+	lastUsed time.Time
+	// drivers resolve conflicts of cherry-picks
+	drivers git.MergeDrivers
+}
+
+// Directory returns an empty string, the repo is not on disk.
+func (r *Repo) Directory() string {
+	return ""
+}
+
+func (r *Repo) worktree() (*gogit.Worktree, error) {
+	return r.repo.Worktree()
+}
+
+func (r *Repo) resolve(rev string) (plumbing.Hash, error) {
+	h, err := r.repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("resolve %s: %w", rev, err)
+	}
+	return *h, nil
+}
+
+func (r *Repo) head() (*object.Commit, error) {
+	ref, err := r.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	return r.repo.CommitObject(ref.Hash())
+}
+
+// Status show the working tree status
+func (r *Repo) Status() (string, error) {
+	w, err := r.worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return "", fmt.Errorf("error status %v", err)
+	}
+	return status.String(), nil
+}
+
+// Clean resets the worktree to HEAD and removes untracked files.
+func (r *Repo) Clean() error {
+	w, err := r.worktree()
+	if err != nil {
+		return err
+	}
+	if err := w.Reset(&gogit.ResetOptions{Mode: gogit.HardReset}); err != nil {
+		return fmt.Errorf("reset failed, error: %v", err)
+	}
+	if err := w.Clean(&gogit.CleanOptions{Dir: true}); err != nil {
+		return fmt.Errorf("clean failed, error: %v", err)
+	}
+	return nil
+}
+
+// Checkout checks out a commit with detached HEAD.
+func (r *Repo) Checkout(commitLike string) error {
+	logrus.Infof("Checkout %s.", commitLike)
+	h, err := r.resolve(commitLike)
+	if err != nil {
+		return fmt.Errorf("error checking out %s: %v", commitLike, err)
+	}
+	w, err := r.worktree()
+	if err != nil {
+		return err
+	}
+	if err := w.Checkout(&gogit.CheckoutOptions{Hash: h, Force: true}); err != nil {
+		return fmt.Errorf("error checking out %s: %v", commitLike, err)
+	}
+	return nil
+}
+
+// CheckoutNewBranch creates a new branch at HEAD and checks it out.
+func (r *Repo) CheckoutNewBranch(branch string, force bool) error {
+	logrus.Infof("Create and checkout %s.", branch)
+	name := plumbing.NewBranchReferenceName(branch)
+	head, err := r.repo.Head()
+	if err != nil {
+		return err
+	}
+	if force && head.Name() != name {
+		_ = r.repo.Storer.RemoveReference(name)
+	}
+	w, err := r.worktree()
+	if err != nil {
+		return err
+	}
+	err = w.Checkout(&gogit.CheckoutOptions{Hash: head.Hash(), Branch: name, Create: true, Force: true})
+	if err != nil {
+		return fmt.Errorf("error checking out %s: %v", branch, err)
+	}
+	return nil
+}
+
+func (r *Repo) fetch(remote, url string, refSpecs ...config.RefSpec) error {
+	err := r.repo.Fetch(&gogit.FetchOptions{
+		RemoteName: remote,
+		RemoteURL:  url,
+		RefSpecs:   refSpecs,
+		Auth:       r.client.auth(),
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
+
+func (r *Repo) push(remote string, refSpecs ...config.RefSpec) error {
+	err := r.repo.Push(&gogit.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   refSpecs,
+		Auth:       r.client.auth(),
+	})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
+
+// FetchPullRequest fetches the head of a pull request to origin/pull/<number>.
+func (r *Repo) FetchPullRequest(number int) error {
+	logrus.Infof("Fetching %s/%s#%d.", r.owner, r.name, number)
+	src := fmt.Sprintf(r.client.pullRequestRef, number)
+	ref := config.RefSpec(fmt.Sprintf("+%s:refs/remotes/origin/pull/%d", src, number))
+	if err := r.fetch("origin", "", ref); err != nil {
+		return fmt.Errorf("git fetch failed for PR %d: %v", number, err)
+	}
+	return nil
+}
+
+// commitRange returns the commits of first^..last along first parents, oldest first.
+func (r *Repo) commitRange(first, last string) ([]*object.Commit, error) {
+	firstHash, err := r.resolve(first)
+	if err != nil {
+		return nil, err
+	}
+	lastHash, err := r.resolve(last)
+	if err != nil {
+		return nil, err
+	}
+	var commits []*object.Commit
+	c, err := r.repo.CommitObject(lastHash)
+	for err == nil {
+		commits = append([]*object.Commit{c}, commits...)
+		if c.Hash == firstHash {
+			return commits, nil
+		}
+		c, err = c.Parent(0)
+	}
+	return nil, fmt.Errorf("%s is not an ancestor of %s", first, last)
+}
+
+// fileContent returns the content of path in commit, ok is false if not found.
+func fileContent(c *object.Commit, path string) (content string, ok bool, err error) {
+	f, err := c.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	content, err = f.Contents()
+	return content, err == nil, err
+}
+
+// write writes content to path in the worktree with the mode of file, and
+// stages it.
+func (r *Repo) write(w *gogit.Worktree, path string, file *object.File, content []byte) error {
+	mode, err := file.Mode.ToOSFileMode()
+	if err != nil {
+		return err
+	}
+	if err := util.WriteFile(w.Filesystem, path, content, mode); err != nil {
+		return err
+	}
+	_, err = w.Add(path)
+	return err
+}
+
+// pick applies the changes of commit onto HEAD. A file can only be changed if
+// its content in HEAD equals the content in the parent of commit, otherwise it
+// is a conflict, unless it is resolved by the merge driver of the file.
+func (r *Repo) pick(commit *object.Commit) error {
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return err
+	}
+	head, err := r.head()
+	if err != nil {
+		return err
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return err
+	}
+	w, err := r.worktree()
+	if err != nil {
+		return err
+	}
+	var conflicts []string
+	for _, change := range changes {
+		from, to, err := change.Files()
+		if err != nil {
+			return err
+		}
+		path := change.To.Name
+		if to == nil {
+			path = change.From.Name
+		}
+		base, baseOK, err := fileContent(parent, path)
+		if err != nil {
+			return err
+		}
+		ours, oursOK, err := fileContent(head, path)
+		if err != nil {
+			return err
+		}
+		var theirs string
+		if to != nil {
+			if theirs, err = to.Contents(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case to == nil && !oursOK:
+			// deleted on both sides
+		case to != nil && oursOK && ours == theirs:
+			// already applied
+		case oursOK != baseOK || ours != base:
+			driver := r.drivers.Lookup(path)
+			if driver == nil || to == nil || !oursOK || !baseOK {
+				conflicts = append(conflicts, path)
+				break
+			}
+			merged, err := driver([]byte(base), []byte(ours), []byte(theirs))
+			if err != nil {
+				logrus.Warnf("Resolve conflicts of %s failed: %v.", path, err)
+				conflicts = append(conflicts, path)
+				break
+			}
+			logrus.Infof("Resolved conflicts of %s.", path)
+			if err := r.write(w, path, to, merged); err != nil {
+				return err
+			}
+		case to == nil:
+			if _, err := w.Remove(path); err != nil {
+				return err
+			}
+		default:
+			if err := r.write(w, path, to, []byte(theirs)); err != nil {
+				return err
+			}
+		}
+		// a renamed file removes its old path
+		if from != nil && to != nil && change.From.Name != change.To.Name {
+			if _, err := w.Remove(change.From.Name); err != nil {
+				return err
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("conflicts in %v", conflicts)
+	}
+
+	message := strings.TrimRight(commit.Message, "\n") +
+		fmt.Sprintf("\n\n(cherry picked from commit %s)\n", commit.Hash)
+	committer := commit.Committer
+	committer.When = time.Now()
+	_, err = w.Commit(message, &gogit.CommitOptions{
+		Author:            &commit.Author,
+		Committer:         &committer,
+		AllowEmptyCommits: true,
+	})
+	return err
+}
+
+// CherryPick applies the commits of first^..last onto HEAD.
+func (r *Repo) CherryPick(first, last string, strategyOption git.StrategyOption) error {
+	logrus.Infof("Cherry Pick from %s to %s.", first, last)
+	commits, err := r.commitRange(first, last)
+	if err != nil {
+		return fmt.Errorf("cherry pick failed, error: %v", err)
+	}
+	for _, c := range commits {
+		if err := r.pick(c); err != nil {
+			_ = r.Clean()
+			return fmt.Errorf("cherry pick %s failed, error: %v", c.Hash, err)
+		}
+	}
+	return nil
+}
+
+// SetMergeDriver resolves the conflicts of files matching pattern in
+// cherry-picks with driver.
+func (r *Repo) SetMergeDriver(pattern string, driver git.MergeDriver) {
+	if r.drivers == nil {
+		r.drivers = make(git.MergeDrivers)
+	}
+	r.drivers[pattern] = driver
+}
+
+// CherryPickAbort discards an uncompleted cherry-pick.
+func (r *Repo) CherryPickAbort() error {
+	return r.Clean()
+}
+
+// descendant returns the commits of HEAD and ref, ref must be a descendant of
+// HEAD as diverged histories can't be merged in-process.
+func (r *Repo) descendant(ref string) (head, target *object.Commit, err error) {
+	head, err = r.head()
+	if err != nil {
+		return nil, nil, err
+	}
+	h, err := r.resolve(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err = r.repo.CommitObject(h)
+	if err != nil {
+		return nil, nil, err
+	}
+	ok, err := head.IsAncestor(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a descendant of HEAD, in-process merge doesn't support diverged histories", ref)
+	}
+	return head, target, nil
+}
+
+// moveHead points HEAD, or the branch checked out, to h and resets the
+// worktree to it.
+func (r *Repo) moveHead(h plumbing.Hash) error {
+	headRef, err := r.repo.Head()
+	if err != nil {
+		return err
+	}
+	w, err := r.worktree()
+	if err != nil {
+		return err
+	}
+	if headRef.Name() == plumbing.HEAD {
+		return w.Checkout(&gogit.CheckoutOptions{Hash: h, Force: true})
+	}
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(headRef.Name(), h)); err != nil {
+		return err
+	}
+	return w.Reset(&gogit.ResetOptions{Commit: h, Mode: gogit.HardReset})
+}
+
+// fastForward moves HEAD to ref, which must be a descendant of HEAD.
+func (r *Repo) fastForward(ref string) error {
+	_, target, err := r.descendant(ref)
+	if err != nil {
+		return err
+	}
+	return r.moveHead(target.Hash)
+}
+
+// mergeCommit creates a merge commit of HEAD and ref even if HEAD could be
+// fast-forwarded, ref must be a descendant of HEAD.
+func (r *Repo) mergeCommit(ref string) error {
+	head, target, err := r.descendant(ref)
+	if err != nil {
+		return err
+	}
+	if head.Hash == target.Hash {
+		return nil
+	}
+	if err := r.moveHead(target.Hash); err != nil {
+		return err
+	}
+	w, err := r.worktree()
+	if err != nil {
+		return err
+	}
+	sig := target.Committer
+	sig.When = time.Now()
+	_, err = w.Commit(fmt.Sprintf("Merge %s\n", ref), &gogit.CommitOptions{
+		Author:            &sig,
+		Committer:         &sig,
+		Parents:           []plumbing.Hash{head.Hash, target.Hash},
+		AllowEmptyCommits: true,
+	})
+	return err
+}
+
+// squash stages the changes of ref onto HEAD without committing them, ref
+// must be a descendant of HEAD.
+func (r *Repo) squash(ref string) error {
+	head, target, err := r.descendant(ref)
+	if err != nil {
+		return err
+	}
+	headRef, err := r.repo.Head()
+	if err != nil {
+		return err
+	}
+	if err := r.moveHead(target.Hash); err != nil {
+		return err
+	}
+	// keep the worktree and index of ref but move HEAD back
+	return r.repo.Storer.SetReference(plumbing.NewHashReference(headRef.Name(), head.Hash))
+}
+
+// Merge incorporates changes from other branch. The in-process merge supports
+// git.MergeFF, git.MergeNoFF and git.MergeSquash of descendants of HEAD.
+func (r *Repo) Merge(ref string, option git.MergeOption) error {
+	logrus.Infof("Running git merge %v %s", option, ref)
+	var err error
+	switch option {
+	case git.MergeFF:
+		err = r.fastForward(ref)
+	case git.MergeNoFF:
+		err = r.mergeCommit(ref)
+	case git.MergeSquash:
+		err = r.squash(ref)
+	default:
+		err = fmt.Errorf("unsupported in-process, use %s, %s or %s", git.MergeFF, git.MergeNoFF, git.MergeSquash)
+	}
+	if err != nil {
+		return fmt.Errorf("git merge %s %s failed: %v", option, ref, err)
+	}
+	return nil
+}
+
+// Push pushes a local branch to origin.
+func (r *Repo) Push(branch string, force bool) error {
+	logrus.Infof("Pushing to '%s/%s (branch: %s)'.", r.owner, r.name, branch)
+	spec := fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch)
+	if force {
+		spec = "+" + spec
+	}
+	if err := r.push("origin", config.RefSpec(spec)); err != nil {
+		return fmt.Errorf("pushing failed, error: %v", err)
+	}
+	return nil
+}
+
+// RemoteBranchExists returns true if branch exists in origin.
+func (r *Repo) RemoteBranchExists(branch string) bool {
+	remote, err := r.repo.Remote("origin")
+	if err != nil {
+		return false
+	}
+	refs, err := remote.List(&gogit.ListOptions{Auth: r.client.auth()})
+	if err != nil {
+		return false
+	}
+	for _, ref := range refs {
+		if ref.Name() == plumbing.NewBranchReferenceName(branch) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteRemoteBranch deletes a branch of origin.
+func (r *Repo) DeleteRemoteBranch(branch string) error {
+	logrus.Infof("Delete remote branch '%s/%s (branch: %s)'.", r.owner, r.name, branch)
+	if err := r.push("origin", config.RefSpec(":refs/heads/"+branch)); err != nil {
+		return fmt.Errorf("delete remote branch %s failed, error: %v", branch, err)
+	}
+	return nil
+}
+
+// ListRemote returns true if the upstream remote exists.
+func (r *Repo) ListRemote() (bool, error) {
+	_, err := r.repo.Remote(upstream)
+	if errors.Is(err, gogit.ErrRemoteNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// AddRemote adds the upstream remote.
+func (r *Repo) AddRemote(remotePath string) error {
+	logrus.Infof("Add remote %s", remotePath)
+	_, err := r.repo.CreateRemote(&config.RemoteConfig{Name: upstream, URLs: []string{remotePath}})
+	return err
+}
+
+// FetchUpstream fetches a branch of upstream to upstream/<branch>.
+func (r *Repo) FetchUpstream(branch string) error {
+	logrus.Infof("fetch upstream branch %s", branch)
+	spec := config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, upstream, branch))
+	if err := r.fetch(upstream, "", spec); err != nil {
+		return fmt.Errorf("git fetch %s failed, err: %v", branch, err)
+	}
+	return nil
+}
+
+// MergeUpstream fast-forwards HEAD to upstream/<branch>.
+func (r *Repo) MergeUpstream(branch string) error {
+	logrus.Infof("merge upstream branch %s", branch)
+	if err := r.fastForward(upstream + "/" + branch); err != nil {
+		return fmt.Errorf("git merge %s failed, err: %v", branch, err)
+	}
+	return nil
+}
+
+// PushUpstreamToOrigin pushes HEAD to a branch of origin.
+func (r *Repo) PushUpstreamToOrigin(branch string) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return err
+	}
+	// push needs a named source reference
+	tmp := plumbing.ReferenceName("refs/sync-bot/" + branch)
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(tmp, head.Hash())); err != nil {
+		return err
+	}
+	defer func() { _ = r.repo.Storer.RemoveReference(tmp) }()
+	if err := r.push("origin", config.RefSpec(fmt.Sprintf("%s:refs/heads/%s", tmp, branch))); err != nil {
+		return fmt.Errorf("pushing failed, error: %v", err)
+	}
+	return nil
+}
+
+// CreateBranchAndPushToOrigin creates a branch from upstream and pushes it to origin.
+func (r *Repo) CreateBranchAndPushToOrigin(branch, upstreamRef string) error {
+	logrus.Infof("Create new branch from upstream")
+	if err := r.Checkout(upstreamRef); err != nil {
+		return fmt.Errorf("create branch by upstream failed, err: %v", err)
+	}
+	if err := r.CheckoutNewBranch(branch, false); err != nil {
+		return fmt.Errorf("create branch by upstream failed, err: %v", err)
+	}
+	return r.Push(branch, false)
+}
+
+// ReadFile returns the content of a file in the worktree, it helps to inspect
+// the in-memory worktree.
+func (r *Repo) ReadFile(path string) (string, error) {
+	w, err := r.worktree()
+	if err != nil {
+		return "", err
+	}
+	f, err := w.Filesystem.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	return string(b), err
+}
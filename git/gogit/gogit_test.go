@@ -0,0 +1,208 @@
+package gogit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/git"
+	"sync-bot/internal/gittest"
+)
+
+func TestCherryPick(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	remote.Commit("a", "a\n")
+	remote.Branch("branch1")
+	remote.Commit("a", "a1\n")
+	remote.Push("refs/heads/master:refs/heads/master", "refs/heads/branch1:refs/heads/branch1")
+	first := remote.Commit("b", "b\n")
+	last := remote.Commit("a", "a2\n")
+	remote.Push("refs/heads/master:refs/pull/1/head")
+
+	var c git.Backend = NewClient("file://" + root)
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := r.Checkout("origin/branch1"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if err := r.CheckoutNewBranch("sync-pr1-master-to-branch1", true); err != nil {
+		t.Fatalf("CheckoutNewBranch failed: %v", err)
+	}
+	if err := r.FetchPullRequest(1); err != nil {
+		t.Fatalf("FetchPullRequest failed: %v", err)
+	}
+
+	// a was changed on master before the pull request
+	if err := r.CherryPick(first, last, git.Theirs); err == nil {
+		t.Fatalf("CherryPick should conflict")
+	}
+	if err := r.CherryPick(first, first, git.Theirs); err != nil {
+		t.Fatalf("CherryPick failed: %v", err)
+	}
+	if got, _ := r.(*Repo).ReadFile("b"); got != "b\n" {
+		t.Errorf("content of b = %q, want %q", got, "b\n")
+	}
+
+	if err := r.Push("sync-pr1-master-to-branch1", true); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if !r.RemoteBranchExists("sync-pr1-master-to-branch1") {
+		t.Errorf("pushed branch not found")
+	}
+	if err := r.DeleteRemoteBranch("sync-pr1-master-to-branch1"); err != nil {
+		t.Fatalf("DeleteRemoteBranch failed: %v", err)
+	}
+	if r.RemoteBranchExists("sync-pr1-master-to-branch1") {
+		t.Errorf("deleted branch still exists")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	remote.Push("refs/heads/master:refs/heads/master")
+	for i := 0; i < 3; i++ {
+		remote.Commit("a", fmt.Sprintf("%d\n", i))
+	}
+	remote.Push("refs/heads/master:refs/pull/2/head")
+
+	c := NewClient("file://" + root)
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := r.Checkout("origin/master"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if err := r.CheckoutNewBranch("master", true); err != nil {
+		t.Fatalf("CheckoutNewBranch failed: %v", err)
+	}
+	if err := r.FetchPullRequest(2); err != nil {
+		t.Fatalf("FetchPullRequest failed: %v", err)
+	}
+	if err := r.Merge("origin/pull/2", git.MergeFF); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if got, _ := r.(*Repo).ReadFile("a"); got != "2\n" {
+		t.Errorf("content of a = %q, want %q", got, "2\n")
+	}
+
+	repos, _ := c.CachedRepos()
+	if len(repos) != 1 || repos[0].Name != "owner/repo" {
+		t.Errorf("CachedRepos() = %v", repos)
+	}
+}
+
+func TestCherryPickMergeDriver(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("a", "ours\n")
+	remote.Branch("branch1")
+	remote.Commit("a", "base\n")
+	remote.Push("refs/heads/master:refs/heads/master", "refs/heads/branch1:refs/heads/branch1")
+	first := remote.Commit("a", "theirs\n")
+	last := remote.Commit("b", "b\n")
+	remote.Push("refs/heads/master:refs/pull/1/head")
+
+	c := NewClient("file://" + root)
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := r.Checkout("origin/branch1"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if err := r.CheckoutNewBranch("sync", true); err != nil {
+		t.Fatalf("CheckoutNewBranch failed: %v", err)
+	}
+	if err := r.FetchPullRequest(1); err != nil {
+		t.Fatalf("FetchPullRequest failed: %v", err)
+	}
+	r.SetMergeDriver("a", func(base, ours, theirs []byte) ([]byte, error) {
+		return []byte(fmt.Sprintf("%s%s%s", base, ours, theirs)), nil
+	})
+	if err := r.CherryPick(first, last, git.Theirs); err != nil {
+		t.Fatalf("CherryPick failed: %v", err)
+	}
+	if got, _ := r.(*Repo).ReadFile("a"); got != "base\nours\ntheirs\n" {
+		t.Errorf("content of a = %q, want %q", got, "base\nours\ntheirs\n")
+	}
+	if got, _ := r.(*Repo).ReadFile("b"); got != "b\n" {
+		t.Errorf("content of b = %q, want %q", got, "b\n")
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	base := remote.Commit("README", "init\n")
+	remote.Push("refs/heads/master:refs/heads/master")
+	remote.Commit("a", "1\n")
+	pr := remote.Commit("a", "2\n")
+	remote.Push("refs/heads/master:refs/pull/2/head")
+
+	tests := []struct {
+		option  git.MergeOption
+		parents []string
+		staged  bool
+		wantErr bool
+	}{
+		{git.MergeFF, nil, false, false},
+		{git.MergeNoFF, []string{base, pr}, false, false},
+		{git.MergeSquash, nil, true, false},
+		{git.MergeOption("--ff-only"), nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.option), func(t *testing.T) {
+			r, err := NewClient("file://"+root).Clone("owner", "repo")
+			if err != nil {
+				t.Fatalf("Clone failed: %v", err)
+			}
+			if err := r.Checkout("origin/master"); err != nil {
+				t.Fatalf("Checkout failed: %v", err)
+			}
+			if err := r.CheckoutNewBranch("master", true); err != nil {
+				t.Fatalf("CheckoutNewBranch failed: %v", err)
+			}
+			if err := r.FetchPullRequest(2); err != nil {
+				t.Fatalf("FetchPullRequest failed: %v", err)
+			}
+			err = r.Merge("origin/pull/2", tt.option)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Merge() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got, _ := r.(*Repo).ReadFile("a"); got != "2\n" {
+				t.Errorf("content of a = %q, want %q", got, "2\n")
+			}
+			head, _ := r.(*Repo).head()
+			switch {
+			case tt.parents != nil:
+				var parents []string
+				for _, p := range head.ParentHashes {
+					parents = append(parents, p.String())
+				}
+				if !reflect.DeepEqual(parents, tt.parents) {
+					t.Errorf("parents of HEAD = %v, want %v", parents, tt.parents)
+				}
+			case tt.staged:
+				status, _ := r.Status()
+				if head.Hash.String() != base || !strings.Contains(status, "A  a") {
+					t.Errorf("HEAD = %s with status %q, want %s with a staged", head.Hash, status, base)
+				}
+			default:
+				if head.Hash.String() != pr {
+					t.Errorf("HEAD = %s, want %s", head.Hash, pr)
+				}
+			}
+		})
+	}
+}
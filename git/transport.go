@@ -0,0 +1,198 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Protocol transport protocol of git operations
+type Protocol string
+
+// Protocol enum
+const (
+	HTTPS Protocol = "https"
+	SSH   Protocol = "ssh"
+)
+
+// sshUser is the user of SSH remotes: "git" in "git@host:owner/repo.git".
+const sshUser = "git"
+
+// Transport configures how a repository is accessed.
+type Transport struct {
+	Protocol Protocol
+	// Key returns the private SSH key, usually a secret.
+	Key func() []byte
+	// KnownHosts is the path of the known_hosts file, SSH is refused without
+	// it unless TrustOnFirstUse.
+	KnownHosts string
+	// TrustOnFirstUse trusts the host keys on first use if KnownHosts is
+	// empty.
+	TrustOnFirstUse bool
+}
+
+// remote is how a cloned repository talks to its remotes.
+type remote struct {
+	// url returns the URL of owner/repo.
+	url func(owner, repo string) string
+	// authorize returns the arguments and environment which authorize the
+	// git command with arguments arg.
+	authorize func(arg []string) ([]string, []string)
+	// canPush returns an error if pushing is not authorized.
+	canPush func() error
+}
+
+// origin returns the URL of origin of owner/repo.
+func (r *remote) origin(owner, repo string) string {
+	// special for big size repos
+	if owner == "openeuler" && repo == "kernel" {
+		owner = "openeuler-sync-bot"
+	}
+	return r.url(owner, repo)
+}
+
+// SetTransport sets the function deciding how each repository is accessed,
+// repositories are accessed over HTTPS by default.
+func (c *Client) SetTransport(transport func(owner, repo string) Transport) {
+	c.transport = transport
+}
+
+// remote returns the remote of owner/repo over its transport.
+func (c *Client) remote(owner, repo string) (*remote, error) {
+	t := c.transport(owner, repo)
+	switch t.Protocol {
+	case "", HTTPS:
+		return &remote{
+			url: func(owner, repo string) string {
+				return fmt.Sprintf("%s/%s/%s.git", c.base, owner, repo)
+			},
+			authorize: func(arg []string) ([]string, []string) {
+				return authorize(c.getCredentials, c.base, arg)
+			},
+			canPush: func() error {
+				if user, pass := c.getCredentials(); user == "" || pass == "" {
+					return errors.New("cannot push without credentials - configure your git client")
+				}
+				return nil
+			},
+		}, nil
+	case SSH:
+		command, err := c.sshCommand(t)
+		if err != nil {
+			return nil, err
+		}
+		env := append(os.Environ(), "GIT_SSH_COMMAND="+command, "GIT_TERMINAL_PROMPT=0")
+		// the port of the web server is not the port of sshd
+		host := c.host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		return &remote{
+			url: func(owner, repo string) string {
+				return fmt.Sprintf("%s@%s:%s/%s.git", sshUser, host, owner, repo)
+			},
+			authorize: func(arg []string) ([]string, []string) {
+				return arg, env
+			},
+			canPush: func() error { return nil },
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport protocol %q", t.Protocol)
+	}
+}
+
+// sshCommand returns GIT_SSH_COMMAND of the transport.
+func (c *Client) sshCommand(t Transport) (string, error) {
+	if t.Key == nil || len(t.Key()) == 0 {
+		return "", errors.New("cannot use ssh without a key - configure your git client")
+	}
+	if t.KnownHosts == "" && !t.TrustOnFirstUse {
+		return "", errors.New("cannot use ssh without known_hosts - configure known_hosts, or trust_on_first_use explicitly")
+	}
+	dir, err := c.sshDirectory()
+	if err != nil {
+		return "", err
+	}
+	key, err := writeKey(dir, t.Key())
+	if err != nil {
+		return "", err
+	}
+	args := []string{c.ssh, "-i", key, "-o", "IdentitiesOnly=yes", "-o", "BatchMode=yes"}
+	if t.KnownHosts != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+t.KnownHosts, "-o", "StrictHostKeyChecking=yes")
+	} else {
+		logrus.Warnf("No known_hosts configured for %s, trusting its host key on first use.", c.host)
+		args = append(args, "-o", "UserKnownHostsFile="+filepath.Join(dir, "known_hosts"), "-o", "StrictHostKeyChecking=accept-new")
+	}
+	for i, a := range args {
+		args[i] = shellQuote(a)
+	}
+	return strings.Join(args, " "), nil
+}
+
+// sshDirectory returns the private directory of SSH keys and known hosts.
+// It is kept out of the repo cache, which only contains repos.
+func (c *Client) sshDirectory() (string, error) {
+	c.credLock.Lock()
+	defer c.credLock.Unlock()
+	if c.sshDir == "" {
+		dir, err := ioutil.TempDir("", "sync-bot-ssh")
+		if err != nil {
+			return "", err
+		}
+		c.sshDir = dir
+	}
+	return c.sshDir, nil
+}
+
+// writeKey writes key to dir and returns its path. ssh refuses keys readable
+// by others, so a key mounted as a secret can not be used in place.
+// The file is named by its content, a rotated key is written to a new file
+// and the files of the old keys are removed.
+func writeKey(dir string, key []byte) (string, error) {
+	sum := sha256.Sum256(key)
+	path := filepath.Join(dir, "id_"+hex.EncodeToString(sum[:8]))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	// ssh requires the key to end with a newline
+	if err := ioutil.WriteFile(path, append(key, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("write ssh key failed: %w", err)
+	}
+	old, _ := filepath.Glob(filepath.Join(dir, "id_*"))
+	for _, o := range old {
+		if o == path {
+			continue
+		}
+		if err := os.Remove(o); err != nil {
+			logrus.Warnf("Remove old ssh key failed: %v.", err)
+		}
+	}
+	return path, nil
+}
+
+// Close removes the SSH keys and known hosts written by the client, they are
+// written again if SSH is used afterwards.
+func (c *Client) Close() error {
+	c.credLock.Lock()
+	defer c.credLock.Unlock()
+	if c.sshDir == "" {
+		return nil
+	}
+	err := os.RemoveAll(c.sshDir)
+	c.sshDir = ""
+	return err
+}
+
+// shellQuote quotes s for the shell, GIT_SSH_COMMAND is run by the shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,202 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeSSH writes a stand-in of ssh which serves remote commands from the
+// remote directory, and records its arguments.
+func fakeSSH(t *testing.T, remote string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "args")
+	script := `#!/bin/sh
+echo "$@" >> '` + log + `'
+for a; do last=$a; done
+cd '` + remote + `' && exec sh -c "$last"
+`
+	ssh := filepath.Join(dir, "ssh")
+	if err := ioutil.WriteFile(ssh, []byte(script), 0755); err != nil {
+		t.Fatalf("Write fake ssh failed: %v", err)
+	}
+	return ssh, log
+}
+
+func TestSSHTransport(t *testing.T) {
+	c, remote := newLocalClient(t)
+	work := initBareRepo(t, remote, "owner", "repo")
+	ssh, log := fakeSSH(t, remote)
+	c.ssh = ssh
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	c.SetTransport(func(owner, repo string) Transport {
+		return Transport{Protocol: SSH, Key: func() []byte { return []byte("private key") }, KnownHosts: knownHosts}
+	})
+
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if got, want := runGit(t, r.Directory(), "remote", "get-url", "origin"), "git@gitee.com:owner/repo.git"; got != want {
+		t.Errorf("origin = %s, want %s", got, want)
+	}
+	if err := r.CheckoutNewBranch("sync", true); err != nil {
+		t.Fatalf("CheckoutNewBranch failed: %v", err)
+	}
+	if err := r.Push("sync", false); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if !r.RemoteBranchExists("sync") {
+		t.Errorf("branch sync not pushed")
+	}
+	runGit(t, work, "fetch", "origin")
+	runGit(t, work, "rev-parse", "origin/sync")
+
+	b, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Read ssh arguments failed: %v", err)
+	}
+	args := string(b)
+	for _, want := range []string{"-o IdentitiesOnly=yes", "-o UserKnownHostsFile=" + knownHosts, "-o StrictHostKeyChecking=yes", "git@gitee.com"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("ssh arguments %q do not contain %q", args, want)
+		}
+	}
+
+	keys, _ := filepath.Glob(filepath.Join(c.sshDir, "id_*"))
+	if len(keys) != 1 {
+		t.Fatalf("ssh keys = %v, want one key", keys)
+	}
+	if !strings.Contains(args, "-i "+keys[0]) {
+		t.Errorf("ssh arguments %q do not use key %s", args, keys[0])
+	}
+	info, err := os.Stat(keys[0])
+	if err != nil {
+		t.Fatalf("Stat ssh key failed: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("ssh key mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestSSHTransportWithoutKey(t *testing.T) {
+	c, remote := newLocalClient(t)
+	initBareRepo(t, remote, "owner", "repo")
+	c.SetTransport(func(owner, repo string) Transport {
+		return Transport{Protocol: SSH, Key: func() []byte { return nil }}
+	})
+	if _, err := c.Clone("owner", "repo"); err == nil {
+		t.Errorf("Clone without ssh key succeeded, want error")
+	}
+}
+
+func TestSSHTransportKnownHosts(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport Transport
+		want      string
+		wantErr   bool
+	}{
+		{"refused", Transport{}, "", true},
+		{"trust on first use", Transport{TrustOnFirstUse: true}, "-o StrictHostKeyChecking=accept-new", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, remote := newLocalClient(t)
+			initBareRepo(t, remote, "owner", "repo")
+			ssh, log := fakeSSH(t, remote)
+			c.ssh = ssh
+			c.SetTransport(func(owner, repo string) Transport {
+				tr := tt.transport
+				tr.Protocol = SSH
+				tr.Key = func() []byte { return []byte("private key") }
+				return tr
+			})
+			_, err := c.Clone("owner", "repo")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Clone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			b, _ := ioutil.ReadFile(log)
+			if !strings.Contains(string(b), tt.want) {
+				t.Errorf("ssh arguments %q do not contain %q", string(b), tt.want)
+			}
+		})
+	}
+}
+
+func TestSSHKeyRotation(t *testing.T) {
+	c, remote := newLocalClient(t)
+	initBareRepo(t, remote, "owner", "repo")
+	ssh, _ := fakeSSH(t, remote)
+	c.ssh = ssh
+	key := "key1"
+	c.SetTransport(func(owner, repo string) Transport {
+		return Transport{Protocol: SSH, Key: func() []byte { return []byte(key) }, TrustOnFirstUse: true}
+	})
+	if _, err := c.Clone("owner", "repo"); err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	key = "key2"
+	if _, err := c.Clone("owner", "repo"); err != nil {
+		t.Fatalf("Clone with rotated key failed: %v", err)
+	}
+	keys, _ := filepath.Glob(filepath.Join(c.sshDir, "id_*"))
+	if len(keys) != 1 {
+		t.Errorf("ssh keys = %v, want only the rotated key", keys)
+	} else if b, _ := ioutil.ReadFile(keys[0]); string(b) != "key2\n" {
+		t.Errorf("ssh key = %q, want %q", string(b), "key2\n")
+	}
+
+	dir := c.sshDir
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("ssh directory %s not removed: %v", dir, err)
+	}
+	// keys are written again by the next clone
+	if _, err := c.Clone("owner", "repo"); err != nil {
+		t.Fatalf("Clone after Close failed: %v", err)
+	}
+	if keys, _ := filepath.Glob(filepath.Join(c.sshDir, "id_*")); len(keys) != 1 {
+		t.Errorf("ssh keys after Close = %v, want one key", keys)
+	}
+}
+
+func TestNewClientWithURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		origin  string
+		dir     string
+		wantErr bool
+	}{
+		{"gitee", "https://gitee.com", "https://gitee.com/owner/repo.git", "repos", false},
+		{"private", "http://git.example.com:8080/", "http://git.example.com:8080/owner/repo.git", "repos-git.example.com:8080", false},
+		{"no host", "gitee.com", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClientWithURL(tt.base)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewClientWithURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.dir != tt.dir {
+				t.Errorf("dir = %v, want %v", c.dir, tt.dir)
+			}
+			r, _ := c.remote("owner", "repo")
+			if got := r.url("owner", "repo"); got != tt.origin {
+				t.Errorf("url() = %v, want %v", got, tt.origin)
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+// Package gitea provides the Gitea (and Forgejo) implementation of the API
+// client and the webhook of sync-bot. Gitea objects are converted to the model
+// of package gitee, so the same workflow serves both.
+package gitea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sync-bot/gitee"
+)
+
+// perPage is the maximum page size of the default Gitea configuration.
+const perPage = 50
+
+type user struct {
+	ID       int    `json:"id"`
+	Login    string `json:"login"`
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	HTMLURL  string `json:"html_url"`
+}
+
+type repository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Owner         user   `json:"owner"`
+	HTMLURL       string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+	Private       bool   `json:"private"`
+	Fork          bool   `json:"fork"`
+}
+
+type pullRequestBranch struct {
+	Label string     `json:"label"`
+	Ref   string     `json:"ref"`
+	Sha   string     `json:"sha"`
+	Repo  repository `json:"repo"`
+}
+
+type label struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type pullRequest struct {
+	ID             int               `json:"id"`
+	Number         int               `json:"number"`
+	Title          string            `json:"title"`
+	Body           string            `json:"body"`
+	State          string            `json:"state"`
+	Merged         bool              `json:"merged"`
+	Mergeable      bool              `json:"mergeable"`
+	MergeCommitSha string            `json:"merge_commit_sha"`
+	HTMLURL        string            `json:"html_url"`
+	DiffURL        string            `json:"diff_url"`
+	PatchURL       string            `json:"patch_url"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Head           pullRequestBranch `json:"head"`
+	Base           pullRequestBranch `json:"base"`
+	User           user              `json:"user"`
+	Labels         []label           `json:"labels"`
+	Comments       int               `json:"comments"`
+}
+
+type branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+type comment struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	User      user      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type gitUser struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+type commit struct {
+	Sha     string `json:"sha"`
+	URL     string `json:"url"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Author    gitUser `json:"author"`
+		Committer gitUser `json:"committer"`
+		Message   string  `json:"message"`
+		URL       string  `json:"url"`
+	} `json:"commit"`
+	Author    *user `json:"author"`
+	Committer *user `json:"committer"`
+	Parents   []struct {
+		Sha string `json:"sha"`
+		URL string `json:"url"`
+	} `json:"parents"`
+}
+
+type file struct {
+	Filename         string `json:"filename"`
+	Status           string `json:"status"`
+	Additions        int    `json:"additions"`
+	Deletions        int    `json:"deletions"`
+	Changes          int    `json:"changes"`
+	HTMLURL          string `json:"html_url"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+type tree struct {
+	Tree       []treeEntry `json:"tree"`
+	TotalCount int         `json:"total_count"`
+}
+
+// client Gitea API implementation of gitee.Client
+type client struct {
+	token func() []byte
+	// base is the URL of the API, "https://gitea.com/api/v1".
+	base       string
+	httpClient *http.Client
+}
+
+var _ gitee.Client = (*client)(nil)
+
+// NewClient client to access the Gitea instance at web, such as
+// "https://gitea.com".
+func NewClient(getToken func() []byte, web string) gitee.Client {
+	return &client{
+		token:      getToken,
+		base:       strings.TrimSuffix(web, "/") + "/api/v1",
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Error is returned for failed requests of the Gitea API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gitea: %d %s", e.StatusCode, e.Message)
+}
+
+// request sends a request to path and decodes the JSON response into out.
+func (c *client) request(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.token(); len(token) > 0 {
+		req.Header.Set("Authorization", "token "+string(token))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &e) != nil || e.Message == "" {
+			e.Message = string(b)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: e.Message}
+	}
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*o = b
+		return nil
+	default:
+		return json.Unmarshal(b, out)
+	}
+}
+
+// list requests all pages of path, page is decoded by decode, which returns
+// the number of items of the page.
+func (c *client) list(path string, decode func(b []byte) (int, error)) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for page := 1; ; page++ {
+		var b []byte
+		if err := c.request(http.MethodGet, fmt.Sprintf("%s%slimit=%d&page=%d", path, sep, perPage, page), nil, &b); err != nil {
+			return err
+		}
+		n, err := decode(b)
+		if err != nil {
+			return err
+		}
+		if n < perPage {
+			return nil
+		}
+	}
+}
+
+func repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+func (c *client) GetBranches(owner, repo string, onlyProtected bool) ([]gitee.Branch, error) {
+	branches := make([]gitee.Branch, 0)
+	err := c.list(repoPath(owner, repo)+"/branches", func(b []byte) (int, error) {
+		var bs []branch
+		if err := json.Unmarshal(b, &bs); err != nil {
+			return 0, err
+		}
+		for _, b := range bs {
+			if onlyProtected && !b.Protected {
+				continue
+			}
+			branches = append(branches, gitee.Branch{Name: b.Name, Protected: b.Protected})
+		}
+		return len(bs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+func (c *client) GetBranch(owner, repo, branchName string) (gitee.Branch, error) {
+	var b branch
+	if err := c.request(http.MethodGet, repoPath(owner, repo)+"/branches/"+url.PathEscape(branchName), nil, &b); err != nil {
+		return gitee.Branch{}, err
+	}
+	return gitee.Branch{Name: b.Name, Protected: b.Protected}, nil
+}
+
+// CreateBranch creates branchName at ref, a branch, tag or commit sha.
+func (c *client) CreateBranch(owner, repo, branchName, ref string) error {
+	in := map[string]string{"new_branch_name": branchName, "old_ref_name": ref}
+	return c.request(http.MethodPost, repoPath(owner, repo)+"/branches", in, nil)
+}
+
+func (c *client) GetTextFile(owner, repo, filepath, ref string) (string, error) {
+	var b []byte
+	path := repoPath(owner, repo) + "/raw/" + strings.TrimPrefix(filepath, "/") + "?ref=" + url.QueryEscape(ref)
+	if err := c.request(http.MethodGet, path, nil, &b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ListFiles lists the paths of the files in the tree of ref recursively.
+func (c *client) ListFiles(owner, repo, ref string) ([]string, error) {
+	var files []string
+	// the entries of trees are paged by per_page rather than limit
+	for page, n := 1, 0; ; page++ {
+		var t tree
+		path := fmt.Sprintf("%s/git/trees/%s?recursive=true&per_page=%d&page=%d",
+			repoPath(owner, repo), url.PathEscape(ref), perPage, page)
+		if err := c.request(http.MethodGet, path, nil, &t); err != nil {
+			return nil, err
+		}
+		for _, e := range t.Tree {
+			if e.Type == "blob" {
+				files = append(files, e.Path)
+			}
+		}
+		n += len(t.Tree)
+		if len(t.Tree) == 0 || n >= t.TotalCount {
+			return files, nil
+		}
+	}
+}
+
+// GetPermission returns the permission of username on the repository, one of
+// "owner", "admin", "write", "read" or "none".
+func (c *client) GetPermission(owner, repo, username string) (string, error) {
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	path := repoPath(owner, repo) + "/collaborators/" + url.PathEscape(username) + "/permission"
+	if err := c.request(http.MethodGet, path, nil, &p); err != nil {
+		return "", err
+	}
+	return p.Permission, nil
+}
+
+func (c *client) GetPullRequests(owner, repo string) ([]gitee.PullRequest, error) {
+	var prs []gitee.PullRequest
+	err := c.list(repoPath(owner, repo)+"/pulls?state=open", func(b []byte) (int, error) {
+		var ps []pullRequest
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return 0, err
+		}
+		for _, p := range ps {
+			prs = append(prs, convertPullRequest(p))
+		}
+		return len(ps), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+func (c *client) GetPullRequest(owner, repo string, number int) (*gitee.PullRequest, error) {
+	var p pullRequest
+	if err := c.request(http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), nil, &p); err != nil {
+		return nil, err
+	}
+	pr := convertPullRequest(p)
+	return &pr, nil
+}
+
+func (c *client) GetPullRequestChanges(owner, repo string, number int) ([]gitee.PullRequestChange, error) {
+	var changes []gitee.PullRequestChange
+	err := c.list(fmt.Sprintf("%s/pulls/%d/files", repoPath(owner, repo), number), func(b []byte) (int, error) {
+		var fs []file
+		if err := json.Unmarshal(b, &fs); err != nil {
+			return 0, err
+		}
+		for _, f := range fs {
+			changes = append(changes, gitee.PullRequestChange{
+				Filename:         f.Filename,
+				Status:           f.Status,
+				Additions:        f.Additions,
+				Deletions:        f.Deletions,
+				Changes:          f.Changes,
+				BlobURL:          f.HTMLURL,
+				PreviousFilename: f.PreviousFilename,
+			})
+		}
+		return len(fs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (c *client) GetPullRequestPatch(owner, repo string, number int) ([]byte, error) {
+	var b []byte
+	err := c.request(http.MethodGet, fmt.Sprintf("%s/pulls/%d.patch", repoPath(owner, repo), number), nil, &b)
+	return b, err
+}
+
+// CreatePullRequest creates a pull request. Gitea deletes merged branches
+// by a setting of the repository, pruneSourceBranch is ignored.
+func (c *client) CreatePullRequest(owner, repo, title, body, head, base string, pruneSourceBranch bool) (int, error) {
+	in := map[string]string{
+		"title": title,
+		"body":  body,
+		"head":  head,
+		"base":  base,
+	}
+	var p pullRequest
+	if err := c.request(http.MethodPost, repoPath(owner, repo)+"/pulls", in, &p); err != nil {
+		return 0, err
+	}
+	return p.Number, nil
+}
+
+func (c *client) ListPullRequestComments(owner, repo string, number int) ([]gitee.Comment, error) {
+	var cs []comment
+	if err := c.request(http.MethodGet, fmt.Sprintf("%s/issues/%d/comments", repoPath(owner, repo), number), nil, &cs); err != nil {
+		return nil, err
+	}
+	comments := make([]gitee.Comment, 0, len(cs))
+	for _, c := range cs {
+		comments = append(comments, convertComment(c))
+	}
+	return comments, nil
+}
+
+func (c *client) ClosePullRequest(owner, repo string, number int) error {
+	in := map[string]string{"state": "closed"}
+	return c.request(http.MethodPatch, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), in, nil)
+}
+
+func (c *client) CreateComment(owner, repo string, number int, body string) error {
+	in := map[string]string{"body": body}
+	return c.request(http.MethodPost, fmt.Sprintf("%s/issues/%d/comments", repoPath(owner, repo), number), in, nil)
+}
+
+// ListPullRequestCommits lists the commits of a pull request, the latest
+// first like Gitee.
+func (c *client) ListPullRequestCommits(owner, repo string, number int) ([]gitee.PullRequestCommit, error) {
+	var commits []gitee.PullRequestCommit
+	err := c.list(fmt.Sprintf("%s/pulls/%d/commits", repoPath(owner, repo), number), func(b []byte) (int, error) {
+		var cs []commit
+		if err := json.Unmarshal(b, &cs); err != nil {
+			return 0, err
+		}
+		for _, c := range cs {
+			commits = append(commits, convertCommit(c))
+		}
+		return len(cs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// ListPullRequestIssues returns no issues, Gitea has no API for the issues
+// linked to a pull request.
+func (c *client) ListPullRequestIssues(owner, repo string, number int) ([]gitee.Issue, error) {
+	return nil, nil
+}
+
+func convertUser(u user) gitee.User {
+	return gitee.User{
+		Email:    u.Email,
+		HTMLURL:  u.HTMLURL,
+		ID:       u.ID,
+		Name:     u.FullName,
+		Username: u.Login,
+	}
+}
+
+func convertRepository(r repository) gitee.Repository {
+	return gitee.Repository{
+		DefaultBranch:     r.DefaultBranch,
+		Fork:              r.Fork,
+		HTMLURL:           r.HTMLURL,
+		Name:              r.Name,
+		Namespace:         r.Owner.Login,
+		Owner:             convertUser(r.Owner),
+		Path:              r.Name,
+		PathWithNamespace: r.FullName,
+		Private:           r.Private,
+	}
+}
+
+func convertBranch(b pullRequestBranch) gitee.PullRequestBranch {
+	return gitee.PullRequestBranch{
+		Label: b.Label,
+		Ref:   b.Ref,
+		Repo:  convertRepository(b.Repo),
+		Sha:   b.Sha,
+	}
+}
+
+func convertPullRequest(p pullRequest) gitee.PullRequest {
+	state := gitee.State(p.State)
+	if p.Merged {
+		state = gitee.StateMerged
+	}
+	labels := make([]gitee.Label, 0, len(p.Labels))
+	for _, l := range p.Labels {
+		labels = append(labels, gitee.Label{Color: l.Color, ID: l.ID, Name: l.Name})
+	}
+	return gitee.PullRequest{
+		Base:           convertBranch(p.Base),
+		Body:           p.Body,
+		Comments:       p.Comments,
+		CreatedAt:      p.CreatedAt,
+		DiffURL:        p.DiffURL,
+		Head:           convertBranch(p.Head),
+		HTMLURL:        p.HTMLURL,
+		ID:             p.ID,
+		Labels:         labels,
+		MergeCommitSha: p.MergeCommitSha,
+		Mergeable:      p.Mergeable,
+		Merged:         p.Merged,
+		Number:         p.Number,
+		PatchURL:       p.PatchURL,
+		State:          state,
+		Title:          p.Title,
+		User:           convertUser(p.User),
+	}
+}
+
+func convertComment(c comment) gitee.Comment {
+	return gitee.Comment{
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+		HTMLURL:   c.HTMLURL,
+		ID:        c.ID,
+		UpdatedAt: c.UpdatedAt,
+		User:      convertUser(c.User),
+	}
+}
+
+func convertCommit(c commit) gitee.PullRequestCommit {
+	var author, committer gitee.User
+	if c.Author != nil {
+		author = convertUser(*c.Author)
+	}
+	if c.Committer != nil {
+		committer = convertUser(*c.Committer)
+	}
+	var parents gitee.Parents
+	if len(c.Parents) > 0 {
+		parents = gitee.Parents{Sha: c.Parents[0].Sha, URL: c.Parents[0].URL}
+	}
+	return gitee.PullRequestCommit{
+		Author: author,
+		Commit: gitee.GitCommit{
+			Author:    gitee.GitUser(c.Commit.Author),
+			Committer: gitee.GitUser(c.Commit.Committer),
+			Message:   c.Commit.Message,
+			URL:       c.Commit.URL,
+		},
+		Committer: committer,
+		HTMLURL:   c.HTMLURL,
+		Parents:   parents,
+		Sha:       c.Sha,
+		URL:       c.URL,
+	}
+}
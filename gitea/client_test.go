@@ -0,0 +1,127 @@
+package gitea
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(func() []byte { return []byte("token") }, server.URL).(*client)
+}
+
+func TestGetBranches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "token token" {
+			t.Errorf("Authorization = %q, want token token", got)
+		}
+		if r.URL.Path != "/api/v1/repos/owner/repo/branches" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`[{"name": "master", "protected": true}, {"name": "dev"}]`))
+	})
+	branches, err := c.GetBranches("owner", "repo", false)
+	if err != nil {
+		t.Fatalf("GetBranches() error = %v", err)
+	}
+	if len(branches) != 2 || !branches[0].Protected || branches[1].Name != "dev" {
+		t.Errorf("GetBranches() = %v", branches)
+	}
+}
+
+func TestGetTextFile(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/owner/repo/raw/repo.spec" || r.URL.Query().Get("ref") != "master" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte("Version: 1.0"))
+	})
+	got, err := c.GetTextFile("owner", "repo", "repo.spec", "master")
+	if err != nil {
+		t.Fatalf("GetTextFile() error = %v", err)
+	}
+	if got != "Version: 1.0" {
+		t.Errorf("GetTextFile() = %q, want %q", got, "Version: 1.0")
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/owner/repo/git/trees/master" || r.URL.Query().Get("recursive") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte(`{"tree": [], "total_count": 3}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"tree": [{"path": "README.md", "type": "blob"}, {"path": "SPECS", "type": "tree"},
+			{"path": "SPECS/repo.spec", "type": "blob"}], "total_count": 3}`))
+	})
+	files, err := c.ListFiles("owner", "repo", "master")
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	want := []string{"README.md", "SPECS/repo.spec"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ListFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestGetPermission(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/owner/repo/collaborators/alice/permission" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"permission": "owner"}`))
+	})
+	got, err := c.GetPermission("owner", "repo", "alice")
+	if err != nil {
+		t.Fatalf("GetPermission() error = %v", err)
+	}
+	if got != "owner" {
+		t.Errorf("GetPermission() = %q, want %q", got, "owner")
+	}
+}
+
+func TestCreateBranch(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/repos/owner/repo/branches" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		var in map[string]string
+		_ = json.Unmarshal(b, &in)
+		if want := map[string]string{"new_branch_name": "sync", "old_ref_name": "abc"}; !reflect.DeepEqual(in, want) {
+			t.Errorf("request body = %v, want %v", in, want)
+		}
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"message": "The branch already exists."}`))
+	})
+	err := c.CreateBranch("owner", "repo", "sync", "abc")
+	if e, ok := err.(*Error); !ok || e.StatusCode != http.StatusConflict {
+		t.Errorf("CreateBranch() error = %v, want 409", err)
+	}
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/repos/owner/repo/pulls" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number": 7}`))
+	})
+	number, err := c.CreatePullRequest("owner", "repo", "title", "body", "sync", "master", true)
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if number != 7 {
+		t.Errorf("CreatePullRequest() = %d, want 7", number)
+	}
+}
@@ -0,0 +1,172 @@
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/git"
+	"sync-bot/gitee"
+)
+
+// Provider is the Gitea provider of sync-bot, it serves Forgejo as well.
+type Provider struct {
+	// Secret returns the webhook secret.
+	Secret func() []byte
+	// Web is the web URL of Gitea, such as "https://gitea.com".
+	Web string
+}
+
+// NewProvider creates the provider of the Gitea instance at web.
+func NewProvider(secret func() []byte, web string) *Provider {
+	return &Provider{Secret: secret, Web: strings.TrimSuffix(web, "/")}
+}
+
+// Name implements hook.Provider.
+func (p *Provider) Name() string {
+	return "gitea"
+}
+
+// header returns the Gitea header, or the Forgejo one.
+func header(h http.Header, name string) string {
+	if v := h.Get("X-Gitea-" + name); v != "" {
+		return v
+	}
+	return h.Get("X-Forgejo-" + name)
+}
+
+// Authorize implements hook.Provider, Gitea signs the payload with the
+// secret in X-Gitea-Signature.
+func (p *Provider) Authorize(h http.Header, payload []byte) error {
+	signature := header(h, "Signature")
+	if signature == "" {
+		return errors.New("missing X-Gitea-Signature header")
+	}
+	secret := p.Secret()
+	if len(secret) == 0 {
+		return errors.New("webhook secret not configured")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return errors.New("invalid X-Gitea-Signature")
+	}
+	return nil
+}
+
+type pullRequestEvent struct {
+	Action      string      `json:"action"`
+	PullRequest pullRequest `json:"pull_request"`
+	Repository  repository  `json:"repository"`
+}
+
+type issueCommentEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number int `json:"number"`
+	} `json:"issue"`
+	PullRequest *pullRequest `json:"pull_request"`
+	Comment     comment      `json:"comment"`
+	Repository  repository   `json:"repository"`
+	IsPull      bool         `json:"is_pull"`
+}
+
+// ParseWebhook implements hook.Provider. It handles pull_request and
+// issue_comment events.
+func (p *Provider) ParseWebhook(h http.Header, payload []byte) (interface{}, error) {
+	eventType := header(h, "Event")
+	switch eventType {
+	case "":
+		return nil, errors.New("missing X-Gitea-Event header")
+	case "pull_request":
+		var e pullRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		if pe := convertPullRequestEvent(e); pe != nil {
+			return pe, nil
+		}
+		return nil, nil
+	case "issue_comment", "pull_request_comment":
+		var e issueCommentEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		if ce := convertIssueCommentEvent(e); ce != nil {
+			return ce, nil
+		}
+		return nil, nil
+	default:
+		logrus.Infoln("Ignoring unhandled event type:", eventType)
+		return nil, nil
+	}
+}
+
+// convertPullRequestEvent returns nil for the actions sync-bot does not handle.
+func convertPullRequestEvent(e pullRequestEvent) *gitee.PullRequestEvent {
+	pr := convertPullRequest(e.PullRequest)
+	var action gitee.Action
+	switch e.Action {
+	case "opened", "reopened":
+		action = gitee.ActionOpen
+	case "synchronized":
+		action = gitee.ActionUpdate
+	case "closed":
+		if pr.Merged {
+			action = gitee.ActionMerge
+		} else {
+			action = gitee.ActionClose
+		}
+	default:
+		logrus.Infoln("Ignoring unhandled pull request action:", e.Action)
+		return nil
+	}
+	return &gitee.PullRequestEvent{
+		Action:      action,
+		PullRequest: pr,
+		Repository:  convertRepository(e.Repository),
+	}
+}
+
+// convertIssueCommentEvent returns nil for comments not on pull requests.
+func convertIssueCommentEvent(e issueCommentEvent) *gitee.CommentPullRequestEvent {
+	if e.Action != "created" || !e.IsPull || e.PullRequest == nil {
+		logrus.Infoln("Ignoring comment not created on a pull request, action:", e.Action)
+		return nil
+	}
+	return &gitee.CommentPullRequestEvent{
+		Action:      gitee.ActionComment,
+		Comment:     convertComment(e.Comment),
+		NotableType: gitee.NotableTypePullRequest,
+		PullRequest: convertPullRequest(*e.PullRequest),
+		Repository:  convertRepository(e.Repository),
+	}
+}
+
+// RepoURL implements hook.Provider.
+func (p *Provider) RepoURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", p.Web, owner, repo)
+}
+
+// BranchURL implements hook.Provider.
+func (p *Provider) BranchURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/src/branch/%s", p.Web, owner, repo, branch)
+}
+
+// PullRequestURL implements hook.Provider.
+func (p *Provider) PullRequestURL(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/%s/%s/pulls/%d", p.Web, owner, repo, number)
+}
+
+// PullRequestRef implements hook.Provider.
+func (p *Provider) PullRequestRef() string {
+	return git.PullRequestRef
+}
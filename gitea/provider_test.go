@@ -0,0 +1,84 @@
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"sync-bot/gitee"
+)
+
+func sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthorize(t *testing.T) {
+	secret := []byte("secret")
+	payload := []byte(`{"action": "opened"}`)
+	p := NewProvider(func() []byte { return secret }, "https://gitea.com")
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		wantErr bool
+	}{
+		{"gitea", "X-Gitea-Signature", sign(secret, payload), false},
+		{"forgejo", "X-Forgejo-Signature", sign(secret, payload), false},
+		{"wrong secret", "X-Gitea-Signature", sign([]byte("other"), payload), true},
+		{"missing", "X-Gitea-Event", "pull_request", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(tt.header, tt.value)
+			if err := p.Authorize(h, payload); (err != nil) != tt.wantErr {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseWebhook(t *testing.T) {
+	p := NewProvider(nil, "https://gitea.com")
+	tests := []struct {
+		name    string
+		event   string
+		payload string
+		want    interface{}
+	}{
+		{"opened", "pull_request", `{"action": "opened", "pull_request": {"state": "open"}}`, gitee.ActionOpen},
+		{"synchronized", "pull_request", `{"action": "synchronized", "pull_request": {"state": "open"}}`, gitee.ActionUpdate},
+		{"merged", "pull_request", `{"action": "closed", "pull_request": {"state": "closed", "merged": true}}`, gitee.ActionMerge},
+		{"comment", "issue_comment", `{"action": "created", "is_pull": true, "pull_request": {"number": 3, "state": "closed", "merged": true}, "comment": {"body": "/sync release"}}`, gitee.ActionComment},
+		{"issue comment", "issue_comment", `{"action": "created", "is_pull": false}`, nil},
+		{"push", "push", `{}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("X-Gitea-Event", tt.event)
+			event, err := p.ParseWebhook(h, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			var got interface{}
+			switch e := event.(type) {
+			case *gitee.PullRequestEvent:
+				got = e.Action
+			case *gitee.CommentPullRequestEvent:
+				got = e.Action
+				if e.PullRequest.State != gitee.StateMerged || e.Comment.Body != "/sync release" {
+					t.Errorf("ParseWebhook() = %+v", e)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("ParseWebhook() action = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
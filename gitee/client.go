@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 
 	giteeapi "gitee.com/openeuler/go-gitee/gitee"
 	"github.com/antihax/optional"
@@ -34,6 +36,8 @@ type RepositoryClient interface {
 	GetBranch(owner, repo, branch string) (Branch, error)
 	CreateBranch(owner, repo, branch, ref string) error
 	GetTextFile(owner, repo, filepath, ref string) (string, error)
+	ListFiles(owner, repo, ref string) ([]string, error)
+	GetPermission(owner, repo, username string) (string, error)
 }
 
 // Client interface for Gitee API
@@ -117,16 +121,75 @@ func (c *client) GetTextFile(owner, repo, filepath, ref string) (string, error)
 	return string(data), nil
 }
 
+// ListFiles lists the paths of the files in the tree of ref recursively.
+func (c *client) ListFiles(owner, repo, ref string) ([]string, error) {
+	param := &giteeapi.GetV5ReposOwnerRepoGitTreesShaOpts{
+		Recursive: optional.NewInt32(1),
+	}
+	tree, _, err := c.giteeAPI.GitDataApi.GetV5ReposOwnerRepoGitTreesSha(c.context, owner, repo, ref, param)
+	if err != nil {
+		return nil, err
+	}
+	// the trees of big repositories are truncated
+	if fmt.Sprint(tree.Truncated) == "true" {
+		return nil, fmt.Errorf("tree of %s is truncated", ref)
+	}
+	var files []string
+	for _, t := range tree.Tree {
+		if t.Type == "blob" {
+			files = append(files, t.Path)
+		}
+	}
+	return files, nil
+}
+
+// GetPermission returns the permission of username on the repository, like
+// "admin", "write" or "read".
+func (c *client) GetPermission(owner, repo, username string) (string, error) {
+	opts := &giteeapi.GetV5ReposOwnerRepoCollaboratorsUsernamePermissionOpts{}
+	p, _, err := c.giteeAPI.RepositoriesApi.GetV5ReposOwnerRepoCollaboratorsUsernamePermission(c.context, owner, repo, username, opts)
+	if err != nil {
+		return "", err
+	}
+	return p.Permission, nil
+}
+
 func (c *client) GetPullRequests(owner, repo string) ([]PullRequest, error) {
 	panic("implement me")
 }
 
 func (c *client) GetPullRequest(owner, repo string, number int) (*PullRequest, error) {
-	panic("implement me")
+	opts := &giteeapi.GetV5ReposOwnerRepoPullsNumberOpts{}
+	pr, _, err := c.giteeAPI.PullRequestsApi.GetV5ReposOwnerRepoPullsNumber(c.context, owner, repo, int32(number), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequest{
+		Body:    pr.Body,
+		HTMLURL: pr.HtmlUrl,
+		ID:      int(pr.Id),
+		Number:  int(pr.Number),
+		State:   State(pr.State),
+		Title:   pr.Title,
+	}, nil
 }
 
 func (c *client) GetPullRequestChanges(owner, repo string, number int) ([]PullRequestChange, error) {
-	panic("implement me")
+	opts := &giteeapi.GetV5ReposOwnerRepoPullsNumberFilesOpts{}
+	fs, _, err := c.giteeAPI.PullRequestsApi.GetV5ReposOwnerRepoPullsNumberFiles(c.context, owner, repo, int32(number), opts)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]PullRequestChange, 0, len(fs))
+	for _, f := range fs {
+		changes = append(changes, PullRequestChange{
+			SHA:      f.Sha,
+			Filename: f.Filename,
+			Status:   f.Status,
+			BlobURL:  f.BlobUrl,
+		})
+	}
+	return changes, nil
 }
 
 func (c *client) GetPullRequestPatch(owner, repo string, number int) ([]byte, error) {
@@ -273,8 +336,17 @@ func (c *client) ListPullRequestIssues(owner, repo string, number int) ([]Issue,
 	return issues, nil
 }
 
+// DefaultURL is the URL of the public Gitee.
+const DefaultURL = "https://gitee.com"
+
 //NewClient client to access Gitee
 func NewClient(getToken func() []byte) Client {
+	return NewClientWithURL(getToken, DefaultURL)
+}
+
+// NewClientWithURL client to access the Gitee instance at web, such as a
+// private Gitee Enterprise deployment. The OpenAPI is served at web/api.
+func NewClientWithURL(getToken func() []byte, web string) Client {
 	// oauth
 	oauthSecret := string(getToken())
 	ctx := context.Background()
@@ -283,6 +355,7 @@ func NewClient(getToken func() []byte) Client {
 	)
 	// configuration
 	giteeConf := giteeapi.NewConfiguration()
+	giteeConf.BasePath = strings.TrimSuffix(web, "/") + "/api"
 	giteeConf.HTTPClient = oauth2.NewClient(ctx, ts)
 
 	return &client{
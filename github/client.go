@@ -0,0 +1,595 @@
+// Package github provides the GitHub implementation of the API client and the
+// webhook of sync-bot. GitHub objects are converted to the model of package
+// gitee, so the same workflow serves both.
+package github
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sync-bot/gitee"
+)
+
+const (
+	apiURL  = "https://api.github.com"
+	webURL  = "https://github.com"
+	perPage = 100
+)
+
+type user struct {
+	Login   string `json:"login"`
+	ID      int    `json:"id"`
+	HTMLURL string `json:"html_url"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+}
+
+type repository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Owner         user   `json:"owner"`
+	HTMLURL       string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+	Private       bool   `json:"private"`
+	Fork          bool   `json:"fork"`
+}
+
+type pullRequestBranch struct {
+	Label string     `json:"label"`
+	Ref   string     `json:"ref"`
+	Sha   string     `json:"sha"`
+	User  user       `json:"user"`
+	Repo  repository `json:"repo"`
+}
+
+type label struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type pullRequest struct {
+	Number         int               `json:"number"`
+	ID             int               `json:"id"`
+	Title          string            `json:"title"`
+	Body           string            `json:"body"`
+	State          string            `json:"state"`
+	Merged         bool              `json:"merged"`
+	MergedAt       *time.Time        `json:"merged_at"`
+	Mergeable      *bool             `json:"mergeable"`
+	MergeCommitSha string            `json:"merge_commit_sha"`
+	HTMLURL        string            `json:"html_url"`
+	DiffURL        string            `json:"diff_url"`
+	PatchURL       string            `json:"patch_url"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Head           pullRequestBranch `json:"head"`
+	Base           pullRequestBranch `json:"base"`
+	User           user              `json:"user"`
+	Labels         []label           `json:"labels"`
+	Commits        int               `json:"commits"`
+	Additions      int               `json:"additions"`
+	ChangedFiles   int               `json:"changed_files"`
+	Comments       int               `json:"comments"`
+}
+
+type branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+	Commit    struct {
+		Sha string `json:"sha"`
+	} `json:"commit"`
+}
+
+type comment struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	User      user      `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type gitUser struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+type commit struct {
+	Sha     string `json:"sha"`
+	URL     string `json:"url"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Author       gitUser `json:"author"`
+		Committer    gitUser `json:"committer"`
+		Message      string  `json:"message"`
+		URL          string  `json:"url"`
+		CommentCount int     `json:"comment_count"`
+	} `json:"commit"`
+	Author      *user  `json:"author"`
+	Committer   *user  `json:"committer"`
+	CommentsURL string `json:"comments_url"`
+	Parents     []struct {
+		Sha string `json:"sha"`
+		URL string `json:"url"`
+	} `json:"parents"`
+}
+
+type file struct {
+	Sha              string `json:"sha"`
+	Filename         string `json:"filename"`
+	Status           string `json:"status"`
+	Additions        int    `json:"additions"`
+	Deletions        int    `json:"deletions"`
+	Changes          int    `json:"changes"`
+	Patch            string `json:"patch"`
+	BlobURL          string `json:"blob_url"`
+	PreviousFilename string `json:"previous_filename"`
+}
+
+type content struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+type tree struct {
+	Tree      []treeEntry `json:"tree"`
+	Truncated bool        `json:"truncated"`
+}
+
+// client GitHub API implementation of gitee.Client
+type client struct {
+	token      func() []byte
+	base       string
+	httpClient *http.Client
+}
+
+var _ gitee.Client = (*client)(nil)
+
+// NewClient client to access GitHub
+func NewClient(getToken func() []byte) gitee.Client {
+	return NewClientWithBase(getToken, apiURL)
+}
+
+// NewClientWithBase creates a client of the GitHub API at base, such as
+// "https://github.example.com/api/v3" for GitHub Enterprise.
+func NewClientWithBase(getToken func() []byte, base string) gitee.Client {
+	return &client{
+		token:      getToken,
+		base:       strings.TrimSuffix(base, "/"),
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Error is returned for failed requests of the GitHub API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("github: %d %s", e.StatusCode, e.Message)
+}
+
+// request sends a request to path and decodes the JSON response into out.
+func (c *client) request(method, path string, accept string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.token(); len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &e) != nil || e.Message == "" {
+			e.Message = string(b)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: e.Message}
+	}
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*o = b
+		return nil
+	default:
+		return json.Unmarshal(b, out)
+	}
+}
+
+// list requests all pages of path, page is decoded into a new value by
+// decode, which returns the number of items of the page.
+func (c *client) list(path string, decode func(b []byte) (int, error)) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for page := 1; ; page++ {
+		var b []byte
+		if err := c.request(http.MethodGet, fmt.Sprintf("%s%sper_page=%d&page=%d", path, sep, perPage, page), "", nil, &b); err != nil {
+			return err
+		}
+		n, err := decode(b)
+		if err != nil {
+			return err
+		}
+		if n < perPage {
+			return nil
+		}
+	}
+}
+
+func repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+func (c *client) GetBranches(owner, repo string, onlyProtected bool) ([]gitee.Branch, error) {
+	path := repoPath(owner, repo) + "/branches"
+	if onlyProtected {
+		path += "?protected=true"
+	}
+	branches := make([]gitee.Branch, 0)
+	err := c.list(path, func(b []byte) (int, error) {
+		var bs []branch
+		if err := json.Unmarshal(b, &bs); err != nil {
+			return 0, err
+		}
+		for _, b := range bs {
+			branches = append(branches, gitee.Branch{Name: b.Name, Protected: b.Protected})
+		}
+		return len(bs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+func (c *client) GetBranch(owner, repo, branchName string) (gitee.Branch, error) {
+	var b branch
+	if err := c.request(http.MethodGet, repoPath(owner, repo)+"/branches/"+url.PathEscape(branchName), "", nil, &b); err != nil {
+		return gitee.Branch{}, err
+	}
+	return gitee.Branch{Name: b.Name, Protected: b.Protected}, nil
+}
+
+// CreateBranch creates branchName at ref, which must be a commit sha.
+func (c *client) CreateBranch(owner, repo, branchName, ref string) error {
+	in := map[string]string{
+		"ref": "refs/heads/" + branchName,
+		"sha": ref,
+	}
+	return c.request(http.MethodPost, repoPath(owner, repo)+"/git/refs", "", in, nil)
+}
+
+func (c *client) GetTextFile(owner, repo, filepath, ref string) (string, error) {
+	var f content
+	path := repoPath(owner, repo) + "/contents/" + strings.TrimPrefix(filepath, "/") + "?ref=" + url.QueryEscape(ref)
+	if err := c.request(http.MethodGet, path, "", nil, &f); err != nil {
+		return "", err
+	}
+	if f.Encoding != "base64" {
+		return "", fmt.Errorf("unsupported encoding %q of %s", f.Encoding, filepath)
+	}
+	// the content is wrapped at 60 characters
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(f.Content, "\n", ""))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ListFiles lists the paths of the files in the tree of ref recursively.
+func (c *client) ListFiles(owner, repo, ref string) ([]string, error) {
+	var t tree
+	path := repoPath(owner, repo) + "/git/trees/" + url.PathEscape(ref) + "?recursive=1"
+	if err := c.request(http.MethodGet, path, "", nil, &t); err != nil {
+		return nil, err
+	}
+	if t.Truncated {
+		return nil, fmt.Errorf("tree of %s is truncated", ref)
+	}
+	var files []string
+	for _, e := range t.Tree {
+		if e.Type == "blob" {
+			files = append(files, e.Path)
+		}
+	}
+	return files, nil
+}
+
+// GetPermission returns the permission of username on the repository, one of
+// "admin", "write", "read" or "none".
+func (c *client) GetPermission(owner, repo, username string) (string, error) {
+	var p struct {
+		Permission string `json:"permission"`
+	}
+	path := repoPath(owner, repo) + "/collaborators/" + url.PathEscape(username) + "/permission"
+	if err := c.request(http.MethodGet, path, "", nil, &p); err != nil {
+		return "", err
+	}
+	return p.Permission, nil
+}
+
+func (c *client) GetPullRequests(owner, repo string) ([]gitee.PullRequest, error) {
+	var prs []gitee.PullRequest
+	err := c.list(repoPath(owner, repo)+"/pulls?state=open", func(b []byte) (int, error) {
+		var ps []pullRequest
+		if err := json.Unmarshal(b, &ps); err != nil {
+			return 0, err
+		}
+		for _, p := range ps {
+			prs = append(prs, convertPullRequest(p))
+		}
+		return len(ps), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+func (c *client) GetPullRequest(owner, repo string, number int) (*gitee.PullRequest, error) {
+	var p pullRequest
+	if err := c.request(http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), "", nil, &p); err != nil {
+		return nil, err
+	}
+	pr := convertPullRequest(p)
+	return &pr, nil
+}
+
+func (c *client) GetPullRequestChanges(owner, repo string, number int) ([]gitee.PullRequestChange, error) {
+	var changes []gitee.PullRequestChange
+	err := c.list(fmt.Sprintf("%s/pulls/%d/files", repoPath(owner, repo), number), func(b []byte) (int, error) {
+		var fs []file
+		if err := json.Unmarshal(b, &fs); err != nil {
+			return 0, err
+		}
+		for _, f := range fs {
+			changes = append(changes, gitee.PullRequestChange{
+				SHA:              f.Sha,
+				Filename:         f.Filename,
+				Status:           f.Status,
+				Additions:        f.Additions,
+				Deletions:        f.Deletions,
+				Changes:          f.Changes,
+				Patch:            f.Patch,
+				BlobURL:          f.BlobURL,
+				PreviousFilename: f.PreviousFilename,
+			})
+		}
+		return len(fs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func (c *client) GetPullRequestPatch(owner, repo string, number int) ([]byte, error) {
+	var b []byte
+	err := c.request(http.MethodGet, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), "application/vnd.github.patch", nil, &b)
+	return b, err
+}
+
+// CreatePullRequest creates a pull request. GitHub deletes merged branches
+// by a setting of the repository, pruneSourceBranch is ignored.
+func (c *client) CreatePullRequest(owner, repo, title, body, head, base string, pruneSourceBranch bool) (int, error) {
+	in := map[string]string{
+		"title": title,
+		"body":  body,
+		"head":  head,
+		"base":  base,
+	}
+	var p pullRequest
+	if err := c.request(http.MethodPost, repoPath(owner, repo)+"/pulls", "", in, &p); err != nil {
+		return 0, err
+	}
+	return p.Number, nil
+}
+
+func (c *client) ListPullRequestComments(owner, repo string, number int) ([]gitee.Comment, error) {
+	comments := make([]gitee.Comment, 0)
+	err := c.list(fmt.Sprintf("%s/issues/%d/comments", repoPath(owner, repo), number), func(b []byte) (int, error) {
+		var cs []comment
+		if err := json.Unmarshal(b, &cs); err != nil {
+			return 0, err
+		}
+		for _, c := range cs {
+			comments = append(comments, convertComment(c))
+		}
+		return len(cs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (c *client) ClosePullRequest(owner, repo string, number int) error {
+	in := map[string]string{"state": "closed"}
+	return c.request(http.MethodPatch, fmt.Sprintf("%s/pulls/%d", repoPath(owner, repo), number), "", in, nil)
+}
+
+func (c *client) CreateComment(owner, repo string, number int, body string) error {
+	in := map[string]string{"body": body}
+	return c.request(http.MethodPost, fmt.Sprintf("%s/issues/%d/comments", repoPath(owner, repo), number), "", in, nil)
+}
+
+// ListPullRequestCommits lists the commits of a pull request, the latest
+// first like Gitee.
+func (c *client) ListPullRequestCommits(owner, repo string, number int) ([]gitee.PullRequestCommit, error) {
+	var commits []gitee.PullRequestCommit
+	err := c.list(fmt.Sprintf("%s/pulls/%d/commits", repoPath(owner, repo), number), func(b []byte) (int, error) {
+		var cs []commit
+		if err := json.Unmarshal(b, &cs); err != nil {
+			return 0, err
+		}
+		for _, c := range cs {
+			commits = append(commits, convertCommit(c))
+		}
+		return len(cs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// ListPullRequestIssues returns no issues, GitHub has no API for the issues
+// linked to a pull request.
+func (c *client) ListPullRequestIssues(owner, repo string, number int) ([]gitee.Issue, error) {
+	return nil, nil
+}
+
+func convertUser(u user) gitee.User {
+	return gitee.User{
+		Email:    u.Email,
+		HTMLURL:  u.HTMLURL,
+		ID:       u.ID,
+		Name:     u.Name,
+		Username: u.Login,
+	}
+}
+
+func convertRepository(r repository) gitee.Repository {
+	return gitee.Repository{
+		DefaultBranch:     r.DefaultBranch,
+		Fork:              r.Fork,
+		HTMLURL:           r.HTMLURL,
+		Name:              r.Name,
+		Namespace:         r.Owner.Login,
+		Owner:             convertUser(r.Owner),
+		Path:              r.Name,
+		PathWithNamespace: r.FullName,
+		Private:           r.Private,
+	}
+}
+
+func convertBranch(b pullRequestBranch) gitee.PullRequestBranch {
+	return gitee.PullRequestBranch{
+		Label: b.Label,
+		Ref:   b.Ref,
+		Repo:  convertRepository(b.Repo),
+		Sha:   b.Sha,
+		User:  convertUser(b.User),
+	}
+}
+
+func convertPullRequest(p pullRequest) gitee.PullRequest {
+	state := gitee.State(p.State)
+	merged := p.Merged || p.MergedAt != nil
+	if merged {
+		state = gitee.StateMerged
+	}
+	labels := make([]gitee.Label, 0, len(p.Labels))
+	for _, l := range p.Labels {
+		labels = append(labels, gitee.Label{Color: l.Color, ID: l.ID, Name: l.Name})
+	}
+	return gitee.PullRequest{
+		Additions:      p.Additions,
+		Base:           convertBranch(p.Base),
+		Body:           p.Body,
+		ChangedFiles:   p.ChangedFiles,
+		Comments:       p.Comments,
+		Commits:        p.Commits,
+		CreatedAt:      p.CreatedAt,
+		DiffURL:        p.DiffURL,
+		Head:           convertBranch(p.Head),
+		HTMLURL:        p.HTMLURL,
+		ID:             p.ID,
+		Labels:         labels,
+		MergeCommitSha: p.MergeCommitSha,
+		Mergeable:      p.Mergeable != nil && *p.Mergeable,
+		Merged:         merged,
+		Number:         p.Number,
+		PatchURL:       p.PatchURL,
+		State:          state,
+		Title:          p.Title,
+		User:           convertUser(p.User),
+	}
+}
+
+func convertComment(c comment) gitee.Comment {
+	return gitee.Comment{
+		Body:      c.Body,
+		CreatedAt: c.CreatedAt,
+		HTMLURL:   c.HTMLURL,
+		ID:        c.ID,
+		UpdatedAt: c.UpdatedAt,
+		User:      convertUser(c.User),
+	}
+}
+
+func convertCommit(c commit) gitee.PullRequestCommit {
+	var author, committer gitee.User
+	if c.Author != nil {
+		author = convertUser(*c.Author)
+	}
+	if c.Committer != nil {
+		committer = convertUser(*c.Committer)
+	}
+	var parents gitee.Parents
+	if len(c.Parents) > 0 {
+		parents = gitee.Parents{Sha: c.Parents[0].Sha, URL: c.Parents[0].URL}
+	}
+	return gitee.PullRequestCommit{
+		Author:      author,
+		CommentsURL: c.CommentsURL,
+		Commit: gitee.GitCommit{
+			Author:       gitee.GitUser(c.Commit.Author),
+			CommentCount: c.Commit.CommentCount,
+			Committer:    gitee.GitUser(c.Commit.Committer),
+			Message:      c.Commit.Message,
+			URL:          c.Commit.URL,
+		},
+		Committer: committer,
+		HTMLURL:   c.HTMLURL,
+		Parents:   parents,
+		Sha:       c.Sha,
+		URL:       c.URL,
+	}
+}
@@ -0,0 +1,149 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClientWithBase(func() []byte { return []byte("token") }, server.URL).(*client)
+}
+
+func TestGetBranches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q, want Bearer token", got)
+		}
+		if r.URL.Path != "/repos/owner/repo/branches" || r.URL.Query().Get("protected") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		// the first page is full, the second is the last
+		var bs []branch
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < perPage; i++ {
+				bs = append(bs, branch{Name: fmt.Sprintf("b%d", i), Protected: true})
+			}
+		} else {
+			bs = append(bs, branch{Name: "master", Protected: true})
+		}
+		_ = json.NewEncoder(w).Encode(bs)
+	})
+	branches, err := c.GetBranches("owner", "repo", true)
+	if err != nil {
+		t.Fatalf("GetBranches() error = %v", err)
+	}
+	if len(branches) != perPage+1 || branches[perPage].Name != "master" {
+		t.Errorf("GetBranches() returned %d branches, last %v", len(branches), branches[len(branches)-1])
+	}
+}
+
+func TestGetTextFile(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/contents/repo.spec" || r.URL.Query().Get("ref") != "master" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"encoding": "base64", "content": "VmVyc2lvbjog\nMS4w\n"}`))
+	})
+	got, err := c.GetTextFile("owner", "repo", "repo.spec", "master")
+	if err != nil {
+		t.Fatalf("GetTextFile() error = %v", err)
+	}
+	if got != "Version: 1.0" {
+		t.Errorf("GetTextFile() = %q, want %q", got, "Version: 1.0")
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/git/trees/master" || r.URL.Query().Get("recursive") != "1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"tree": [{"path": "README.md", "type": "blob"}, {"path": "SPECS", "type": "tree"},
+			{"path": "SPECS/repo.spec", "type": "blob"}]}`))
+	})
+	files, err := c.ListFiles("owner", "repo", "master")
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	want := []string{"README.md", "SPECS/repo.spec"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ListFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestGetPermission(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/collaborators/alice/permission" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"permission": "write"}`))
+	})
+	got, err := c.GetPermission("owner", "repo", "alice")
+	if err != nil {
+		t.Fatalf("GetPermission() error = %v", err)
+	}
+	if got != "write" {
+		t.Errorf("GetPermission() = %q, want %q", got, "write")
+	}
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/owner/repo/pulls" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		var in map[string]string
+		_ = json.Unmarshal(b, &in)
+		want := map[string]string{"title": "title", "body": "body", "head": "sync", "base": "master"}
+		if !reflect.DeepEqual(in, want) {
+			t.Errorf("request body = %v, want %v", in, want)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"number": 7}`))
+	})
+	number, err := c.CreatePullRequest("owner", "repo", "title", "body", "sync", "master", true)
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if number != 7 {
+		t.Errorf("CreatePullRequest() = %d, want 7", number)
+	}
+}
+
+func TestListPullRequestCommits(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"sha": "first"}, {"sha": "second"}, {"sha": "last"}]`))
+	})
+	commits, err := c.ListPullRequestCommits("owner", "repo", 1)
+	if err != nil {
+		t.Fatalf("ListPullRequestCommits() error = %v", err)
+	}
+	var got []string
+	for _, c := range commits {
+		got = append(got, c.Sha)
+	}
+	if want := []string{"last", "second", "first"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPullRequestCommits() = %v, want %v", got, want)
+	}
+}
+
+func TestError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(`{"message": "Reference already exists"}`))
+	})
+	err := c.CreateBranch("owner", "repo", "sync", "abc")
+	e, ok := err.(*Error)
+	if !ok || e.StatusCode != http.StatusUnprocessableEntity || e.Message != "Reference already exists" {
+		t.Errorf("CreateBranch() error = %v, want 422 Reference already exists", err)
+	}
+}
@@ -0,0 +1,180 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/git"
+	"sync-bot/gitee"
+)
+
+// Provider is the GitHub provider of sync-bot.
+type Provider struct {
+	// Client is used to complete the pull requests of comment events.
+	Client gitee.Client
+	// Secret returns the webhook secret.
+	Secret func() []byte
+	// Web is the web URL of GitHub, "https://github.com" if empty.
+	Web string
+}
+
+// NewProvider creates the provider of github.com.
+func NewProvider(client gitee.Client, secret func() []byte) *Provider {
+	return &Provider{Client: client, Secret: secret, Web: webURL}
+}
+
+// Name implements hook.Provider.
+func (p *Provider) Name() string {
+	return "github"
+}
+
+// Authorize implements hook.Provider, GitHub signs the payload with the
+// secret in X-Hub-Signature-256.
+func (p *Provider) Authorize(h http.Header, payload []byte) error {
+	signature := h.Get("X-Hub-Signature-256")
+	if !strings.HasPrefix(signature, "sha256=") {
+		return errors.New("missing X-Hub-Signature-256 header")
+	}
+	secret := p.Secret()
+	if len(secret) == 0 {
+		return errors.New("webhook secret not configured")
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(strings.TrimPrefix(signature, "sha256=")), []byte(expected)) {
+		return errors.New("invalid X-Hub-Signature-256")
+	}
+	return nil
+}
+
+type pullRequestEvent struct {
+	Action      string      `json:"action"`
+	PullRequest pullRequest `json:"pull_request"`
+	Repository  repository  `json:"repository"`
+}
+
+type issueCommentEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Comment    comment    `json:"comment"`
+	Repository repository `json:"repository"`
+}
+
+// ParseWebhook implements hook.Provider. It handles pull_request and
+// issue_comment events.
+func (p *Provider) ParseWebhook(h http.Header, payload []byte) (interface{}, error) {
+	eventType := h.Get("X-GitHub-Event")
+	switch eventType {
+	case "":
+		return nil, errors.New("missing X-GitHub-Event header")
+	case "pull_request":
+		var e pullRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		if pe := convertPullRequestEvent(e); pe != nil {
+			return pe, nil
+		}
+		return nil, nil
+	case "issue_comment":
+		var e issueCommentEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		ce, err := p.convertIssueCommentEvent(e)
+		if ce == nil || err != nil {
+			return nil, err
+		}
+		return ce, nil
+	default:
+		logrus.Infoln("Ignoring unhandled event type:", eventType)
+		return nil, nil
+	}
+}
+
+// convertPullRequestEvent returns nil for the actions sync-bot does not handle.
+func convertPullRequestEvent(e pullRequestEvent) *gitee.PullRequestEvent {
+	pr := convertPullRequest(e.PullRequest)
+	var action gitee.Action
+	switch e.Action {
+	case "opened", "reopened":
+		action = gitee.ActionOpen
+	case "synchronize":
+		action = gitee.ActionUpdate
+	case "closed":
+		if pr.Merged {
+			action = gitee.ActionMerge
+		} else {
+			action = gitee.ActionClose
+		}
+	default:
+		logrus.Infoln("Ignoring unhandled pull request action:", e.Action)
+		return nil
+	}
+	return &gitee.PullRequestEvent{
+		Action:      action,
+		PullRequest: pr,
+		Repository:  convertRepository(e.Repository),
+	}
+}
+
+// convertIssueCommentEvent returns nil for comments not on pull requests.
+// Comment events do not include the branches and the state of the pull
+// request, they are fetched by the API.
+func (p *Provider) convertIssueCommentEvent(e issueCommentEvent) (*gitee.CommentPullRequestEvent, error) {
+	if e.Action != "created" || e.Issue.PullRequest == nil {
+		logrus.Infoln("Ignoring comment not created on a pull request, action:", e.Action)
+		return nil, nil
+	}
+	repo := convertRepository(e.Repository)
+	pr, err := p.Client.GetPullRequest(repo.Namespace, repo.Path, e.Issue.Number)
+	if err != nil {
+		return nil, fmt.Errorf("get pull request %s#%d failed: %w", repo.PathWithNamespace, e.Issue.Number, err)
+	}
+	return &gitee.CommentPullRequestEvent{
+		Action:      gitee.ActionComment,
+		Comment:     convertComment(e.Comment),
+		NotableType: gitee.NotableTypePullRequest,
+		PullRequest: *pr,
+		Repository:  repo,
+	}, nil
+}
+
+// RepoURL implements hook.Provider.
+func (p *Provider) RepoURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", p.web(), owner, repo)
+}
+
+// BranchURL implements hook.Provider.
+func (p *Provider) BranchURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/tree/%s", p.web(), owner, repo, branch)
+}
+
+// PullRequestURL implements hook.Provider.
+func (p *Provider) PullRequestURL(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/%s/%s/pull/%d", p.web(), owner, repo, number)
+}
+
+// PullRequestRef implements hook.Provider.
+func (p *Provider) PullRequestRef() string {
+	return git.PullRequestRef
+}
+
+func (p *Provider) web() string {
+	if p.Web == "" {
+		return webURL
+	}
+	return strings.TrimSuffix(p.Web, "/")
+}
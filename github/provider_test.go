@@ -0,0 +1,115 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"sync-bot/gitee"
+)
+
+func sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthorize(t *testing.T) {
+	secret := []byte("secret")
+	payload := []byte(`{"action": "opened"}`)
+	p := NewProvider(nil, func() []byte { return secret })
+
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   bool
+	}{
+		{"valid", sign(secret, payload), false},
+		{"wrong secret", sign([]byte("other"), payload), true},
+		{"missing", "", true},
+		{"sha1", "sha1=abc", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("X-Hub-Signature-256", tt.signature)
+			if err := p.Authorize(h, payload); (err != nil) != tt.wantErr {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParsePullRequestEvent(t *testing.T) {
+	p := NewProvider(nil, nil)
+	tests := []struct {
+		name    string
+		payload string
+		want    gitee.Action
+	}{
+		{"opened", `{"action": "opened", "pull_request": {"state": "open"}}`, gitee.ActionOpen},
+		{"synchronize", `{"action": "synchronize", "pull_request": {"state": "open"}}`, gitee.ActionUpdate},
+		{"merged", `{"action": "closed", "pull_request": {"state": "closed", "merged": true}}`, gitee.ActionMerge},
+		{"closed", `{"action": "closed", "pull_request": {"state": "closed"}}`, gitee.ActionClose},
+		{"labeled", `{"action": "labeled", "pull_request": {"state": "open"}}`, ""},
+	}
+	h := http.Header{}
+	h.Set("X-GitHub-Event", "pull_request")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := p.ParseWebhook(h, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if tt.want == "" {
+				if event != nil {
+					t.Errorf("ParseWebhook() = %v, want nil", event)
+				}
+				return
+			}
+			e, ok := event.(*gitee.PullRequestEvent)
+			if !ok || e.Action != tt.want {
+				t.Errorf("ParseWebhook() = %v, want action %v", event, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIssueCommentEvent(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/pulls/3" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"number": 3, "state": "closed", "merged_at": "2022-01-01T00:00:00Z", "base": {"ref": "master"}}`))
+	})
+	p := NewProvider(c, nil)
+	h := http.Header{}
+	h.Set("X-GitHub-Event", "issue_comment")
+	payload := `{
+		"action": "created",
+		"issue": {"number": 3, "pull_request": {}},
+		"comment": {"body": "/sync release", "user": {"login": "alice"}},
+		"repository": {"name": "repo", "full_name": "owner/repo", "owner": {"login": "owner"}}
+	}`
+	event, err := p.ParseWebhook(h, []byte(payload))
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	e, ok := event.(*gitee.CommentPullRequestEvent)
+	if !ok {
+		t.Fatalf("ParseWebhook() = %v, want comment event", event)
+	}
+	if e.NotableType != gitee.NotableTypePullRequest || e.PullRequest.State != gitee.StateMerged ||
+		e.PullRequest.Base.Ref != "master" || e.Comment.User.Username != "alice" ||
+		e.Repository.Namespace != "owner" || e.Repository.Path != "repo" {
+		t.Errorf("ParseWebhook() = %+v", e)
+	}
+
+	// comments on issues are ignored
+	event, err = p.ParseWebhook(h, []byte(`{"action": "created", "issue": {"number": 4}}`))
+	if err != nil || event != nil {
+		t.Errorf("ParseWebhook() = %v, %v, want nil", event, err)
+	}
+}
@@ -0,0 +1,574 @@
+// Package gitlab provides the GitLab implementation of the API client and the
+// webhook of sync-bot. Merge requests are converted to the pull requests of
+// package gitee, so the same workflow serves both.
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sync-bot/gitee"
+)
+
+const perPage = 100
+
+type user struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	WebURL   string `json:"web_url"`
+}
+
+type branch struct {
+	Name      string `json:"name"`
+	Protected bool   `json:"protected"`
+}
+
+type mergeRequest struct {
+	ID             int       `json:"id"`
+	IID            int       `json:"iid"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	State          string    `json:"state"`
+	MergeStatus    string    `json:"merge_status"`
+	WebURL         string    `json:"web_url"`
+	SourceBranch   string    `json:"source_branch"`
+	TargetBranch   string    `json:"target_branch"`
+	Sha            string    `json:"sha"`
+	MergeCommitSha string    `json:"merge_commit_sha"`
+	Author         user      `json:"author"`
+	Labels         []string  `json:"labels"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type note struct {
+	ID        int       `json:"id"`
+	Body      string    `json:"body"`
+	Author    user      `json:"author"`
+	System    bool      `json:"system"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type commit struct {
+	ID             string    `json:"id"`
+	Message        string    `json:"message"`
+	AuthorName     string    `json:"author_name"`
+	AuthorEmail    string    `json:"author_email"`
+	AuthoredDate   time.Time `json:"authored_date"`
+	CommitterName  string    `json:"committer_name"`
+	CommitterEmail string    `json:"committer_email"`
+	CommittedDate  time.Time `json:"committed_date"`
+	WebURL         string    `json:"web_url"`
+	ParentIDs      []string  `json:"parent_ids"`
+}
+
+type change struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+	Diff        string `json:"diff"`
+}
+
+type treeEntry struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+}
+
+type member struct {
+	Username    string `json:"username"`
+	AccessLevel int    `json:"access_level"`
+}
+
+// access levels of members
+const (
+	developerAccess  = 30
+	maintainerAccess = 40
+)
+
+type issue struct {
+	ID          int    `json:"id"`
+	IID         int    `json:"iid"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	State       string `json:"state"`
+	WebURL      string `json:"web_url"`
+	IssueType   string `json:"issue_type"`
+}
+
+// client GitLab API implementation of gitee.Client
+type client struct {
+	token func() []byte
+	// base is the URL of the API, "https://gitlab.com/api/v4".
+	base string
+	// web is the web URL, "https://gitlab.com".
+	web        string
+	httpClient *http.Client
+}
+
+var _ gitee.Client = (*client)(nil)
+
+// NewClient client to access the GitLab instance at web, such as
+// "https://gitlab.com".
+func NewClient(getToken func() []byte, web string) gitee.Client {
+	web = strings.TrimSuffix(web, "/")
+	return &client{
+		token:      getToken,
+		base:       web + "/api/v4",
+		web:        web,
+		httpClient: &http.Client{Timeout: time.Minute},
+	}
+}
+
+// Error is returned for failed requests of the GitLab API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gitlab: %d %s", e.StatusCode, e.Message)
+}
+
+// request sends a request to path and decodes the JSON response into out.
+func (c *client) request(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token := c.token(); len(token) > 0 {
+		req.Header.Set("PRIVATE-TOKEN", string(token))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var e struct {
+			Message interface{} `json:"message"`
+		}
+		message := string(b)
+		if json.Unmarshal(b, &e) == nil && e.Message != nil {
+			message = fmt.Sprint(e.Message)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: message}
+	}
+	switch o := out.(type) {
+	case nil:
+		return nil
+	case *[]byte:
+		*o = b
+		return nil
+	default:
+		return json.Unmarshal(b, out)
+	}
+}
+
+// list requests all pages of path, page is decoded by decode, which returns
+// the number of items of the page.
+func (c *client) list(path string, decode func(b []byte) (int, error)) error {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	for page := 1; ; page++ {
+		var b []byte
+		if err := c.request(http.MethodGet, fmt.Sprintf("%s%sper_page=%d&page=%d", path, sep, perPage, page), nil, &b); err != nil {
+			return err
+		}
+		n, err := decode(b)
+		if err != nil {
+			return err
+		}
+		if n < perPage {
+			return nil
+		}
+	}
+}
+
+// projectPath returns the API path of the project owner/repo, owner may
+// contain subgroups.
+func projectPath(owner, repo string) string {
+	return "/projects/" + url.PathEscape(owner+"/"+repo)
+}
+
+func mergeRequestPath(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/merge_requests/%d", projectPath(owner, repo), number)
+}
+
+func (c *client) GetBranches(owner, repo string, onlyProtected bool) ([]gitee.Branch, error) {
+	branches := make([]gitee.Branch, 0)
+	err := c.list(projectPath(owner, repo)+"/repository/branches", func(b []byte) (int, error) {
+		var bs []branch
+		if err := json.Unmarshal(b, &bs); err != nil {
+			return 0, err
+		}
+		for _, b := range bs {
+			if onlyProtected && !b.Protected {
+				continue
+			}
+			branches = append(branches, gitee.Branch{Name: b.Name, Protected: b.Protected})
+		}
+		return len(bs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return branches, nil
+}
+
+func (c *client) GetBranch(owner, repo, branchName string) (gitee.Branch, error) {
+	var b branch
+	if err := c.request(http.MethodGet, projectPath(owner, repo)+"/repository/branches/"+url.PathEscape(branchName), nil, &b); err != nil {
+		return gitee.Branch{}, err
+	}
+	return gitee.Branch{Name: b.Name, Protected: b.Protected}, nil
+}
+
+func (c *client) CreateBranch(owner, repo, branchName, ref string) error {
+	in := map[string]string{"branch": branchName, "ref": ref}
+	return c.request(http.MethodPost, projectPath(owner, repo)+"/repository/branches", in, nil)
+}
+
+func (c *client) GetTextFile(owner, repo, filepath, ref string) (string, error) {
+	var b []byte
+	path := projectPath(owner, repo) + "/repository/files/" + url.PathEscape(strings.TrimPrefix(filepath, "/")) +
+		"/raw?ref=" + url.QueryEscape(ref)
+	if err := c.request(http.MethodGet, path, nil, &b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ListFiles lists the paths of the files in the tree of ref recursively.
+func (c *client) ListFiles(owner, repo, ref string) ([]string, error) {
+	var files []string
+	path := projectPath(owner, repo) + "/repository/tree?recursive=true&ref=" + url.QueryEscape(ref)
+	err := c.list(path, func(b []byte) (int, error) {
+		var es []treeEntry
+		if err := json.Unmarshal(b, &es); err != nil {
+			return 0, err
+		}
+		for _, e := range es {
+			if e.Type == "blob" {
+				files = append(files, e.Path)
+			}
+		}
+		return len(es), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetPermission returns the permission of username on the project, "admin"
+// for maintainers and owners, "write" for developers, "read" for the other
+// members and "none" for others.
+func (c *client) GetPermission(owner, repo, username string) (string, error) {
+	permission := "none"
+	path := projectPath(owner, repo) + "/members/all?query=" + url.QueryEscape(username)
+	err := c.list(path, func(b []byte) (int, error) {
+		var ms []member
+		if err := json.Unmarshal(b, &ms); err != nil {
+			return 0, err
+		}
+		for _, m := range ms {
+			if m.Username != username {
+				continue
+			}
+			switch {
+			case m.AccessLevel >= maintainerAccess:
+				permission = "admin"
+			case m.AccessLevel >= developerAccess:
+				permission = "write"
+			default:
+				permission = "read"
+			}
+		}
+		return len(ms), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return permission, nil
+}
+
+func (c *client) GetPullRequests(owner, repo string) ([]gitee.PullRequest, error) {
+	var prs []gitee.PullRequest
+	err := c.list(projectPath(owner, repo)+"/merge_requests?state=opened", func(b []byte) (int, error) {
+		var mrs []mergeRequest
+		if err := json.Unmarshal(b, &mrs); err != nil {
+			return 0, err
+		}
+		for _, mr := range mrs {
+			prs = append(prs, convertMergeRequest(mr))
+		}
+		return len(mrs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
+
+func (c *client) GetPullRequest(owner, repo string, number int) (*gitee.PullRequest, error) {
+	var mr mergeRequest
+	if err := c.request(http.MethodGet, mergeRequestPath(owner, repo, number), nil, &mr); err != nil {
+		return nil, err
+	}
+	pr := convertMergeRequest(mr)
+	return &pr, nil
+}
+
+func (c *client) changes(owner, repo string, number int) ([]change, error) {
+	var out struct {
+		Changes []change `json:"changes"`
+	}
+	if err := c.request(http.MethodGet, mergeRequestPath(owner, repo, number)+"/changes", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Changes, nil
+}
+
+func (c *client) GetPullRequestChanges(owner, repo string, number int) ([]gitee.PullRequestChange, error) {
+	cs, err := c.changes(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var changes []gitee.PullRequestChange
+	for _, c := range cs {
+		status := "modified"
+		switch {
+		case c.NewFile:
+			status = "added"
+		case c.DeletedFile:
+			status = "removed"
+		case c.RenamedFile:
+			status = "renamed"
+		}
+		var previous string
+		if c.RenamedFile {
+			previous = c.OldPath
+		}
+		changes = append(changes, gitee.PullRequestChange{
+			Filename:         c.NewPath,
+			Status:           status,
+			Patch:            c.Diff,
+			PreviousFilename: previous,
+		})
+	}
+	return changes, nil
+}
+
+// GetPullRequestPatch returns the diff of a merge request, assembled from its
+// changes since the API has no patch of merge requests.
+func (c *client) GetPullRequestPatch(owner, repo string, number int) ([]byte, error) {
+	cs, err := c.changes(owner, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	for _, c := range cs {
+		fmt.Fprintf(&b, "diff --git a/%s b/%s\n", c.OldPath, c.NewPath)
+		oldPath, newPath := "a/"+c.OldPath, "b/"+c.NewPath
+		if c.NewFile {
+			oldPath = "/dev/null"
+		}
+		if c.DeletedFile {
+			newPath = "/dev/null"
+		}
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n%s", oldPath, newPath, c.Diff)
+	}
+	return b.Bytes(), nil
+}
+
+func (c *client) CreatePullRequest(owner, repo, title, body, head, base string, pruneSourceBranch bool) (int, error) {
+	in := map[string]interface{}{
+		"title":                title,
+		"description":          body,
+		"source_branch":        head,
+		"target_branch":        base,
+		"remove_source_branch": pruneSourceBranch,
+	}
+	var mr mergeRequest
+	if err := c.request(http.MethodPost, projectPath(owner, repo)+"/merge_requests", in, &mr); err != nil {
+		return 0, err
+	}
+	return mr.IID, nil
+}
+
+// ListPullRequestComments lists the comments of a merge request, system
+// notes are skipped.
+func (c *client) ListPullRequestComments(owner, repo string, number int) ([]gitee.Comment, error) {
+	comments := make([]gitee.Comment, 0)
+	path := mergeRequestPath(owner, repo, number) + "/notes?sort=asc&order_by=created_at"
+	err := c.list(path, func(b []byte) (int, error) {
+		var ns []note
+		if err := json.Unmarshal(b, &ns); err != nil {
+			return 0, err
+		}
+		for _, n := range ns {
+			if n.System {
+				continue
+			}
+			comments = append(comments, gitee.Comment{
+				Body:      n.Body,
+				CreatedAt: n.CreatedAt,
+				HTMLURL:   fmt.Sprintf("%s/%s/%s/-/merge_requests/%d#note_%d", c.web, owner, repo, number, n.ID),
+				ID:        n.ID,
+				UpdatedAt: n.UpdatedAt,
+				User:      convertUser(n.Author),
+			})
+		}
+		return len(ns), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (c *client) ClosePullRequest(owner, repo string, number int) error {
+	in := map[string]string{"state_event": "close"}
+	return c.request(http.MethodPut, mergeRequestPath(owner, repo, number), in, nil)
+}
+
+func (c *client) CreateComment(owner, repo string, number int, body string) error {
+	in := map[string]string{"body": body}
+	return c.request(http.MethodPost, mergeRequestPath(owner, repo, number)+"/notes", in, nil)
+}
+
+// ListPullRequestCommits lists the commits of a merge request, the latest
+// first like Gitee.
+func (c *client) ListPullRequestCommits(owner, repo string, number int) ([]gitee.PullRequestCommit, error) {
+	var commits []gitee.PullRequestCommit
+	err := c.list(mergeRequestPath(owner, repo, number)+"/commits", func(b []byte) (int, error) {
+		var cs []commit
+		if err := json.Unmarshal(b, &cs); err != nil {
+			return 0, err
+		}
+		for _, c := range cs {
+			commits = append(commits, convertCommit(c))
+		}
+		return len(cs), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// ListPullRequestIssues lists the issues closed by a merge request.
+func (c *client) ListPullRequestIssues(owner, repo string, number int) ([]gitee.Issue, error) {
+	var is []issue
+	if err := c.request(http.MethodGet, mergeRequestPath(owner, repo, number)+"/closes_issues", nil, &is); err != nil {
+		return nil, err
+	}
+	var issues []gitee.Issue
+	for _, i := range is {
+		issues = append(issues, gitee.Issue{
+			Body:      i.Description,
+			HTMLURL:   i.WebURL,
+			ID:        i.ID,
+			IssueType: i.IssueType,
+			Number:    fmt.Sprint(i.IID),
+			State:     i.State,
+			Title:     i.Title,
+		})
+	}
+	return issues, nil
+}
+
+func convertUser(u user) gitee.User {
+	return gitee.User{
+		Email:    u.Email,
+		HTMLURL:  u.WebURL,
+		ID:       u.ID,
+		Name:     u.Name,
+		Username: u.Username,
+	}
+}
+
+// convertState converts the state of a merge request: opened, closed,
+// locked or merged.
+func convertState(state string) gitee.State {
+	switch state {
+	case "opened", "locked":
+		return gitee.StateOpen
+	case "merged":
+		return gitee.StateMerged
+	default:
+		return gitee.StateClosed
+	}
+}
+
+func convertMergeRequest(mr mergeRequest) gitee.PullRequest {
+	labels := make([]gitee.Label, 0, len(mr.Labels))
+	for _, l := range mr.Labels {
+		labels = append(labels, gitee.Label{Name: l})
+	}
+	state := convertState(mr.State)
+	return gitee.PullRequest{
+		Base:           gitee.PullRequestBranch{Ref: mr.TargetBranch},
+		Body:           mr.Description,
+		CreatedAt:      mr.CreatedAt,
+		Head:           gitee.PullRequestBranch{Ref: mr.SourceBranch, Sha: mr.Sha},
+		HTMLURL:        mr.WebURL,
+		ID:             mr.ID,
+		Labels:         labels,
+		MergeCommitSha: mr.MergeCommitSha,
+		MergeStatus:    mr.MergeStatus,
+		Mergeable:      mr.MergeStatus == "can_be_merged",
+		Merged:         state == gitee.StateMerged,
+		Number:         mr.IID,
+		State:          state,
+		Title:          mr.Title,
+		User:           convertUser(mr.Author),
+	}
+}
+
+func convertCommit(c commit) gitee.PullRequestCommit {
+	var parents gitee.Parents
+	if len(c.ParentIDs) > 0 {
+		parents.Sha = c.ParentIDs[0]
+	}
+	return gitee.PullRequestCommit{
+		Author: gitee.User{Email: c.AuthorEmail, Name: c.AuthorName},
+		Commit: gitee.GitCommit{
+			Author:    gitee.GitUser{Name: c.AuthorName, Email: c.AuthorEmail, Date: c.AuthoredDate},
+			Committer: gitee.GitUser{Name: c.CommitterName, Email: c.CommitterEmail, Date: c.CommittedDate},
+			Message:   c.Message,
+		},
+		Committer: gitee.User{Email: c.CommitterEmail, Name: c.CommitterName},
+		HTMLURL:   c.WebURL,
+		Parents:   parents,
+		Sha:       c.ID,
+	}
+}
@@ -0,0 +1,140 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(func() []byte { return []byte("token") }, server.URL).(*client)
+}
+
+func TestGetBranches(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "token" {
+			t.Errorf("PRIVATE-TOKEN = %q, want token", got)
+		}
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fsub%2Frepo/repository/branches" {
+			t.Errorf("unexpected request %s", r.URL.EscapedPath())
+		}
+		_, _ = w.Write([]byte(`[{"name": "master", "protected": true}, {"name": "dev"}]`))
+	})
+	branches, err := c.GetBranches("group/sub", "repo", true)
+	if err != nil {
+		t.Fatalf("GetBranches() error = %v", err)
+	}
+	if len(branches) != 1 || branches[0].Name != "master" {
+		t.Errorf("GetBranches() = %v, want [master]", branches)
+	}
+}
+
+func TestGetTextFile(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/owner%2Frepo/repository/files/dir%2Frepo.spec/raw" ||
+			r.URL.Query().Get("ref") != "master" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte("Version: 1.0"))
+	})
+	got, err := c.GetTextFile("owner", "repo", "dir/repo.spec", "master")
+	if err != nil {
+		t.Fatalf("GetTextFile() error = %v", err)
+	}
+	if got != "Version: 1.0" {
+		t.Errorf("GetTextFile() = %q, want %q", got, "Version: 1.0")
+	}
+}
+
+func TestListFiles(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/owner%2Frepo/repository/tree" ||
+			r.URL.Query().Get("ref") != "master" || r.URL.Query().Get("recursive") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`[{"path": "README.md", "type": "blob"}, {"path": "SPECS", "type": "tree"},
+			{"path": "SPECS/repo.spec", "type": "blob"}]`))
+	})
+	files, err := c.ListFiles("owner", "repo", "master")
+	if err != nil {
+		t.Fatalf("ListFiles() error = %v", err)
+	}
+	want := []string{"README.md", "SPECS/repo.spec"}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ListFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestGetPermission(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/owner%2Frepo/members/all" || r.URL.Query().Get("query") != "alice" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`[{"username": "alice2", "access_level": 50}, {"username": "alice", "access_level": 30}]`))
+	})
+	tests := []struct {
+		username string
+		want     string
+	}{
+		{"alice", "write"},
+	}
+	for _, tt := range tests {
+		got, err := c.GetPermission("owner", "repo", tt.username)
+		if err != nil {
+			t.Fatalf("GetPermission() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("GetPermission() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.EscapedPath() != "/api/v4/projects/owner%2Frepo/merge_requests" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		var in map[string]interface{}
+		_ = json.Unmarshal(b, &in)
+		want := map[string]interface{}{
+			"title": "title", "description": "body", "source_branch": "sync",
+			"target_branch": "master", "remove_source_branch": true,
+		}
+		if !reflect.DeepEqual(in, want) {
+			t.Errorf("request body = %v, want %v", in, want)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id": 1000, "iid": 7}`))
+	})
+	number, err := c.CreatePullRequest("owner", "repo", "title", "body", "sync", "master", true)
+	if err != nil {
+		t.Fatalf("CreatePullRequest() error = %v", err)
+	}
+	if number != 7 {
+		t.Errorf("CreatePullRequest() = %d, want 7", number)
+	}
+}
+
+func TestListPullRequestComments(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"id": 1, "body": "/sync release", "author": {"username": "alice"}},
+			{"id": 2, "body": "added 1 commit", "system": true}
+		]`))
+	})
+	comments, err := c.ListPullRequestComments("owner", "repo", 3)
+	if err != nil {
+		t.Fatalf("ListPullRequestComments() error = %v", err)
+	}
+	if len(comments) != 1 || comments[0].User.Username != "alice" ||
+		comments[0].HTMLURL != c.web+"/owner/repo/-/merge_requests/3#note_1" {
+		t.Errorf("ListPullRequestComments() = %+v", comments)
+	}
+}
@@ -0,0 +1,227 @@
+package gitlab
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/gitee"
+)
+
+// Provider is the GitLab provider of sync-bot.
+type Provider struct {
+	// Secret returns the webhook secret.
+	Secret func() []byte
+	// Web is the web URL of GitLab, such as "https://gitlab.com".
+	Web string
+}
+
+// NewProvider creates the provider of the GitLab instance at web.
+func NewProvider(secret func() []byte, web string) *Provider {
+	return &Provider{Secret: secret, Web: strings.TrimSuffix(web, "/")}
+}
+
+// Name implements hook.Provider.
+func (p *Provider) Name() string {
+	return "gitlab"
+}
+
+// Authorize implements hook.Provider, GitLab sends the secret in X-Gitlab-Token.
+func (p *Provider) Authorize(h http.Header, payload []byte) error {
+	secret := p.Secret()
+	if len(secret) == 0 {
+		return errors.New("webhook secret not configured")
+	}
+	if !hmac.Equal([]byte(h.Get("X-Gitlab-Token")), secret) {
+		return errors.New("invalid X-Gitlab-Token")
+	}
+	return nil
+}
+
+type project struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+	Name              string `json:"name"`
+	WebURL            string `json:"web_url"`
+	DefaultBranch     string `json:"default_branch"`
+}
+
+type hookMergeRequest struct {
+	ID           int    `json:"id"`
+	IID          int    `json:"iid"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	State        string `json:"state"`
+	MergeStatus  string `json:"merge_status"`
+	URL          string `json:"url"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Action       string `json:"action"`
+	OldRev       string `json:"oldrev"`
+	Note         string `json:"note"`
+	NoteableType string `json:"noteable_type"`
+	LastCommit   struct {
+		ID string `json:"id"`
+	} `json:"last_commit"`
+	Labels []struct {
+		ID    int    `json:"id"`
+		Title string `json:"title"`
+		Color string `json:"color"`
+	} `json:"labels"`
+}
+
+type mergeRequestEvent struct {
+	User             user             `json:"user"`
+	Project          project          `json:"project"`
+	ObjectAttributes hookMergeRequest `json:"object_attributes"`
+}
+
+type noteEvent struct {
+	User             user              `json:"user"`
+	Project          project           `json:"project"`
+	ObjectAttributes hookMergeRequest  `json:"object_attributes"`
+	MergeRequest     *hookMergeRequest `json:"merge_request"`
+}
+
+// ParseWebhook implements hook.Provider. It handles Merge Request Hook and
+// Note Hook events.
+func (p *Provider) ParseWebhook(h http.Header, payload []byte) (interface{}, error) {
+	eventType := h.Get("X-Gitlab-Event")
+	switch eventType {
+	case "":
+		return nil, errors.New("missing X-Gitlab-Event header")
+	case "Merge Request Hook":
+		var e mergeRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		if pe := convertMergeRequestEvent(e); pe != nil {
+			return pe, nil
+		}
+		return nil, nil
+	case "Note Hook":
+		var e noteEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		if ne := convertNoteEvent(e); ne != nil {
+			return ne, nil
+		}
+		return nil, nil
+	default:
+		logrus.Infoln("Ignoring unhandled event type:", eventType)
+		return nil, nil
+	}
+}
+
+// convertRepository splits the path of the project at the last slash,
+// the namespace may contain subgroups.
+func convertRepository(p project) gitee.Repository {
+	namespace, path := "", p.PathWithNamespace
+	if i := strings.LastIndex(p.PathWithNamespace, "/"); i >= 0 {
+		namespace, path = p.PathWithNamespace[:i], p.PathWithNamespace[i+1:]
+	}
+	return gitee.Repository{
+		DefaultBranch:     p.DefaultBranch,
+		HTMLURL:           p.WebURL,
+		Name:              p.Name,
+		Namespace:         namespace,
+		Path:              path,
+		PathWithNamespace: p.PathWithNamespace,
+	}
+}
+
+func convertHookMergeRequest(mr hookMergeRequest) gitee.PullRequest {
+	labels := make([]gitee.Label, 0, len(mr.Labels))
+	for _, l := range mr.Labels {
+		labels = append(labels, gitee.Label{Color: l.Color, ID: l.ID, Name: l.Title})
+	}
+	state := convertState(mr.State)
+	return gitee.PullRequest{
+		Base:        gitee.PullRequestBranch{Ref: mr.TargetBranch},
+		Body:        mr.Description,
+		Head:        gitee.PullRequestBranch{Ref: mr.SourceBranch, Sha: mr.LastCommit.ID},
+		HTMLURL:     mr.URL,
+		ID:          mr.ID,
+		Labels:      labels,
+		MergeStatus: mr.MergeStatus,
+		Mergeable:   mr.MergeStatus == "can_be_merged",
+		Merged:      state == gitee.StateMerged,
+		Number:      mr.IID,
+		State:       state,
+		Title:       mr.Title,
+	}
+}
+
+// convertMergeRequestEvent returns nil for the actions sync-bot does not handle.
+func convertMergeRequestEvent(e mergeRequestEvent) *gitee.PullRequestEvent {
+	var action gitee.Action
+	switch e.ObjectAttributes.Action {
+	case "open", "reopen":
+		action = gitee.ActionOpen
+	case "update":
+		// updates of the title, labels etc. have no oldrev
+		if e.ObjectAttributes.OldRev == "" {
+			logrus.Infoln("Ignoring update of merge request without new commits")
+			return nil
+		}
+		action = gitee.ActionUpdate
+	case "merge":
+		action = gitee.ActionMerge
+	case "close":
+		action = gitee.ActionClose
+	default:
+		logrus.Infoln("Ignoring unhandled merge request action:", e.ObjectAttributes.Action)
+		return nil
+	}
+	return &gitee.PullRequestEvent{
+		Action:      action,
+		PullRequest: convertHookMergeRequest(e.ObjectAttributes),
+		Repository:  convertRepository(e.Project),
+	}
+}
+
+// convertNoteEvent returns nil for notes not on merge requests.
+func convertNoteEvent(e noteEvent) *gitee.CommentPullRequestEvent {
+	if e.ObjectAttributes.NoteableType != "MergeRequest" || e.MergeRequest == nil {
+		logrus.Infoln("Ignoring note on:", e.ObjectAttributes.NoteableType)
+		return nil
+	}
+	return &gitee.CommentPullRequestEvent{
+		Action: gitee.ActionComment,
+		Comment: gitee.Comment{
+			Body:    e.ObjectAttributes.Note,
+			HTMLURL: e.ObjectAttributes.URL,
+			ID:      e.ObjectAttributes.ID,
+			User:    convertUser(e.User),
+		},
+		NotableType: gitee.NotableTypePullRequest,
+		PullRequest: convertHookMergeRequest(*e.MergeRequest),
+		Repository:  convertRepository(e.Project),
+	}
+}
+
+// RepoURL implements hook.Provider.
+func (p *Provider) RepoURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", p.Web, owner, repo)
+}
+
+// BranchURL implements hook.Provider.
+func (p *Provider) BranchURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/-/tree/%s", p.Web, owner, repo, branch)
+}
+
+// PullRequestURL implements hook.Provider.
+func (p *Provider) PullRequestURL(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/%s/%s/-/merge_requests/%d", p.Web, owner, repo, number)
+}
+
+// PullRequestRef implements hook.Provider, GitLab keeps the heads of merge
+// requests in refs/merge-requests.
+func (p *Provider) PullRequestRef() string {
+	return "refs/merge-requests/%d/head"
+}
@@ -0,0 +1,123 @@
+package gitlab
+
+import (
+	"net/http"
+	"testing"
+
+	"sync-bot/git/gogit"
+	"sync-bot/gitee"
+	"sync-bot/internal/gittest"
+)
+
+func TestAuthorize(t *testing.T) {
+	p := NewProvider(func() []byte { return []byte("secret") }, "https://gitlab.com")
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", "secret", false},
+		{"invalid", "other", true},
+		{"missing", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("X-Gitlab-Token", tt.token)
+			if err := p.Authorize(h, nil); (err != nil) != tt.wantErr {
+				t.Errorf("Authorize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseMergeRequestEvent(t *testing.T) {
+	p := NewProvider(nil, "https://gitlab.com")
+	tests := []struct {
+		name    string
+		payload string
+		want    gitee.Action
+	}{
+		{"open", `{"object_attributes": {"action": "open", "state": "opened"}}`, gitee.ActionOpen},
+		{"push", `{"object_attributes": {"action": "update", "state": "opened", "oldrev": "abc"}}`, gitee.ActionUpdate},
+		{"edit", `{"object_attributes": {"action": "update", "state": "opened"}}`, ""},
+		{"merge", `{"object_attributes": {"action": "merge", "state": "merged"}}`, gitee.ActionMerge},
+		{"close", `{"object_attributes": {"action": "close", "state": "closed"}}`, gitee.ActionClose},
+	}
+	h := http.Header{}
+	h.Set("X-Gitlab-Event", "Merge Request Hook")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := p.ParseWebhook(h, []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if tt.want == "" {
+				if event != nil {
+					t.Errorf("ParseWebhook() = %v, want nil", event)
+				}
+				return
+			}
+			e, ok := event.(*gitee.PullRequestEvent)
+			if !ok || e.Action != tt.want {
+				t.Errorf("ParseWebhook() = %v, want action %v", event, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNoteEvent(t *testing.T) {
+	p := NewProvider(nil, "https://gitlab.com")
+	h := http.Header{}
+	h.Set("X-Gitlab-Event", "Note Hook")
+	payload := `{
+		"user": {"username": "alice"},
+		"project": {"path_with_namespace": "group/sub/repo"},
+		"object_attributes": {"id": 5, "note": "/sync release", "noteable_type": "MergeRequest", "url": "https://gitlab.com/group/sub/repo/-/merge_requests/3#note_5"},
+		"merge_request": {"iid": 3, "state": "merged", "target_branch": "master", "source_branch": "fix"}
+	}`
+	event, err := p.ParseWebhook(h, []byte(payload))
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	e, ok := event.(*gitee.CommentPullRequestEvent)
+	if !ok {
+		t.Fatalf("ParseWebhook() = %v, want note event", event)
+	}
+	if e.Repository.Namespace != "group/sub" || e.Repository.Path != "repo" ||
+		e.PullRequest.Number != 3 || e.PullRequest.State != gitee.StateMerged ||
+		e.PullRequest.Base.Ref != "master" || e.Comment.Body != "/sync release" || e.Comment.User.Username != "alice" {
+		t.Errorf("ParseWebhook() = %+v", e)
+	}
+
+	// notes on issues are ignored
+	event, err = p.ParseWebhook(h, []byte(`{"object_attributes": {"noteable_type": "Issue"}}`))
+	if err != nil || event != nil {
+		t.Errorf("ParseWebhook() = %v, %v, want nil", event, err)
+	}
+}
+
+func TestFetchMergeRequest(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	remote.Push("refs/heads/master:refs/heads/master")
+	remote.Commit("a", "a\n")
+	remote.Push("refs/heads/master:refs/merge-requests/3/head")
+
+	c := gogit.NewClient("file://" + root)
+	c.SetPullRequestRef(NewProvider(nil, "https://gitlab.com").PullRequestRef())
+	r, err := c.Clone("owner", "repo")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if err := r.FetchPullRequest(3); err != nil {
+		t.Fatalf("FetchPullRequest failed: %v", err)
+	}
+	if err := r.Checkout("origin/pull/3"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if got, _ := r.(*gogit.Repo).ReadFile("a"); got != "a\n" {
+		t.Errorf("content of a = %q, want %q", got, "a\n")
+	}
+}
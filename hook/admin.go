@@ -0,0 +1,79 @@
+package hook
+
+import (
+	"crypto/hmac"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sync-bot/git"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/sirupsen/logrus"
+)
+
+func (s *Server) listCachedRepos(req *restful.Request, resp *restful.Response) {
+	repos, err := s.GitClient.CachedRepos()
+	if err != nil {
+		logrus.Errorln("List cached repos failed:", err)
+		_ = resp.WriteErrorString(http.StatusInternalServerError, err.Error())
+		return
+	}
+	_ = resp.WriteEntity(repos)
+}
+
+func (s *Server) purgeCachedRepo(req *restful.Request, resp *restful.Response) {
+	owner := req.PathParameter("owner")
+	repo := req.PathParameter("repo")
+	if err := s.GitClient.Purge(owner, repo, purgeForced(req)); err != nil {
+		logrus.Errorf("Purge cached repo %s/%s failed: %v", owner, repo, err)
+		status := http.StatusNotFound
+		if errors.Is(err, git.ErrRepoInUse) {
+			status = http.StatusConflict
+		}
+		_ = resp.WriteErrorString(status, err.Error())
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) purgeCachedRepos(req *restful.Request, resp *restful.Response) {
+	if err := s.GitClient.PurgeAll(purgeForced(req)); err != nil {
+		logrus.Errorln("Purge cached repos failed:", err)
+		_ = resp.WriteErrorString(http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// purgeForced reports whether the request purges repos even if they may still
+// be in use: "?force=true".
+func purgeForced(req *restful.Request) bool {
+	force, _ := strconv.ParseBool(req.QueryParameter("force"))
+	return force
+}
+
+func adminAuth(token func() []byte) func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		expected := token()
+		bearer := strings.TrimPrefix(req.Request.Header.Get("Authorization"), "Bearer ")
+		if len(expected) == 0 || !hmac.Equal([]byte(bearer), expected) {
+			logrus.Errorln("Admin authorized failed from:", req.Request.RemoteAddr)
+			_ = resp.WriteErrorString(http.StatusUnauthorized, "401: Not Authorized")
+			return
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
+
+// AdminService serves the administration API, authorized by a bearer token.
+func (s *Server) AdminService() *restful.WebService {
+	ws := new(restful.WebService)
+	ws.Path("/admin").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("/repos").To(s.listCachedRepos))
+	ws.Route(ws.DELETE("/repos").To(s.purgeCachedRepos))
+	ws.Route(ws.DELETE("/repos/{owner}/{repo}").To(s.purgeCachedRepo))
+	ws.Filter(adminAuth(s.AdminToken))
+	return ws
+}
@@ -0,0 +1,227 @@
+package hook
+
+import (
+	"sort"
+	"strings"
+	"text/template"
+
+	"sync-bot/gitee"
+	"sync-bot/util"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Language language of the replies of sync-bot
+type Language string
+
+// Language enum
+const (
+	English Language = "en"
+	Chinese Language = "zh"
+)
+
+// defaultLanguage is the language of repos without configuration.
+const defaultLanguage = Chinese
+
+// catalog is the messages and templates of a language.
+type catalog struct {
+	branchExist    string
+	branchNonExist string
+	createdPR      string
+	syncFailed     string
+	notMergeable   string
+	// parseFailed is formatted with the error.
+	parseFailed string
+	// listBranchesFailed is formatted with the error.
+	listBranchesFailed string
+	// languageChanged is formatted with the language.
+	languageChanged string
+	// unknownLanguage is formatted with the language and supported languages.
+	unknownLanguage string
+	// syncCancelled is formatted with the cancelled branches.
+	syncCancelled    string
+	syncCancelledAll string
+	// syncPending is formatted with the branches to be synchronized.
+	syncPending   string
+	noPendingSync string
+	// cancelAfterMerge is the reply of /sync-cancel on merged pull requests.
+	cancelAfterMerge string
+	// notCreated and stateUnknown are states of the pull requests created
+	// by synchronization.
+	notCreated   string
+	stateUnknown string
+	// retryNotMerged is the reply of /sync-retry on unmerged pull requests.
+	retryNotMerged string
+	nothingToRetry string
+	// retryFailedOnly is formatted with the failed branches.
+	retryFailedOnly string
+	// notAuthorized is formatted with the user and the OWNERS file.
+	notAuthorized string
+	// unknownCommand is formatted with the command and the usage of the
+	// suggested one, unknownCommandNoSuggestion with the command.
+	unknownCommand             string
+	unknownCommandNoSuggestion string
+	// syncLoop is the status of branches already on the synchronization
+	// chain.
+	syncLoop string
+	// syncChain prefixes the readable chain in the bodies of the pull
+	// requests created by synchronization.
+	syncChain string
+	// commandHelp is the descriptions of commands keyed by their names.
+	commandHelp map[string]string
+
+	// templates are keyed by the names of templates.
+	templates map[string]*template.Template
+}
+
+var catalogs = map[Language]*catalog{
+	English: {
+		branchExist:                "sync operation will be performed",
+		branchNonExist:             "branch not found, ignored",
+		createdPR:                  "Create pull request",
+		syncFailed:                 "sync failed: please create a pull request manually, we keep improving the synchronization between branches to avoid failures",
+		notMergeable:               "The current pull request can not be merged.",
+		parseFailed:                "Receive comment look like /sync command, but parse it failed: %v",
+		listBranchesFailed:         "List branches failed: %v",
+		languageChanged:            "Replies on this pull request will be in %s.",
+		unknownLanguage:            "Unknown language %q, supported languages: %s.",
+		syncCancelled:              "Synchronization to %s is cancelled.",
+		syncCancelledAll:           "All synchronization is cancelled.",
+		syncPending:                "The current PR will be synchronized to %s once it is merged.",
+		noPendingSync:              "There is no synchronization to perform.",
+		cancelAfterMerge:           "The current PR has been merged, the synchronization can not be cancelled, please close the created pull requests by /close.",
+		notCreated:                 "not created",
+		stateUnknown:               "unknown",
+		retryNotMerged:             "The current PR has not been merged, the synchronization will be performed once it is merged.",
+		nothingToRetry:             "There is no failed synchronization to retry.",
+		retryFailedOnly:            "Only failed synchronization can be retried, failed branches: %s.",
+		notAuthorized:              "@%s is not authorized to run this command, only collaborators with write permission and the maintainers or committers in the %s file of the target branch can.",
+		unknownCommand:             "Unrecognized command `%s`, did you mean `%s`? Use `/sync-help` to list the supported commands.",
+		unknownCommandNoSuggestion: "Unrecognized command `%s`, use `/sync-help` to list the supported commands.",
+		syncLoop:                   "already on the synchronization chain, ignored",
+		syncChain:                  "Synchronization chain: ",
+		commandHelp: map[string]string{
+			"/sync-check":  "Show the versions of the spec files on the branches",
+			"/sync":        "Synchronize the current PR to the branches once it is merged, branches may be patterns like `release-*` or aliases like `@lts`",
+			"/sync-cancel": "Cancel the synchronization to the branches, or all synchronization, before the current PR is merged",
+			"/sync-status": "Show the branches to be synchronized, or the pull requests created by synchronization",
+			"/sync-retry":  "Retry the failed synchronization of the merged PR",
+			"/sync-lang":   "Change the language of the replies on the current PR",
+			"/sync-help":   "Show this help",
+			"/close":       "Close the pull request created by synchronization",
+		},
+		templates: map[string]*template.Template{
+			greetingTmpl:         mustParse(greetingTmpl, replySyncCheckEn),
+			replySyncTmpl:        mustParse(replySyncTmpl, replySyncEn),
+			syncPRBodyTmpl:       mustParse(syncPRBodyTmpl, syncPRBodyEn),
+			syncKernelPRBodyTmpl: mustParse(syncKernelPRBodyTmpl, syncKernelPRBodyEn),
+			syncResultTmpl:       mustParse(syncResultTmpl, syncResultEn),
+			replyCloseTmpl:       mustParse(replyCloseTmpl, replyCloseEn),
+			syncStatusTmpl:       mustParse(syncStatusTmpl, syncStatusEn),
+			syncHelpTmpl:         mustParse(syncHelpTmpl, syncHelpEn),
+		},
+	},
+	Chinese: {
+		branchExist:                "当前 PR 合并后，将创建同步 PR",
+		branchNonExist:             "目标分支不存在，忽略处理",
+		createdPR:                  "创建同步 PR",
+		syncFailed:                 "同步失败：请手动创建 PR 进行同步，我们会继续完善分支之间同步操作，尽量避免同步失败的情况",
+		notMergeable:               "当前 PR 无法合并。",
+		parseFailed:                "评论类似 /sync 命令，但解析失败：%v",
+		listBranchesFailed:         "获取分支列表失败：%v",
+		languageChanged:            "当前 PR 的回复将使用 %s。",
+		unknownLanguage:            "不支持的语言 %q，支持的语言：%s。",
+		syncCancelled:              "已取消同步到 %s。",
+		syncCancelledAll:           "已取消所有同步。",
+		syncPending:                "当前 PR 合并后，将同步到 %s。",
+		noPendingSync:              "当前没有待执行的同步。",
+		cancelAfterMerge:           "当前 PR 已合并，无法取消同步，请使用 /close 命令关闭已创建的同步 PR。",
+		notCreated:                 "未创建",
+		stateUnknown:               "未知",
+		retryNotMerged:             "当前 PR 尚未合并，合并后将执行同步。",
+		nothingToRetry:             "没有需要重试的同步。",
+		retryFailedOnly:            "只能重试失败的同步，同步失败的分支：%s。",
+		notAuthorized:              "@%s 无权执行该命令，只有拥有写权限的仓库成员以及目标分支 %s 文件中的 maintainers 和 committers 可以执行。",
+		unknownCommand:             "未知命令 `%s`，您是否想输入 `%s`？使用 `/sync-help` 查看支持的命令。",
+		unknownCommandNoSuggestion: "未知命令 `%s`，使用 `/sync-help` 查看支持的命令。",
+		syncLoop:                   "已在同步链中，忽略处理",
+		syncChain:                  "同步链：",
+		commandHelp: map[string]string{
+			"/sync-check":  "查看各分支 spec 文件中的版本信息",
+			"/sync":        "当前 PR 合并后同步到指定分支，分支可以使用 `release-*` 等通配符或 `@lts` 等别名",
+			"/sync-cancel": "当前 PR 合并前，取消到指定分支的同步或所有同步",
+			"/sync-status": "查看待执行的同步，或同步创建的 PR",
+			"/sync-retry":  "重试已合并 PR 失败的同步",
+			"/sync-lang":   "切换当前 PR 中回复使用的语言",
+			"/sync-help":   "查看本帮助",
+			"/close":       "关闭同步创建的 PR",
+		},
+		templates: map[string]*template.Template{
+			greetingTmpl:         mustParse(greetingTmpl, replySyncCheckZh),
+			replySyncTmpl:        mustParse(replySyncTmpl, replySyncZh),
+			syncPRBodyTmpl:       mustParse(syncPRBodyTmpl, syncPRBodyZh),
+			syncKernelPRBodyTmpl: mustParse(syncKernelPRBodyTmpl, syncKernelPRBodyZh),
+			syncResultTmpl:       mustParse(syncResultTmpl, syncResultZh),
+			replyCloseTmpl:       mustParse(replyCloseTmpl, replyCloseZh),
+			syncStatusTmpl:       mustParse(syncStatusTmpl, syncStatusZh),
+			syncHelpTmpl:         mustParse(syncHelpTmpl, syncHelpZh),
+		},
+	},
+}
+
+// languageNames are the names of languages in themselves.
+var languageNames = map[Language]string{
+	English: "English",
+	Chinese: "中文",
+}
+
+// messages returns the catalog of lang, or of the default language if lang
+// is not supported.
+func messages(lang Language) *catalog {
+	if c, ok := catalogs[lang]; ok {
+		return c
+	}
+	return catalogs[defaultLanguage]
+}
+
+// supportedLanguages lists the supported languages.
+func supportedLanguages() string {
+	var langs []string
+	for lang := range catalogs {
+		langs = append(langs, string(lang))
+	}
+	sort.Strings(langs)
+	return strings.Join(langs, ", ")
+}
+
+// language returns the language of the replies on a pull request, it lists the
+// comments so it is called once per event and passed to the handlers.
+func (s *Server) language(owner, repo string, number int) Language {
+	comments, err := s.GiteeClient.ListPullRequestComments(owner, repo, number)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"owner":  owner,
+			"repo":   repo,
+			"number": number,
+		}).Errorln("List PullRequest comments failed:", err)
+	}
+	return s.languageOf(owner, repo, comments)
+}
+
+// languageOf returns the language of the replies on a pull request with
+// comments: the last /sync-lang preference in comments, or the language
+// configured for the repo or its owner.
+func (s *Server) languageOf(owner, repo string, comments []gitee.Comment) Language {
+	comments = commandLines(comments)
+	for i := len(comments) - 1; i >= 0; i-- {
+		if lang := Language(util.ParseSyncLang(comments[i].Body)); catalogs[lang] != nil {
+			return lang
+		}
+	}
+	if s.Config != nil {
+		if lang := Language(s.Config.Repo(owner, repo).Language); catalogs[lang] != nil {
+			return lang
+		}
+	}
+	return defaultLanguage
+}
@@ -0,0 +1,97 @@
+package hook
+
+import (
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/gitee"
+	"sync-bot/util"
+)
+
+func TestLanguage(t *testing.T) {
+	cfg := &config.Config{Repos: map[string]config.Repo{
+		"owner":      {Language: "en"},
+		"owner/repo": {Language: "zh"},
+		"owner/bad":  {Language: "fr"},
+	}}
+	tests := []struct {
+		name     string
+		repo     string
+		comments []string
+		config   *config.Config
+		want     Language
+	}{
+		{"default", "repo", nil, nil, defaultLanguage},
+		{"repo", "repo", nil, cfg, Chinese},
+		{"owner", "other", nil, cfg, English},
+		{"unsupported config", "bad", nil, cfg, defaultLanguage},
+		{"preference", "repo", []string{"/sync-lang en", "/sync branch1"}, cfg, English},
+		{"last preference", "other", []string{"/sync-lang en", "/sync-lang zh"}, cfg, Chinese},
+		{"unsupported preference", "repo", []string{"/sync-lang en", "/sync-lang fr"}, cfg, English},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{}
+			for _, c := range tt.comments {
+				client.comments = append(client.comments, gitee.Comment{Body: c})
+			}
+			s := &Server{GiteeClient: client, Config: tt.config}
+			if got := s.language("owner", tt.repo, 1); got != tt.want {
+				t.Errorf("language() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplySyncLang(t *testing.T) {
+	tests := []struct {
+		name string
+		lang string
+		want string
+	}{
+		{"supported", "en", "Replies on this pull request will be in English."},
+		{"unsupported", "fr", `不支持的语言 "fr"，支持的语言：en, zh。`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{}
+			s := &Server{GiteeClient: client}
+			s.replySyncLang("owner", "repo", 1, defaultLanguage, tt.lang)
+			if len(client.comments) != 1 || client.comments[0].Body != tt.want {
+				t.Errorf("comments = %v, want %q", client.comments, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplySyncCancel(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   gitee.State
+		command string
+		want    string
+	}{
+		{"some branches", gitee.StateOpen, "/sync-cancel b",
+			"Synchronization to b is cancelled.\n\nThe current PR will be synchronized to a once it is merged."},
+		{"all branches", gitee.StateOpen, "/sync-cancel",
+			"All synchronization is cancelled.\n\nThere is no synchronization to perform."},
+		{"merged", gitee.StateMerged, "/sync-cancel",
+			"The current PR has been merged, the synchronization can not be cancelled, please close the created pull requests by /close."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{comments: []gitee.Comment{{ID: 1, Body: "/sync a b"}}}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GiteeClient: client, Config: cfg}
+			e := gitee.CommentPullRequestEvent{
+				Comment:     gitee.Comment{ID: 2, Body: tt.command},
+				PullRequest: gitee.PullRequest{Number: 1, State: tt.state},
+				Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+			}
+			s.replySyncCancel(e, English, util.ParseSyncCancel(tt.command))
+			if n := len(client.comments); n != 2 || client.comments[n-1].Body != tt.want {
+				t.Errorf("comments = %v, want %q", client.comments, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package hook
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sync-bot/gitee"
+	"sync-bot/util"
+
+	"github.com/sirupsen/logrus"
+)
+
+// like "<!-- sync-bot:chain [{"branch":"master","number":1}] -->", which is
+// appended to the bodies of the pull requests created by synchronization,
+// with the readable chain on the same line
+var chainRegex = regexp.MustCompile(`\n*[^\n]*<!-- sync-bot:chain (.*?) -->\n?`)
+
+// like "[sync] PR-12: ", the prefix of the titles of the pull requests created
+// by synchronization
+var syncTitleRegex = regexp.MustCompile(`^\[sync\] PR-\d+: `)
+
+// chainHop is a pull request synchronized along a chain, like master →
+// openEuler-24.03-LTS-Next → openEuler-24.03-LTS-SP1.
+type chainHop struct {
+	Branch string `json:"branch"`
+	Number int    `json:"number"`
+}
+
+// parseChain returns the chain recorded in the body of a pull request created
+// by synchronization, nil for other pull requests.
+func parseChain(body string) []chainHop {
+	m := chainRegex.FindAllStringSubmatch(body, -1)
+	if m == nil {
+		return nil
+	}
+	var chain []chainHop
+	if err := json.Unmarshal([]byte(m[len(m)-1][1]), &chain); err != nil {
+		logrus.WithField("record", m[len(m)-1][1]).Warnln("Parse sync chain failed:", err)
+		return nil
+	}
+	return chain
+}
+
+// prChain returns the chain recorded in the body of pr, which is trusted only
+// if pr is created by synchronization, from a branch of the bot.
+func prChain(pr gitee.PullRequest) []chainHop {
+	if !util.MatchTitle(pr.Title) || !util.MatchSyncBranch(pr.Head.Ref) {
+		return nil
+	}
+	return parseChain(pr.Body)
+}
+
+// stripChain removes the chain recorded in body.
+func stripChain(body string) string {
+	return chainRegex.ReplaceAllString(body, "\n")
+}
+
+// chainRecord returns the record of chain appended to the bodies of pull
+// requests, which is readable in the language of msg if the chain has several
+// hops.
+func (s *Server) chainRecord(owner, repo string, chain []chainHop, msg *catalog) string {
+	b, err := json.Marshal(chain)
+	if err != nil {
+		return ""
+	}
+	var readable string
+	if len(chain) > 1 {
+		hops := make([]string, 0, len(chain))
+		for _, h := range chain {
+			hops = append(hops, fmt.Sprintf("[%s#%d](%s)", h.Branch, h.Number, s.provider().PullRequestURL(owner, repo, h.Number)))
+		}
+		readable = msg.syncChain + strings.Join(hops, " → ") + " "
+	}
+	return fmt.Sprintf("\n\n%s<!-- sync-bot:chain %s -->\n", readable, b)
+}
+
+// excludeLoops removes the branches on chain from branches, which are
+// returned as the status of looped synchronization.
+func excludeLoops(branches []string, chain []chainHop, msg *catalog) ([]string, []syncStatus) {
+	var result []string
+	var looped []syncStatus
+	for _, b := range branches {
+		onChain := false
+		for _, h := range chain {
+			if h.Branch == b {
+				onChain = true
+				break
+			}
+		}
+		if onChain {
+			looped = append(looped, syncStatus{Name: b, Status: msg.syncLoop})
+			continue
+		}
+		result = append(result, b)
+	}
+	return result, looped
+}
@@ -0,0 +1,126 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/git/gogit"
+	"sync-bot/gitee"
+	"sync-bot/internal/gittest"
+)
+
+func TestChainRecord(t *testing.T) {
+	s := &Server{}
+	msg := messages(English)
+	chain := []chainHop{{Branch: "master", Number: 1}, {Branch: "branch1", Number: 10}}
+	record := s.chainRecord("owner", "repo", chain, msg)
+	if want := "Synchronization chain: [master#1](https://gitee.com/owner/repo/pulls/1) → " +
+		"[branch1#10](https://gitee.com/owner/repo/pulls/10) <!-- sync-bot:chain "; !strings.Contains(record, want) {
+		t.Errorf("chainRecord() = %q, want containing %q", record, want)
+	}
+	if got := s.chainRecord("owner", "repo", chain[:1], msg); strings.Contains(got, "Synchronization chain") {
+		t.Errorf("chainRecord() = %q, want no readable chain of one hop", got)
+	}
+	if got, want := s.chainRecord("owner", "repo", chain, messages(Chinese)), "同步链：[master#1]"; !strings.Contains(got, want) {
+		t.Errorf("chainRecord() = %q, want containing %q", got, want)
+	}
+
+	body := "body" + s.chainRecord("owner", "repo", chain[:1], msg) + "more" + record
+	if got := parseChain(body); !reflect.DeepEqual(got, chain) {
+		t.Errorf("parseChain() = %v, want %v", got, chain)
+	}
+	if got, want := stripChain(body), "body\nmore\n"; got != want {
+		t.Errorf("stripChain() = %q, want %q", got, want)
+	}
+	if got := parseChain("<!-- sync-bot:chain not json -->"); got != nil {
+		t.Errorf("parseChain() = %v, want nil", got)
+	}
+}
+
+func TestPRChain(t *testing.T) {
+	s := &Server{}
+	chain := []chainHop{{Branch: "master", Number: 1}}
+	body := "origin" + s.chainRecord("owner", "repo", chain, messages(English))
+	tests := []struct {
+		name  string
+		title string
+		head  string
+		want  []chainHop
+	}{
+		{"synchronized", "[sync] PR-1: fix", "sync-pr1-master-to-branch1", chain},
+		{"not synchronized", "fix", "sync-pr1-master-to-branch1", nil},
+		{"not from the bot", "[sync] PR-1: fix", "feature", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pr := gitee.PullRequest{Title: tt.title, Body: body, Head: gitee.PullRequestBranch{Ref: tt.head}}
+			if got := prChain(pr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("prChain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcludeLoops(t *testing.T) {
+	msg := messages(English)
+	chain := []chainHop{{Branch: "master", Number: 1}, {Branch: "branch1", Number: 10}}
+	branches, looped := excludeLoops([]string{"master", "branch2", "branch1"}, chain, msg)
+	if want := []string{"branch2"}; !reflect.DeepEqual(branches, want) {
+		t.Errorf("branches = %v, want %v", branches, want)
+	}
+	want := []syncStatus{{Name: "master", Status: msg.syncLoop}, {Name: "branch1", Status: msg.syncLoop}}
+	if !reflect.DeepEqual(looped, want) {
+		t.Errorf("looped = %v, want %v", looped, want)
+	}
+}
+
+func TestSyncChain(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	for _, b := range []string{"branch1", "branch2"} {
+		remote.Branch(b)
+	}
+	remote.Push("refs/heads/master:refs/heads/master", "refs/heads/branch1:refs/heads/branch1",
+		"refs/heads/branch2:refs/heads/branch2")
+	sha := remote.Commit("a", "a\n")
+	remote.Push("refs/heads/master:refs/pull/7/head")
+
+	client := &fakeClient{
+		branches: []gitee.Branch{{Name: "master"}, {Name: "branch1"}, {Name: "branch2"}},
+		commits:  []gitee.PullRequestCommit{{Sha: sha}},
+		comments: []gitee.Comment{{ID: 1, Body: "/sync branch2 master"}},
+	}
+	s := &Server{GitClient: gogit.NewClient("file://" + root), GiteeClient: client}
+	// pull request #7 synchronized #1 from master to branch1
+	s.MergePullRequest(gitee.PullRequestEvent{
+		PullRequest: gitee.PullRequest{
+			Number: 7,
+			Title:  "[sync] PR-1: fix",
+			Body: "origin" + s.chainRecord("owner", "repo", []chainHop{{Branch: "master", Number: 1}},
+				messages(Chinese)),
+			State: gitee.StateMerged,
+			Head:  gitee.PullRequestBranch{Ref: "sync-pr1-master-to-branch1"},
+			Base:  gitee.PullRequestBranch{Ref: "branch1"},
+		},
+		Repository: gitee.Repository{Namespace: "owner", Path: "repo"},
+	})
+
+	if len(client.pullRequests) != 1 {
+		t.Fatalf("created pull requests = %v, want one to branch2", client.pullRequests)
+	}
+	created := client.pullRequests[0]
+	if created.Base.Ref != "branch2" || created.Title != "[sync] PR-7: fix" {
+		t.Errorf("created pull request to %s titled %q, want to branch2 titled %q", created.Base.Ref, created.Title,
+			"[sync] PR-7: fix")
+	}
+	want := []chainHop{{Branch: "master", Number: 1}, {Branch: "branch1", Number: 7}}
+	if got := parseChain(created.Body); !reflect.DeepEqual(got, want) {
+		t.Errorf("chain = %v, want %v", got, want)
+	}
+	reply := client.comments[len(client.comments)-1].Body
+	if want := "|master|已在同步链中，忽略处理|"; !strings.Contains(reply, want) {
+		t.Errorf("reply = %q, want containing %q", reply, want)
+	}
+}
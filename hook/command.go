@@ -2,8 +2,12 @@ package hook
 
 import (
 	"flag"
+	"path"
 	"regexp"
 	"strings"
+
+	"sync-bot/gitee"
+	"sync-bot/util"
 )
 
 // Strategy strategy of sync
@@ -20,6 +24,8 @@ const (
 type SyncCmdOption struct {
 	strategy Strategy
 	branches []string
+	// rules are the branches added by the rules of the repository
+	rules []string
 }
 
 func parseSyncCommand(command string) (*SyncCmdOption, error) {
@@ -38,3 +44,96 @@ func parseSyncCommand(command string) (*SyncCmdOption, error) {
 		branches: branches,
 	}, nil
 }
+
+// pendingSync replays the /sync and /sync-cancel commands in comments. It
+// returns the last /sync command with the branches cancelled afterwards
+// removed, or nil if there is no /sync command or all are cancelled. If union
+// is true, the branches of all /sync commands are accumulated instead. The
+// branches of the commands are expanded by expand if it is not nil.
+func pendingSync(comments []gitee.Comment, union bool, expand func([]string) []string) (*gitee.Comment, *SyncCmdOption) {
+	if expand == nil {
+		expand = func(branches []string) []string { return branches }
+	}
+	var command *gitee.Comment
+	var opt *SyncCmdOption
+	for i, c := range comments {
+		switch {
+		case util.MatchSync(c.Body):
+			o, err := parseSyncCommand(c.Body)
+			if err != nil {
+				continue
+			}
+			o.branches = expand(o.branches)
+			if union && opt != nil {
+				for _, b := range opt.branches {
+					if !contains(o.branches, b) {
+						o.branches = append(o.branches, b)
+					}
+				}
+			}
+			command, opt = &comments[i], o
+		case util.MatchSyncCancel(c.Body) && opt != nil:
+			cancelled := expand(util.ParseSyncCancel(c.Body))
+			var branches []string
+			for _, b := range opt.branches {
+				if len(cancelled) > 0 && !contains(cancelled, b) {
+					branches = append(branches, b)
+				}
+			}
+			opt.branches = branches
+		}
+		if opt != nil && len(opt.branches) == 0 {
+			command, opt = nil, nil
+		}
+	}
+	return command, opt
+}
+
+// expandBranches expands the aliases like "@maintained-lts" and the patterns
+// like "openEuler-22.03-LTS*" in args to the matching names, in the order of
+// names. Unknown aliases and patterns matching nothing are kept as is, so
+// they are reported as missing branches.
+func expandBranches(args []string, names []string, aliases map[string][]string) []string {
+	var branches []string
+	add := func(b string) {
+		if !contains(branches, b) {
+			branches = append(branches, b)
+		}
+	}
+	var expand func(arg string, depth int)
+	expand = func(arg string, depth int) {
+		if alias, ok := aliases[strings.TrimPrefix(arg, "@")]; ok && strings.HasPrefix(arg, "@") && depth < 8 {
+			for _, a := range alias {
+				expand(a, depth+1)
+			}
+			return
+		}
+		if !strings.ContainsAny(arg, "*?[") {
+			add(arg)
+			return
+		}
+		matched := false
+		for _, name := range names {
+			if ok, _ := path.Match(arg, name); ok {
+				add(name)
+				matched = true
+			}
+		}
+		if !matched {
+			add(arg)
+		}
+	}
+	for _, arg := range args {
+		expand(arg, 0)
+	}
+	return branches
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,8 @@ package hook
 import (
 	"reflect"
 	"testing"
+
+	"sync-bot/gitee"
 )
 
 func Test_parse(t *testing.T) {
@@ -103,3 +105,76 @@ func Test_parse(t *testing.T) {
 		})
 	}
 }
+
+func TestPendingSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []string
+		union    bool
+		command  string
+		branches []string
+	}{
+		{"no command", []string{"lgtm"}, false, "", nil},
+		{"last sync", []string{"/sync a b", "/sync c"}, false, "/sync c", []string{"c"}},
+		{"cancel branch", []string{"/sync a b c", "/sync-cancel b c"}, false, "/sync a b c", []string{"a"}},
+		{"cancel all", []string{"/sync a b", "/sync-cancel"}, false, "", nil},
+		{"cancel every branch", []string{"/sync a b", "/sync-cancel a", "/sync-cancel b"}, false, "", nil},
+		{"sync after cancel", []string{"/sync a", "/sync-cancel", "/sync b"}, false, "/sync b", []string{"b"}},
+		{"cancel before sync", []string{"/sync-cancel a", "/sync a"}, false, "/sync a", []string{"a"}},
+		{"union", []string{"/sync a b", "/sync b c"}, true, "/sync b c", []string{"b", "c", "a"}},
+		{"union with cancel", []string{"/sync a b", "/sync-cancel a", "/sync c"}, true, "/sync c", []string{"c", "b"}},
+		{"union cancel all", []string{"/sync a", "/sync b", "/sync-cancel", "/sync c"}, true, "/sync c", []string{"c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comments []gitee.Comment
+			for _, c := range tt.comments {
+				comments = append(comments, gitee.Comment{Body: c})
+			}
+			command, opt := pendingSync(comments, tt.union, nil)
+			if command == nil || opt == nil {
+				if tt.command != "" {
+					t.Fatalf("pendingSync() = nil, want %q", tt.command)
+				}
+				return
+			}
+			if command.Body != tt.command {
+				t.Errorf("pendingSync() command = %q, want %q", command.Body, tt.command)
+			}
+			if !reflect.DeepEqual(opt.branches, tt.branches) {
+				t.Errorf("pendingSync() branches = %v, want %v", opt.branches, tt.branches)
+			}
+		})
+	}
+}
+
+func TestExpandBranches(t *testing.T) {
+	names := []string{"master", "openEuler-20.03-LTS-SP1", "openEuler-20.03-LTS-SP2", "openEuler-22.03-LTS", "openEuler-22.03-LTS-SP1"}
+	aliases := map[string][]string{
+		"maintained-lts": {"openEuler-20.03-LTS-SP2", "openEuler-22.03-LTS*"},
+		"all":            {"master", "@maintained-lts"},
+		"loop":           {"@loop"},
+	}
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"literal", []string{"master", "missing"}, []string{"master", "missing"}},
+		{"pattern", []string{"openEuler-22.03-LTS*"}, []string{"openEuler-22.03-LTS", "openEuler-22.03-LTS-SP1"}},
+		{"pattern matching nothing", []string{"openEuler-24.03*"}, []string{"openEuler-24.03*"}},
+		{"alias", []string{"@maintained-lts"},
+			[]string{"openEuler-20.03-LTS-SP2", "openEuler-22.03-LTS", "openEuler-22.03-LTS-SP1"}},
+		{"nested alias without duplicates", []string{"openEuler-22.03-LTS", "@all"},
+			[]string{"openEuler-22.03-LTS", "master", "openEuler-20.03-LTS-SP2", "openEuler-22.03-LTS-SP1"}},
+		{"unknown alias", []string{"@unknown"}, []string{"@unknown"}},
+		{"recursive alias", []string{"@loop"}, []string{"@loop"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandBranches(tt.args, names, aliases); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandBranches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
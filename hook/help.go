@@ -0,0 +1,128 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"sync-bot/gitee"
+	"sync-bot/util"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxSuggestDistance is the maximum edit distance between a mistyped command
+// and the suggested one.
+const maxSuggestDistance = 3
+
+// suggestCommand returns the command most likely meant by word, like
+// "/sync-check" for "/sync-chek" and "/sync" for "/sync:branch1", or nil if
+// none is close enough.
+func suggestCommand(word string) *command {
+	var best *command
+	// a command followed by a separator, the longest one wins
+	for i, c := range commands {
+		if strings.HasPrefix(word, c.name) && (best == nil || len(c.name) > len(best.name)) {
+			if rest := []rune(word[len(c.name):]); len(rest) == 0 || !isWordRune(rest[0]) {
+				best = &commands[i]
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	distance := maxSuggestDistance + 1
+	for i, c := range commands {
+		if d := editDistance(word, c.name); d < distance {
+			best, distance = &commands[i], d
+		}
+	}
+	return best
+}
+
+// isWordRune returns whether r may be part of the name of a command.
+func isWordRune(r rune) bool {
+	return r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	prev := make([]int, len(y)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		cur := make([]int, len(y)+1)
+		cur[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			cur[j] = cur[j-1] + 1
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := prev[j-1] + cost; d < cur[j] {
+				cur[j] = d
+			}
+		}
+		prev = cur
+	}
+	return prev[len(y)]
+}
+
+// replySyncHelp replies the usage of the commands.
+func (s *Server) replySyncHelp(e gitee.CommentPullRequestEvent, lang Language) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	number := e.PullRequest.Number
+	logger := logrus.WithFields(logrus.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	})
+	msg := messages(lang)
+
+	data := syncHelpData{
+		URL:     e.Comment.HTMLURL,
+		Command: strings.TrimSpace(e.Comment.Body),
+		User:    e.Comment.User.Username,
+	}
+	for _, c := range commands {
+		data.Commands = append(data.Commands, commandHelp{Usage: c.usage, Description: msg.commandHelp[c.name]})
+	}
+	tmpl := s.template(owner, repo, e.PullRequest.Base.Ref, lang, syncHelpTmpl)
+	comment, err := executeTemplate(tmpl, data)
+	if err != nil {
+		logger.WithField("data", data).Errorln("Execute template failed:", err)
+		return
+	}
+	if err := s.GiteeClient.CreateComment(owner, repo, number, comment); err != nil {
+		logger.WithField("comment", comment).Errorln("Create comment failed:", err)
+	}
+}
+
+// replyUnknownCommand replies the command most likely meant by the comment
+// starting with /sync but matching no command.
+func (s *Server) replyUnknownCommand(e gitee.CommentPullRequestEvent, lang Language) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	number := e.PullRequest.Number
+	msg := messages(lang)
+
+	word := util.ParseSyncPrefix(e.Comment.Body)
+	comment := fmt.Sprintf(msg.unknownCommandNoSuggestion, word)
+	if c := suggestCommand(word); c != nil {
+		comment = fmt.Sprintf(msg.unknownCommand, word, c.usage)
+	}
+	if err := s.GiteeClient.CreateComment(owner, repo, number, comment); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"owner":   owner,
+			"repo":    repo,
+			"number":  number,
+			"comment": comment,
+		}).Errorln("Create comment failed:", err)
+	}
+}
@@ -0,0 +1,105 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/gitee"
+)
+
+func TestSuggestCommand(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"/sync-chek", "/sync-check"},
+		{"/sync-stauts", "/sync-status"},
+		{"/sync:branch1", "/sync"},
+		{"/sync-cancel:branch1", "/sync-cancel"},
+		{"/sync", "/sync"},
+		{"/sync-retyr", "/sync-retry"},
+		{"/synchronize-everything", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			got := ""
+			if c := suggestCommand(tt.word); c != nil {
+				got = c.name
+			}
+			if got != tt.want {
+				t.Errorf("suggestCommand(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"/sync", "", 5},
+		{"/sync-chek", "/sync-check", 1},
+		{"kitten", "sitting", 3},
+		{"同步", "同部", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNotePullRequestHelp(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    []string
+	}{
+		{
+			name:    "help",
+			comment: "/sync-help",
+			want: []string{
+				"|`/sync <branch>...`|Synchronize the current PR to the branches once it is merged",
+				"|`/sync-help`|Show this help|",
+			},
+		},
+		{
+			name:    "misspelled",
+			comment: "/sync-chek",
+			want:    []string{"Unrecognized command `/sync-chek`, did you mean `/sync-check`?"},
+		},
+		{
+			name:    "separator",
+			comment: "/sync:branch1",
+			want:    []string{"Unrecognized command `/sync:branch1`, did you mean `/sync <branch>...`?"},
+		},
+		{
+			name:    "no suggestion",
+			comment: "/synchronize-everything now",
+			want:    []string{"Unrecognized command `/synchronize-everything`, use `/sync-help`"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GiteeClient: client, Config: cfg}
+			s.NotePullRequest(gitee.CommentPullRequestEvent{
+				Comment:     gitee.Comment{ID: 1, Body: tt.comment, User: gitee.User{Username: "alice"}},
+				PullRequest: gitee.PullRequest{Number: 1, State: gitee.StateOpen},
+				Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+			})
+			if len(client.comments) != 1 {
+				t.Fatalf("comments = %v, want a reply", client.comments)
+			}
+			for _, want := range tt.want {
+				if got := client.comments[0].Body; !strings.Contains(got, want) {
+					t.Errorf("reply = %q, want containing %q", got, want)
+				}
+			}
+		})
+	}
+}
@@ -1,17 +1,19 @@
 package hook
 
 import (
+	"errors"
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 
 	"sync-bot/gitee"
-	"sync-bot/util"
 	"sync-bot/util/rpm"
 
 	"github.com/sirupsen/logrus"
 )
 
-func (s *Server) greeting(owner string, repo string, number int, targetBranch string) {
+func (s *Server) greeting(owner string, repo string, number int, targetBranch string, lang Language) {
 	logger := logrus.WithFields(logrus.Fields{
 		"owner":        owner,
 		"repo":         repo,
@@ -23,34 +25,85 @@ func (s *Server) greeting(owner string, repo string, number int, targetBranch st
 		logger.Errorln("Get Branches failed:", err)
 		return
 	}
-	for i, branch := range branches {
-		// convert branch to branch URL
-		if branches[i].Name == targetBranch {
-			// mark target branch of current pull request
-			branches[i].Name = fmt.Sprintf("__*__ [%s](https://gitee.com/%s/%s/tree/%s)",
-				branch.Name, owner, repo, branch.Name)
-		} else {
-			branches[i].Name = fmt.Sprintf("[%s](https://gitee.com/%s/%s/tree/%s)",
-				branch.Name, owner, repo, branch.Name)
+	// extract metadata from spec files, errNoSpec or the error of fetching
+	// them is cached as well
+	specs := make(map[string]*rpm.Spec)
+	specErrs := make(map[string]error)
+	getSpec := func(branch string) (*rpm.Spec, error) {
+		if spec, ok := specs[branch]; ok {
+			return spec, specErrs[branch]
+		}
+		specs[branch] = nil
+		file, err := s.specPath(owner, repo, branch)
+		if errors.Is(err, errNoSpec) {
+			logger.Infof("No spec file on %s", branch)
+			specErrs[branch] = err
+			return nil, err
+		} else if err != nil {
+			logger.Errorf("Find spec file on %s failed: %v", branch, err)
+			specErrs[branch] = err
+			return nil, err
 		}
-		// extract Version and Release from spec file
-		spec, err1 := s.GiteeClient.GetTextFile(owner, repo, repo+".spec", branch.Name)
-		if err1 != nil {
-			logger.Errorln("Get spec file failed:", err)
-			continue
+		content, err := s.GiteeClient.GetTextFile(owner, repo, file, branch)
+		if err != nil {
+			logger.Errorf("Get spec file %s on %s failed: %v", file, branch, err)
+			specErrs[branch] = err
+			return nil, err
 		}
-		s := rpm.NewSpec(spec)
-		if s != nil {
-			branches[i].Version = s.Version()
-			branches[i].Release = s.Release()
+		specs[branch] = rpm.NewSpecWithMacros(content, s.macros(owner, repo))
+		return specs[branch], nil
+	}
+	var targetPatches map[string]bool
+	var targetEVR string
+	if target, _ := getSpec(targetBranch); target != nil {
+		targetEVR = target.EVR()
+		targetPatches = make(map[string]bool)
+		for _, p := range target.Patches() {
+			targetPatches[path.Base(p)] = true
 		}
 	}
 
-	replyContent, err := executeTemplate(replySyncCheckTmpl, branches)
+	infos := make([]branchInfo, 0, len(branches))
+	for _, branch := range branches {
+		info := branchInfo{
+			Name:   fmt.Sprintf("[%s](%s)", branch.Name, s.provider().BranchURL(owner, repo, branch.Name)),
+			Branch: branch.Name,
+		}
+		if branch.Name == targetBranch {
+			// mark target branch of current pull request
+			info.Name = "__*__ " + info.Name
+		}
+		if spec, err := getSpec(branch.Name); spec != nil {
+			info.Version = spec.Version()
+			info.Release = spec.Release()
+			info.EVR = spec.EVR()
+			info.Changelog = tableCell(spec.Changelog())
+			if targetEVR != "" && info.EVR != "" && branch.Name != targetBranch {
+				c := rpm.CompareEVR(info.EVR, targetEVR)
+				info.Older = c < 0
+				info.Newer = c > 0
+			}
+			if targetPatches != nil && branch.Name != targetBranch {
+				for _, p := range spec.Patches() {
+					if !targetPatches[path.Base(p)] {
+						info.OnlyPatches = append(info.OnlyPatches, path.Base(p))
+					}
+				}
+			}
+		} else if errors.Is(err, errNoSpec) {
+			info.NoSpec = true
+		} else {
+			info.SpecError = true
+		}
+		infos = append(infos, info)
+	}
+
+	tmpl := s.template(owner, repo, targetBranch, lang, greetingTmpl)
+	replyContent, err := executeTemplate(tmpl, greetingData{Branches: infos, UnionSync: s.unionSync(owner, repo)})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"tmpl":     replySyncCheckTmpl,
-			"branches": branches,
+			"tmpl":     tmpl.Name(),
+			"branches": infos,
 		}).Errorln("Execute template failed:", err)
 		return
 	}
@@ -73,20 +126,21 @@ func (s *Server) greeting(owner string, repo string, number int, targetBranch st
 	}
 }
 
-func (s *Server) replySync(e gitee.CommentPullRequestEvent) {
+func (s *Server) replySync(e gitee.CommentPullRequestEvent, lang Language) {
 	owner := e.Repository.Namespace
 	repo := e.Repository.Path
 	number := e.PullRequest.Number
 	comment := e.Comment.Body
 	user := e.Comment.User.Username
 	url := e.Comment.HTMLURL
+	msg := messages(lang)
 
 	opt, err := parseSyncCommand(comment)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"opt": opt,
 		}).Errorln("Parse /sync command failed:", err)
-		comment := fmt.Sprintf("Receive comment look like /sync command, but parseSyncCommand failed: %v", err)
+		comment := fmt.Sprintf(msg.parseFailed, err)
 		logrus.Errorln(comment)
 		err = s.GiteeClient.CreateComment(owner, repo, number, comment)
 		if err != nil {
@@ -98,7 +152,7 @@ func (s *Server) replySync(e gitee.CommentPullRequestEvent) {
 	// retrieve all branches
 	allBranches, err := s.GiteeClient.GetBranches(owner, repo, false)
 	if err != nil {
-		comment := fmt.Sprintf("List branches failed: %v", err)
+		comment := fmt.Sprintf(msg.listBranchesFailed, err)
 		logrus.Errorln(comment)
 		err = s.GiteeClient.CreateComment(owner, repo, number, comment)
 		if err != nil {
@@ -107,41 +161,49 @@ func (s *Server) replySync(e gitee.CommentPullRequestEvent) {
 		return
 	}
 	branchSet := make(map[string]bool)
+	var names []string
 	for _, b := range allBranches {
 		branchSet[b.Name] = true
+		names = append(names, b.Name)
 	}
+	opt.branches = expandBranches(opt.branches, names, s.aliases(owner, repo))
 
 	var synBranches []branchStatus
 	for _, b := range opt.branches {
 		if ok := branchSet[b]; ok {
 			synBranches = append(synBranches, branchStatus{
 				Name:   b,
-				Status: branchExist,
+				Status: msg.branchExist,
 			})
 		} else {
 			synBranches = append(synBranches, branchStatus{
 				Name:   b,
-				Status: branchNonExist,
+				Status: msg.branchNonExist,
 			})
 		}
 	}
 
-	data := struct {
-		URL      string
-		Command  string
-		User     string
-		Branches []branchStatus
-	}{
+	data := replySyncData{
 		URL:      url,
 		Command:  strings.TrimSpace(comment),
 		User:     user,
 		Branches: synBranches,
 	}
+	// the branches of the former /sync commands are synchronized as well
+	if s.unionSync(owner, repo) {
+		comments, err := s.listComments(e)
+		if err != nil {
+			logrus.Errorln("List PullRequest comments failed:", err)
+		} else if _, opt := pendingSync(comments, true, s.expander(owner, repo)); opt != nil {
+			data.Plan = opt.branches
+		}
+	}
 
-	replyComment, err := executeTemplate(replySyncTmpl, data)
+	tmpl := s.template(owner, repo, e.PullRequest.Base.Ref, lang, replySyncTmpl)
+	replyComment, err := executeTemplate(tmpl, data)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"tmpl": replySyncTmpl,
+			"tmpl": tmpl.Name(),
 			"data": data,
 		}).Errorln("Execute template failed:", err)
 		return
@@ -164,6 +226,170 @@ func (s *Server) replySync(e gitee.CommentPullRequestEvent) {
 	}
 }
 
+// unionSync returns whether owner/repo synchronizes to the branches of all
+// /sync commands.
+func (s *Server) unionSync(owner, repo string) bool {
+	return s.Config != nil && s.Config.Repo(owner, repo).UnionSync
+}
+
+// aliases returns the aliases of branches configured for owner/repo.
+func (s *Server) aliases(owner, repo string) map[string][]string {
+	if s.Config == nil {
+		return nil
+	}
+	return s.Config.Repo(owner, repo).Aliases
+}
+
+// expander returns a function expanding the aliases and patterns of branches
+// of owner/repo, the branches are listed on first use.
+func (s *Server) expander(owner, repo string) func([]string) []string {
+	var names []string
+	listed := false
+	return func(args []string) []string {
+		if !listed {
+			listed = true
+			branches, err := s.GiteeClient.GetBranches(owner, repo, false)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"owner": owner,
+					"repo":  repo,
+				}).Errorln("List branches failed:", err)
+			}
+			for _, b := range branches {
+				names = append(names, b.Name)
+			}
+		}
+		return expandBranches(args, names, s.aliases(owner, repo))
+	}
+}
+
+// tableCell escapes s to be a cell of a markdown table.
+func tableCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "<", "&lt;", ">", "&gt;", "\n", " ").Replace(s)
+}
+
+// errNoSpec is returned by specPath if there is no spec file on the ref.
+var errNoSpec = errors.New("no spec file")
+
+// specPath returns the path of the spec file of owner/repo on ref. The path
+// configured for the repo is used if any, otherwise the tree of ref is
+// searched for *.spec files, preferring "<repo>.spec" and then the one
+// closest to the root.
+func (s *Server) specPath(owner, repo, ref string) (string, error) {
+	if s.Config != nil {
+		if file := s.Config.Repo(owner, repo).Spec; file != "" {
+			return file, nil
+		}
+	}
+	files, err := s.GiteeClient.ListFiles(owner, repo, ref)
+	if err != nil {
+		return "", err
+	}
+	var specs []string
+	for _, f := range files {
+		if strings.HasSuffix(f, ".spec") {
+			specs = append(specs, f)
+		}
+	}
+	if len(specs) == 0 {
+		return "", errNoSpec
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		a, b := specs[i], specs[j]
+		if na, nb := path.Base(a) == repo+".spec", path.Base(b) == repo+".spec"; na != nb {
+			return na
+		}
+		if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+			return da < db
+		}
+		return a < b
+	})
+	return specs[0], nil
+}
+
+// macros returns the rpm macros configured for owner/repo.
+func (s *Server) macros(owner, repo string) map[string]string {
+	if s.Config == nil {
+		return nil
+	}
+	return s.Config.Repo(owner, repo).Macros
+}
+
+// replySyncLang acknowledges the /sync-lang preference in the requested
+// language, or lists the supported languages in lang if requested is not
+// supported.
+func (s *Server) replySyncLang(owner string, repo string, number int, lang Language, requested string) {
+	var comment string
+	if _, ok := catalogs[Language(requested)]; ok {
+		comment = fmt.Sprintf(messages(Language(requested)).languageChanged, languageNames[Language(requested)])
+	} else {
+		comment = fmt.Sprintf(messages(lang).unknownLanguage, requested, supportedLanguages())
+	}
+	err := s.GiteeClient.CreateComment(owner, repo, number, comment)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"owner":   owner,
+			"repo":    repo,
+			"number":  number,
+			"comment": comment,
+		}).Errorln("Create comment failed:", err)
+	}
+}
+
+// listComments lists the comments of the pull request of e, the comment of e
+// included, without the commands of unauthorized users.
+func (s *Server) listComments(e gitee.CommentPullRequestEvent) ([]gitee.Comment, error) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	comments, err := s.GiteeClient.ListPullRequestComments(owner, repo, e.PullRequest.Number)
+	if err != nil {
+		return nil, err
+	}
+	// the event may arrive before the comment is listed
+	if n := len(comments); n == 0 || comments[n-1].ID != e.Comment.ID {
+		comments = append(comments, e.Comment)
+	}
+	return s.authorizedComments(owner, repo, e.PullRequest.Base.Ref, commandLines(comments)), nil
+}
+
+// replySyncCancel confirms the /sync-cancel command of branches, all branches
+// if empty, with the branches to be synchronized afterwards.
+func (s *Server) replySyncCancel(e gitee.CommentPullRequestEvent, lang Language, branches []string) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	number := e.PullRequest.Number
+	logger := logrus.WithFields(logrus.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	})
+	msg := messages(lang)
+
+	var comment string
+	if e.PullRequest.State == gitee.StateMerged {
+		comment = msg.cancelAfterMerge
+	} else {
+		comments, err := s.listComments(e)
+		if err != nil {
+			logger.Errorln("List PullRequest comments failed:", err)
+			return
+		}
+		if len(branches) > 0 {
+			comment = fmt.Sprintf(msg.syncCancelled, strings.Join(branches, ", "))
+		} else {
+			comment = msg.syncCancelledAll
+		}
+		if _, opt := pendingSync(comments, s.unionSync(owner, repo), s.expander(owner, repo)); opt != nil {
+			comment += "\n\n" + fmt.Sprintf(msg.syncPending, strings.Join(opt.branches, ", "))
+		} else {
+			comment += "\n\n" + msg.noPendingSync
+		}
+	}
+	if err := s.GiteeClient.CreateComment(owner, repo, number, comment); err != nil {
+		logger.WithField("comment", comment).Errorln("Create comment failed:", err)
+	}
+}
+
 func (s *Server) NotePullRequest(e gitee.CommentPullRequestEvent) {
 	owner := e.Repository.Namespace
 	repo := e.Repository.Path
@@ -188,38 +414,21 @@ func (s *Server) NotePullRequest(e gitee.CommentPullRequestEvent) {
 	})
 	logger.Infoln("NotePullRequest")
 
-	if util.MatchSyncCheck(comment) {
-		logger.Infoln("Receive /sync-check command")
-		s.greeting(owner, repo, number, targetBranch)
-		return
-	}
-
-	if util.MatchSync(comment) {
-		logger.Infoln("Receive /sync command")
-		switch state {
-		case gitee.StateOpen:
-			logger.Infoln("Pull request is open, just replay sync.")
-			s.replySync(e)
-		case gitee.StateMerged:
-			logger.Infoln("Pull request is merge, perform sync operation.")
-			_ = s.sync(owner, repo, e.PullRequest, user, url, comment)
-		default:
-			logger.Infoln("Ignoring unhandled pull request state.")
+	// a command per line
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		if line = strings.TrimSpace(line); line != "" && isCommand(line) {
+			lines = append(lines, line)
 		}
-		return
 	}
-
-	if util.MatchClose(comment) {
-		logger.Infoln("Receive /close command")
-		if util.MatchTitle(title) {
-			s.ClosePullRequest(owner, repo, e.PullRequest)
-		} else {
-			logger.Infoln("Pull request not created by sync-bot, ignoring /close.")
-		}
+	if len(lines) == 0 {
+		logger.Infoln("Ignoring unhandled comment.")
 		return
 	}
-
-	logger.Infoln("Ignoring unhandled comment.")
+	lang := s.language(owner, repo, number)
+	for _, line := range lines {
+		s.runCommand(e, lang, line)
+	}
 }
 
 func (s *Server) HandleNoteEvent(e gitee.CommentPullRequestEvent) {
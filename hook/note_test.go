@@ -0,0 +1,170 @@
+package hook
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/gitee"
+)
+
+func TestGreeting(t *testing.T) {
+	spec := `
+Name: repo
+Version: 1.0
+Release: 1%{?dist}
+Patch0: 0001-common.patch
+`
+	client := &fakeClient{
+		branches: []gitee.Branch{{Name: "master"}, {Name: "branch1"}, {Name: "branch2"}, {Name: "old1"}, {Name: "old2"}, {Name: "new"},
+			{Name: "broken"}},
+		listErrors: map[string]error{"broken": errors.New("tree of broken is truncated")},
+		files: map[string]string{
+			"master:repo.spec": spec,
+			"branch1:repo.spec": spec + `Patch1: 0002-only-branch1.patch
+
+%changelog
+* Mon Jan 01 2024 Packager <packager@example.com> - 1.0-1
+- backport
+`,
+			"old1:SPECS/other.spec": strings.Replace(spec, "1.0", "0.9", 1),
+			"old2:repo.spec":        strings.Replace(spec, "1%{?dist}", "0.1%{?dist}", 1),
+			"new:repo.spec":         "Epoch: 1\n" + spec,
+		},
+	}
+	cfg := &config.Config{Repos: map[string]config.Repo{
+		"owner": {Language: "en", Macros: map[string]string{"dist": ".oe2203"}},
+	}}
+	s := &Server{GiteeClient: client, Config: cfg}
+	s.greeting("owner", "repo", 1, "master", English)
+
+	if len(client.comments) != 1 {
+		t.Fatalf("comments = %v, want 1 comment", client.comments)
+	}
+	got := client.comments[0].Body
+	for _, want := range []string{
+		"|__*__ [master](https://gitee.com/owner/repo/tree/master)|1.0|1.oe2203|1.0-1.oe2203||\n",
+		"|[branch1](https://gitee.com/owner/repo/tree/branch1)|1.0|1.oe2203|1.0-1.oe2203|Mon Jan 01 2024 Packager &lt;packager@example.com&gt; - 1.0-1|\n",
+		"|[branch2](https://gitee.com/owner/repo/tree/branch2)|_no spec_|-|-|-|\n",
+		"|[broken](https://gitee.com/owner/repo/tree/broken)|_failed to get spec_|-|-|-|\n",
+		"> [branch1](https://gitee.com/owner/repo/tree/branch1) has patches not on the target branch: `0002-only-branch1.patch`\n",
+		"|[old1](https://gitee.com/owner/repo/tree/old1)|0.9|1.oe2203|0.9-1.oe2203 (older)||\n",
+		"|[new](https://gitee.com/owner/repo/tree/new)|1.0|1.oe2203|1:1.0-1.oe2203 (__newer, syncing would downgrade it__)||\n",
+		"> Branches older than the target branch can be synchronized by `/sync old1 old2`\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("greeting = %s, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestSpecPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		spec    string
+		want    string
+		wantErr error
+	}{
+		{"repo name", []string{"a.spec", "repo.spec"}, "", "repo.spec", nil},
+		{"in subdirectory", []string{"README.md", "SPECS/repo.spec", "b.spec"}, "", "SPECS/repo.spec", nil},
+		{"other name", []string{"SPECS/b.spec", "a.spec"}, "", "a.spec", nil},
+		{"configured", []string{"repo.spec"}, "dist/pkg.spec", "dist/pkg.spec", nil},
+		{"no spec", []string{"README.md"}, "", "", errNoSpec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{files: make(map[string]string)}
+			for _, f := range tt.files {
+				client.files["master:"+f] = ""
+			}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner/repo": {Spec: tt.spec}}}
+			s := &Server{GiteeClient: client, Config: cfg}
+			got, err := s.specPath("owner", "repo", "master")
+			if err != tt.wantErr {
+				t.Fatalf("specPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("specPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplySyncPlan(t *testing.T) {
+	tests := []struct {
+		name  string
+		union bool
+		want  string
+	}{
+		{"last command", false, ""},
+		{"union", true, "it will be synchronized to the branches of all /sync commands: `branch2`, `branch1`\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{
+				branches: []gitee.Branch{{Name: "master"}, {Name: "branch1"}, {Name: "branch2"}},
+				comments: []gitee.Comment{{ID: 1, Body: "/sync branch1"}},
+			}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en", UnionSync: tt.union}}}
+			s := &Server{GiteeClient: client, Config: cfg}
+			s.replySync(gitee.CommentPullRequestEvent{
+				Comment:     gitee.Comment{ID: 2, Body: "/sync branch2"},
+				PullRequest: gitee.PullRequest{Number: 1, State: gitee.StateOpen},
+				Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+			}, English)
+			if len(client.comments) != 2 {
+				t.Fatalf("comments = %v, want a reply", client.comments)
+			}
+			got := client.comments[1].Body
+			if tt.want == "" && strings.Contains(got, "all /sync commands") || !strings.Contains(got, tt.want) {
+				t.Errorf("reply = %s, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplySyncExpanded(t *testing.T) {
+	client := &fakeClient{
+		branches: []gitee.Branch{{Name: "master"}, {Name: "openEuler-22.03-LTS"}, {Name: "openEuler-22.03-LTS-SP1"}},
+		comments: []gitee.Comment{{ID: 1, Body: "/sync openEuler-22.03-LTS* @lts"}, {ID: 2, Body: "/sync-cancel openEuler-22.03-LTS"}},
+	}
+	cfg := &config.Config{Repos: map[string]config.Repo{
+		"owner": {Language: "en", UnionSync: true, Aliases: map[string][]string{"lts": {"openEuler-20.03-LTS"}}},
+	}}
+	s := &Server{GiteeClient: client, Config: cfg}
+	s.replySync(gitee.CommentPullRequestEvent{
+		Comment:     gitee.Comment{ID: 3, Body: "/sync master"},
+		PullRequest: gitee.PullRequest{Number: 1, State: gitee.StateOpen},
+		Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+	}, English)
+	if len(client.comments) != 3 {
+		t.Fatalf("comments = %v, want a reply", client.comments)
+	}
+	got := client.comments[2].Body
+	for _, want := range []string{
+		"|master|sync operation will be performed|\n",
+		"branches of all /sync commands: `master`, `openEuler-22.03-LTS-SP1`, `openEuler-20.03-LTS`\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("reply = %s, want it to contain %q", got, want)
+		}
+	}
+
+	s.replySync(gitee.CommentPullRequestEvent{
+		Comment:     gitee.Comment{ID: 4, Body: "/sync @lts openEuler-22.03-LTS*"},
+		PullRequest: gitee.PullRequest{Number: 1, State: gitee.StateOpen},
+		Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+	}, English)
+	got = client.comments[len(client.comments)-1].Body
+	for _, want := range []string{
+		"|openEuler-20.03-LTS|branch not found, ignored|\n",
+		"|openEuler-22.03-LTS|sync operation will be performed|\n",
+		"|openEuler-22.03-LTS-SP1|sync operation will be performed|\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("reply = %s, want it to contain %q", got, want)
+		}
+	}
+}
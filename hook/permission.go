@@ -0,0 +1,91 @@
+package hook
+
+import (
+	"sync-bot/gitee"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// ownersFile lists the maintainers and committers of a repository, who are
+// allowed to run commands besides the collaborators with write permission.
+const ownersFile = "OWNERS"
+
+// owners is the content of ownersFile, like
+//
+//	maintainers:
+//	- alice
+//	committers:
+//	- bob
+type owners struct {
+	Maintainers []string `yaml:"maintainers"`
+	Committers  []string `yaml:"committers"`
+}
+
+// writePermissions are the permissions of collaborators allowed to run
+// commands, as returned by the providers.
+var writePermissions = map[string]bool{
+	"owner":    true,
+	"admin":    true,
+	"maintain": true,
+	"write":    true,
+	"push":     true,
+}
+
+// restricted returns whether the command in line requires authorization.
+func restricted(line string) bool {
+	c, _ := lookupCommand(line)
+	return c != nil && c.permission == collaborator
+}
+
+// authorized returns whether user is allowed to run commands on owner/repo,
+// that is the user is listed in the OWNERS file on ref, or has write
+// permission on the repository.
+func (s *Server) authorized(owner, repo, ref, user string) bool {
+	logger := logrus.WithFields(logrus.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"ref":   ref,
+		"user":  user,
+	})
+	if content, err := s.GiteeClient.GetTextFile(owner, repo, ownersFile, ref); err == nil {
+		var o owners
+		if err := yaml.Unmarshal([]byte(content), &o); err != nil {
+			logger.Warnln("Parse OWNERS failed:", err)
+		} else if contains(o.Maintainers, user) || contains(o.Committers, user) {
+			return true
+		}
+	}
+	permission, err := s.GiteeClient.GetPermission(owner, repo, user)
+	if err != nil {
+		logger.Infoln("Get permission failed:", err)
+		return false
+	}
+	return writePermissions[permission]
+}
+
+// authorizedComments drops the restricted commands of unauthorized users
+// from comments.
+func (s *Server) authorizedComments(owner, repo, ref string, comments []gitee.Comment) []gitee.Comment {
+	users := make(map[string]bool)
+	result := make([]gitee.Comment, 0, len(comments))
+	for _, c := range comments {
+		if restricted(c.Body) {
+			user := c.User.Username
+			ok, checked := users[user]
+			if !checked {
+				ok = s.authorized(owner, repo, ref, user)
+				users[user] = ok
+			}
+			if !ok {
+				logrus.WithFields(logrus.Fields{
+					"user":    user,
+					"comment": c.Body,
+				}).Infoln("Ignore command of unauthorized user")
+				continue
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/gitee"
+)
+
+func TestAuthorized(t *testing.T) {
+	client := &fakeClient{
+		files: map[string]string{
+			"master:OWNERS":   "maintainers:\n- alice\ncommitters:\n- bob\n",
+			"broken:OWNERS":   "maintainers: [",
+			"other:README.md": "readme",
+		},
+		permissions: map[string]string{"carol": "write", "dave": "read", "erin": "admin"},
+	}
+	s := &Server{GiteeClient: client}
+	tests := []struct {
+		ref  string
+		user string
+		want bool
+	}{
+		{"master", "alice", true},
+		{"master", "bob", true},
+		{"master", "carol", true},
+		{"master", "dave", false},
+		{"master", "frank", false},
+		{"other", "alice", false},
+		{"other", "erin", true},
+		{"broken", "carol", true},
+		{"broken", "bob", false},
+	}
+	for _, tt := range tests {
+		if got := s.authorized("owner", "repo", tt.ref, tt.user); got != tt.want {
+			t.Errorf("authorized(%q, %q) = %v, want %v", tt.ref, tt.user, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizedComments(t *testing.T) {
+	client := &fakeClient{permissions: map[string]string{"alice": "write", "bob": "read"}}
+	s := &Server{GiteeClient: client}
+	comments := []gitee.Comment{
+		{ID: 1, Body: "/sync branch1", User: gitee.User{Username: "alice"}},
+		{ID: 2, Body: "/sync branch2", User: gitee.User{Username: "bob"}},
+		{ID: 3, Body: "lgtm", User: gitee.User{Username: "bob"}},
+		{ID: 4, Body: "/sync-status", User: gitee.User{Username: "bob"}},
+		{ID: 5, Body: "/sync-cancel", User: gitee.User{Username: "bob"}},
+	}
+	var got []int
+	for _, c := range s.authorizedComments("owner", "repo", "master", comments) {
+		got = append(got, c.ID)
+	}
+	if want := []int{1, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("authorizedComments() = %v, want %v", got, want)
+	}
+}
+
+func TestNotePullRequestUnauthorized(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		user    string
+		want    string
+	}{
+		{"sync", "/sync branch1", "bob", "@bob is not authorized to run this command"},
+		{"cancel", "/sync-cancel", "bob", "@bob is not authorized to run this command"},
+		{"status", "/sync-status", "bob", "There is no synchronization to perform"},
+		{"authorized", "/sync branch1", "alice", "|branch1|sync operation will be performed|"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{
+				branches:    []gitee.Branch{{Name: "master"}, {Name: "branch1"}},
+				permissions: map[string]string{"alice": "write", "bob": "read"},
+			}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GiteeClient: client, Config: cfg}
+			s.NotePullRequest(gitee.CommentPullRequestEvent{
+				Comment: gitee.Comment{ID: 1, Body: tt.comment, User: gitee.User{Username: tt.user}},
+				PullRequest: gitee.PullRequest{
+					Number: 1,
+					State:  gitee.StateOpen,
+					Base:   gitee.PullRequestBranch{Ref: "master"},
+				},
+				Repository: gitee.Repository{Namespace: "owner", Path: "repo"},
+			})
+			if len(client.comments) != 1 {
+				t.Fatalf("comments = %v, want a reply", client.comments)
+			}
+			if got := client.comments[0].Body; !strings.Contains(got, tt.want) {
+				t.Errorf("reply = %q, want containing %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package hook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/git"
+	"sync-bot/gitee"
+)
+
+// Provider is a code hosting platform, such as Gitee or GitHub.
+// Events and API objects of all providers use the model of package gitee.
+type Provider interface {
+	// Name is the name of the provider, webhooks of providers other than
+	// Gitee are served at /hook/<name>.
+	Name() string
+	// Authorize checks whether a webhook request is sent by the provider.
+	Authorize(h http.Header, payload []byte) error
+	// ParseWebhook returns *gitee.PullRequestEvent, *gitee.CommentPullRequestEvent,
+	// or nil if the event is not handled by sync-bot.
+	ParseWebhook(h http.Header, payload []byte) (interface{}, error)
+	// RepoURL returns the web URL of owner/repo.
+	RepoURL(owner, repo string) string
+	// BranchURL returns the web URL of a branch of owner/repo.
+	BranchURL(owner, repo, branch string) string
+	// PullRequestURL returns the web URL of a pull request of owner/repo.
+	PullRequestURL(owner, repo string, number int) string
+	// PullRequestRef returns the pattern of the git refs of pull request
+	// heads, like git.PullRequestRef, which the git clients fetch.
+	PullRequestRef() string
+}
+
+// GiteeProvider is the Provider of Gitee.
+type GiteeProvider struct {
+	// Secret returns the webhook secret.
+	Secret func() []byte
+	// Web is the web URL of Gitee, gitee.DefaultURL if empty.
+	Web string
+}
+
+func (p *GiteeProvider) web() string {
+	if p.Web == "" {
+		return gitee.DefaultURL
+	}
+	return strings.TrimSuffix(p.Web, "/")
+}
+
+// Name implements Provider.
+func (p *GiteeProvider) Name() string {
+	return "gitee"
+}
+
+// Authorize implements Provider, Gitee sends the secret in X-Gitee-Token.
+func (p *GiteeProvider) Authorize(h http.Header, payload []byte) error {
+	if !hmac.Equal([]byte(h.Get("X-Gitee-Token")), p.Secret()) {
+		return errUnauthorized
+	}
+	return nil
+}
+
+// ParseWebhook implements Provider.
+func (p *GiteeProvider) ParseWebhook(h http.Header, payload []byte) (interface{}, error) {
+	if h.Get("X-Gitee-Ping") == "true" {
+		logrus.Infoln("Receive the Ping Event:", h.Get("X-Gitee-Event"))
+		return nil, nil
+	}
+	eventType := gitee.EventType(h.Get("X-Gitee-Event"))
+	if eventType == "" {
+		return nil, errors.New("missing X-Gitee-Event header")
+	}
+	switch eventType {
+	case gitee.MergeRequestHook:
+		var e gitee.PullRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	case gitee.NoteHook:
+		var e gitee.CommentPullRequestEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	default:
+		logrus.Infoln("Ignoring unhandled event type:", eventType)
+		return nil, nil
+	}
+}
+
+// RepoURL implements Provider.
+func (p *GiteeProvider) RepoURL(owner, repo string) string {
+	return fmt.Sprintf("%s/%s/%s", p.web(), owner, repo)
+}
+
+// BranchURL implements Provider.
+func (p *GiteeProvider) BranchURL(owner, repo, branch string) string {
+	return fmt.Sprintf("%s/%s/%s/tree/%s", p.web(), owner, repo, branch)
+}
+
+// PullRequestURL implements Provider.
+func (p *GiteeProvider) PullRequestURL(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/%s/%s/pulls/%d", p.web(), owner, repo, number)
+}
+
+// PullRequestRef implements Provider.
+func (p *GiteeProvider) PullRequestRef() string {
+	return git.PullRequestRef
+}
@@ -0,0 +1,30 @@
+package hook
+
+import "testing"
+
+func TestGiteeProviderURLs(t *testing.T) {
+	tests := []struct {
+		name   string
+		web    string
+		repo   string
+		branch string
+		pull   string
+	}{
+		{"default", "", "https://gitee.com/owner/repo", "https://gitee.com/owner/repo/tree/master", "https://gitee.com/owner/repo/pulls/1"},
+		{"enterprise", "https://git.example.com/", "https://git.example.com/owner/repo", "https://git.example.com/owner/repo/tree/master", "https://git.example.com/owner/repo/pulls/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &GiteeProvider{Web: tt.web}
+			if got := p.RepoURL("owner", "repo"); got != tt.repo {
+				t.Errorf("RepoURL() = %v, want %v", got, tt.repo)
+			}
+			if got := p.BranchURL("owner", "repo", "master"); got != tt.branch {
+				t.Errorf("BranchURL() = %v, want %v", got, tt.branch)
+			}
+			if got := p.PullRequestURL("owner", "repo", 1); got != tt.pull {
+				t.Errorf("PullRequestURL() = %v, want %v", got, tt.pull)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"sync-bot/git"
 	"sync-bot/gitee"
 	"sync-bot/util"
+	"sync-bot/util/rpm"
 )
 
 func (s *Server) OpenPullRequest(e gitee.PullRequestEvent) {
@@ -24,7 +25,7 @@ func (s *Server) OpenPullRequest(e gitee.PullRequestEvent) {
 		"number": number,
 		"title":  title,
 	}).Infoln("OpenPullRequest")
-	s.greeting(owner, repo, number, targetBranch)
+	s.greeting(owner, repo, number, targetBranch, s.language(owner, repo, number))
 }
 
 func (s *Server) MergePullRequest(e gitee.PullRequestEvent) {
@@ -42,27 +43,41 @@ func (s *Server) MergePullRequest(e gitee.PullRequestEvent) {
 		logrus.Errorln("List PullRequest comments failed", err)
 		return
 	}
+	lang := s.languageOf(owner, repo, comments)
+	comments = s.authorizedComments(owner, repo, e.PullRequest.Base.Ref, commandLines(comments))
 	logrus.WithFields(logrus.Fields{
 		"comments": comments,
 	}).Infoln("Get all comments")
 
-	// find the last /sync command
-	for i, c := range comments {
-		comment := comments[len(comments)-1-i]
-		user := c.User.Username
-		url := c.HTMLURL
-		body := comment.Body
-		if util.MatchSync(body) {
+	// the last /sync command, without the branches cancelled by /sync-cancel
+	command, opt := pendingSync(comments, s.unionSync(owner, repo), s.expander(owner, repo))
+	// the branches of the rules of the repository are synchronized as well
+	ruleBranches := s.ruleBranches(e)
+	if command == nil {
+		if len(ruleBranches) == 0 {
 			logrus.WithFields(logrus.Fields{
-				"comment": body,
-			}).Infoln("match /sync command")
-			_ = s.sync(owner, repo, e.PullRequest, user, url, body)
+				"comments": comments,
+			}).Warnln("Not found valid /sync command in pr comments")
 			return
 		}
+		// reply to the author of the pull request, not to any command
+		opt = &SyncCmdOption{strategy: Pick, branches: ruleBranches, rules: ruleBranches}
+		logrus.WithField("branches", opt.branches).Infoln("match sync rules")
+		_ = s.syncBranches(owner, repo, e.PullRequest, e.PullRequest.User.Username, e.PullRequest.HTMLURL, "", opt,
+			lang)
+		return
+	}
+	for _, b := range ruleBranches {
+		if !contains(opt.branches, b) {
+			opt.branches = append(opt.branches, b)
+			opt.rules = append(opt.rules, b)
+		}
 	}
 	logrus.WithFields(logrus.Fields{
-		"comments": comments,
-	}).Warnln("Not found valid /sync command in pr comments")
+		"comment":  command.Body,
+		"branches": opt.branches,
+	}).Infoln("match /sync command")
+	_ = s.syncBranches(owner, repo, e.PullRequest, command.User.Username, command.HTMLURL, command.Body, opt, lang)
 }
 
 func (s *Server) AutoMerge(e gitee.PullRequestEvent) {
@@ -85,7 +100,7 @@ func (s *Server) AutoMerge(e gitee.PullRequestEvent) {
 
 	if !mergeable {
 		logger.Infoln("The current pull request can not be merge.")
-		comment := "The current pull request can not be merge. "
+		comment := messages(s.language(owner, repo, number)).notMergeable
 		err := s.GiteeClient.CreateComment(owner, repo, number, comment)
 		if err != nil {
 			logger.Errorf("Create comment failed: %v", err)
@@ -126,8 +141,15 @@ func (s *Server) AutoMerge(e gitee.PullRequestEvent) {
 	}
 }
 
+// mergeSpec is the merge driver of spec files, conflicts of Release and
+// %changelog are resolved.
+func mergeSpec(base, ours, theirs []byte) ([]byte, error) {
+	merged, err := rpm.MergeSpec(string(base), string(ours), string(theirs))
+	return []byte(merged), err
+}
+
 func (s *Server) pick(owner string, repo string, opt *SyncCmdOption, branchSet map[string]bool, pr gitee.PullRequest,
-	title string, body string, firstSha string, lastSha string) ([]syncStatus, error) {
+	title string, body string, firstSha string, lastSha string, msg *catalog) ([]syncStatus, error) {
 	number := pr.Number
 	sourceBranch := pr.Head.Ref
 	r, err := s.GitClient.Clone(owner, repo)
@@ -135,21 +157,23 @@ func (s *Server) pick(owner string, repo string, opt *SyncCmdOption, branchSet m
 		logrus.Errorf("Clone %s/%s failed: %v", owner, repo, err)
 		return nil, err
 	}
+	r.SetMergeDriver("*.spec", mergeSpec)
 
 	var status []syncStatus
 	for _, branch := range opt.branches {
 		// branch not in repository
 		if ok := branchSet[branch]; !ok {
 			status = append(status, syncStatus{
-				Name:   branch,
-				Status: branchNonExist,
+				Name:     branch,
+				Status:   msg.branchNonExist,
+				NotFound: true,
 			})
 			continue
 		}
 
 		// pull for big repos by using upstream repos
 		if owner == "openeuler" && repo == "kernel" {
-			bigRemote := fmt.Sprintf("%s/%s.git", "https://gitee.com", owner+"/"+repo)
+			bigRemote := s.provider().RepoURL(owner, repo) + ".git"
 
 			// check remote
 			if hasUpstream, _ := r.ListRemote(); !hasUpstream {
@@ -276,7 +300,7 @@ func (s *Server) pick(owner string, repo string, opt *SyncCmdOption, branchSet m
 			logrus.Errorln("Cherry pick failed:", err.Error())
 			status = append(status, syncStatus{
 				Name:   branch,
-				Status: syncFailed,
+				Status: msg.syncFailed,
 			})
 			continue
 		}
@@ -313,15 +337,15 @@ func (s *Server) pick(owner string, repo string, opt *SyncCmdOption, branchSet m
 			st = err.Error()
 		} else {
 			logrus.Infoln("Create PullRequest:", num)
-			st = createdPR
-			url = fmt.Sprintf("https://gitee.com/%v/%v/pulls/%v", owner, repo, num)
+			st = msg.createdPR
+			url = s.provider().PullRequestURL(owner, repo, num)
 		}
-		status = append(status, syncStatus{Name: branch, Status: st, PR: url})
+		status = append(status, syncStatus{Name: branch, Status: st, PR: url, Number: num})
 	}
 	return status, nil
 }
 
-func (s *Server) merge(owner string, repo string, opt *SyncCmdOption, branchSet map[string]bool, pr gitee.PullRequest, title string, body string) ([]syncStatus, error) {
+func (s *Server) merge(owner string, repo string, opt *SyncCmdOption, branchSet map[string]bool, pr gitee.PullRequest, title string, body string, msg *catalog) ([]syncStatus, error) {
 	number := pr.Number
 	ref := pr.Head.Sha
 
@@ -330,8 +354,9 @@ func (s *Server) merge(owner string, repo string, opt *SyncCmdOption, branchSet
 		// branch not in repository
 		if ok := branchSet[branch]; !ok {
 			status = append(status, syncStatus{
-				Name:   branch,
-				Status: branchNonExist,
+				Name:     branch,
+				Status:   msg.branchNonExist,
+				NotFound: true,
 			})
 			continue
 		}
@@ -355,10 +380,10 @@ func (s *Server) merge(owner string, repo string, opt *SyncCmdOption, branchSet
 			st = err.Error()
 		} else {
 			logrus.Infoln("Create PullRequest:", num)
-			st = "Create sync PR"
-			url = fmt.Sprintf("https://gitee.com/%v/%v/pulls/%v", owner, repo, num)
+			st = msg.createdPR
+			url = s.provider().PullRequestURL(owner, repo, num)
 		}
-		status = append(status, syncStatus{Name: branch, Status: st, PR: url})
+		status = append(status, syncStatus{Name: branch, Status: st, PR: url, Number: num})
 	}
 	return status, nil
 }
@@ -367,9 +392,8 @@ func (s *Server) overwrite() bool {
 	panic("implement me")
 }
 
-func (s *Server) sync(owner string, repo string, pr gitee.PullRequest, user string, url string, command string) error {
-	number := pr.Number
-
+func (s *Server) sync(owner string, repo string, pr gitee.PullRequest, user string, url string, command string,
+	lang Language) error {
 	opt, err := parseSyncCommand(command)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -377,6 +401,16 @@ func (s *Server) sync(owner string, repo string, pr gitee.PullRequest, user stri
 		}).Errorln("Parse /sync command failed:", err)
 		return err
 	}
+	return s.syncBranches(owner, repo, pr, user, url, command, opt, lang)
+}
+
+// syncBranches synchronizes pr to the branches of opt, and replies the result
+// to the /sync command in lang, command is empty if all branches are of the
+// rules.
+func (s *Server) syncBranches(owner string, repo string, pr gitee.PullRequest, user string, url string, command string,
+	opt *SyncCmdOption, lang Language) error {
+	number := pr.Number
+	msg := messages(lang)
 
 	issues, err := s.GiteeClient.ListPullRequestIssues(owner, repo, number)
 	if err != nil {
@@ -400,43 +434,46 @@ func (s *Server) sync(owner string, repo string, pr gitee.PullRequest, user stri
 		return err
 	}
 	branchSet := make(map[string]bool)
+	var names []string
 	for _, b := range branches {
 		branchSet[b.Name] = true
+		names = append(names, b.Name)
 	}
+	opt.branches = expandBranches(opt.branches, names, s.aliases(owner, repo))
 
-	title := fmt.Sprintf("[sync] PR-%v: %v", number, pr.Title)
+	// pull requests created by synchronization continue the chain, which must
+	// not return to the branches on it
+	chain := append(prChain(pr), chainHop{Branch: pr.Base.Ref, Number: number})
+	var looped []syncStatus
+	opt.branches, looped = excludeLoops(opt.branches, chain, msg)
+
+	title := fmt.Sprintf("[sync] PR-%v: %v", number, syncTitleRegex.ReplaceAllString(pr.Title, ""))
 
 	var body string
 	var data interface{}
 	if owner == "openeuler" && repo == "kernel" {
-		data = struct {
-			PR   string
-			Body string
-		}{
+		data = syncKernelPRBodyData{
 			PR:   pr.HTMLURL,
-			Body: pr.Body,
+			Body: stripChain(pr.Body),
 		}
 
-		body, err = executeTemplate(syncPRBodyTmplKernel, data)
+		body, err = executeTemplate(s.template(owner, repo, pr.Base.Ref, lang, syncKernelPRBodyTmpl), data)
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
-				"tmpl": syncPRBodyTmplKernel,
+				"tmpl": syncKernelPRBodyTmpl,
 				"data": data,
 			}).Errorln("Execute template failed:", err)
 			return err
 		}
 	} else {
-		data = struct {
-			PR      string
-			Issues  []gitee.Issue
-			Commits []gitee.PullRequestCommit
-		}{
+		data = syncPRBodyData{
 			PR:      pr.HTMLURL,
+			Body:    stripChain(pr.Body),
 			Issues:  issues,
 			Commits: commits,
 		}
 
-		body, err = executeTemplate(syncPRBodyTmpl, data)
+		body, err = executeTemplate(s.template(owner, repo, pr.Base.Ref, lang, syncPRBodyTmpl), data)
 		if err != nil {
 			logrus.WithFields(logrus.Fields{
 				"tmpl": syncPRBodyTmpl,
@@ -446,29 +483,27 @@ func (s *Server) sync(owner string, repo string, pr gitee.PullRequest, user stri
 		}
 	}
 
+	body += s.chainRecord(owner, repo, chain, msg)
+
 	var status []syncStatus
 	switch opt.strategy {
 	case Pick:
 		firstSha := commits[len(commits)-1].Sha
 		lastSha := commits[0].Sha
-		status, _ = s.pick(owner, repo, opt, branchSet, pr, title, body, firstSha, lastSha)
+		status, _ = s.pick(owner, repo, opt, branchSet, pr, title, body, firstSha, lastSha, msg)
 	case Merge:
-		status, _ = s.merge(owner, repo, opt, branchSet, pr, title, body)
+		status, _ = s.merge(owner, repo, opt, branchSet, pr, title, body, msg)
 	case Overwrite:
 		s.overwrite()
 	default:
 	}
 
-	comment, err := executeTemplate(syncResultTmpl, struct {
-		URL        string
-		User       string
-		Command    string
-		SyncStatus []syncStatus
-	}{
+	comment, err := executeTemplate(s.template(owner, repo, pr.Base.Ref, lang, syncResultTmpl), syncResultData{
 		URL:        url,
 		User:       user,
 		Command:    strings.TrimSpace(command),
-		SyncStatus: status,
+		Rules:      opt.rules,
+		SyncStatus: append(status, looped...),
 	})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -477,6 +512,8 @@ func (s *Server) sync(owner string, repo string, pr gitee.PullRequest, user stri
 		}).Errorln("Execute template failed:", err)
 		return err
 	}
+	// the created pull requests are looked up by /sync-status
+	comment += resultRecord(status)
 
 	err = s.GiteeClient.CreateComment(owner, repo, number, comment)
 	if err != nil {
@@ -572,11 +609,12 @@ func (s *Server) HandlePullRequestEvent(e gitee.PullRequestEvent) {
 			s.OpenPullRequest(e)
 		}
 	case gitee.ActionMerge:
-		if util.MatchTitle(title) {
-			logger.Infoln("Merge Pull Request which created by sync-bot, ignore it.")
-		} else if util.MatchSyncBranch(targetBranch) {
+		if util.MatchSyncBranch(targetBranch) {
 			logger.Infoln("Merge Pull Request to sync branch, ignore it.")
 		} else {
+			if util.MatchTitle(title) {
+				logger.Infoln("Merge Pull Request which created by sync-bot, continue the sync chain.")
+			}
 			s.MergePullRequest(e)
 		}
 	case gitee.ActionUpdate:
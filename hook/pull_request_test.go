@@ -0,0 +1,194 @@
+package hook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"sync-bot/git/gogit"
+	"sync-bot/gitee"
+	"sync-bot/internal/gittest"
+)
+
+// fakeClient is an in-memory gitee.Client.
+type fakeClient struct {
+	branches []gitee.Branch
+	files    map[string]string
+	// listErrors are returned by ListFiles for the refs
+	listErrors   map[string]error
+	comments     []gitee.Comment
+	commits      []gitee.PullRequestCommit
+	pullRequests []gitee.PullRequest
+	changes      []gitee.PullRequestChange
+	// permissions of users, all users are admins if it is nil
+	permissions map[string]string
+	// commentLists counts the calls of ListPullRequestComments
+	commentLists int
+}
+
+func (f *fakeClient) GetPullRequests(owner, repo string) ([]gitee.PullRequest, error) {
+	return f.pullRequests, nil
+}
+
+func (f *fakeClient) GetPullRequest(owner, repo string, number int) (*gitee.PullRequest, error) {
+	for i := range f.pullRequests {
+		if f.pullRequests[i].Number == number {
+			return &f.pullRequests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("pull request %d not found", number)
+}
+
+func (f *fakeClient) GetPullRequestChanges(owner, repo string, number int) ([]gitee.PullRequestChange, error) {
+	return f.changes, nil
+}
+
+func (f *fakeClient) GetPullRequestPatch(owner, repo string, number int) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *fakeClient) CreatePullRequest(owner, repo, title, body, head, base string, pruneSourceBranch bool) (int, error) {
+	number := len(f.pullRequests) + 100
+	f.pullRequests = append(f.pullRequests, gitee.PullRequest{
+		Number: number,
+		Title:  title,
+		Body:   body,
+		Head:   gitee.PullRequestBranch{Ref: head},
+		Base:   gitee.PullRequestBranch{Ref: base},
+		State:  gitee.StateOpen,
+	})
+	return number, nil
+}
+
+func (f *fakeClient) ListPullRequestComments(owner, repo string, number int) ([]gitee.Comment, error) {
+	f.commentLists++
+	return f.comments, nil
+}
+
+func (f *fakeClient) ClosePullRequest(owner, repo string, number int) error {
+	return nil
+}
+
+func (f *fakeClient) ListPullRequestCommits(owner, repo string, number int) ([]gitee.PullRequestCommit, error) {
+	return f.commits, nil
+}
+
+func (f *fakeClient) ListPullRequestIssues(owner, repo string, number int) ([]gitee.Issue, error) {
+	return nil, nil
+}
+
+func (f *fakeClient) CreateComment(owner, repo string, number int, comment string) error {
+	f.comments = append(f.comments, gitee.Comment{Body: comment, User: gitee.User{Username: "bot"}})
+	return nil
+}
+
+func (f *fakeClient) GetBranches(owner, repo string, onlyProtected bool) ([]gitee.Branch, error) {
+	return f.branches, nil
+}
+
+func (f *fakeClient) GetBranch(owner, repo, branch string) (gitee.Branch, error) {
+	for _, b := range f.branches {
+		if b.Name == branch {
+			return b, nil
+		}
+	}
+	return gitee.Branch{}, fmt.Errorf("branch %s not found", branch)
+}
+
+func (f *fakeClient) CreateBranch(owner, repo, branch, ref string) error {
+	f.branches = append(f.branches, gitee.Branch{Name: branch})
+	return nil
+}
+
+func (f *fakeClient) GetTextFile(owner, repo, filepath, ref string) (string, error) {
+	content, ok := f.files[ref+":"+filepath]
+	if !ok {
+		return "", fmt.Errorf("file %s not found in %s", filepath, ref)
+	}
+	return content, nil
+}
+
+func (f *fakeClient) ListFiles(owner, repo, ref string) ([]string, error) {
+	if err := f.listErrors[ref]; err != nil {
+		return nil, err
+	}
+	var files []string
+	for k := range f.files {
+		if strings.HasPrefix(k, ref+":") {
+			files = append(files, strings.TrimPrefix(k, ref+":"))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (f *fakeClient) GetPermission(owner, repo, username string) (string, error) {
+	if f.permissions == nil {
+		return "admin", nil
+	}
+	p, ok := f.permissions[username]
+	if !ok {
+		return "", fmt.Errorf("user %s is not a collaborator", username)
+	}
+	return p, nil
+}
+
+func TestPick(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	remote.Branch("branch1")
+	remote.Push("refs/heads/master:refs/heads/master", "refs/heads/branch1:refs/heads/branch1")
+	first := remote.Commit("a", "a\n")
+	last := remote.Commit("b", "b\n")
+	remote.Push("refs/heads/master:refs/pull/1/head")
+
+	client := &fakeClient{}
+	s := &Server{GitClient: gogit.NewClient("file://" + root), GiteeClient: client}
+	opt := &SyncCmdOption{strategy: Pick, branches: []string{"branch1", "branch2"}}
+	pr := gitee.PullRequest{Number: 1, Head: gitee.PullRequestBranch{Ref: "master"}}
+	status, err := s.pick("owner", "repo", opt, map[string]bool{"branch1": true}, pr, "title", "body", first, last, catalogs[English])
+	if err != nil {
+		t.Fatalf("pick() error = %v", err)
+	}
+
+	want := []syncStatus{
+		{Name: "branch1", Status: catalogs[English].createdPR, PR: "https://gitee.com/owner/repo/pulls/100", Number: 100},
+		{Name: "branch2", Status: catalogs[English].branchNonExist, NotFound: true},
+	}
+	if len(status) != len(want) || status[0] != want[0] || status[1] != want[1] {
+		t.Errorf("pick() = %v, want %v", status, want)
+	}
+	if _, err := remote.Resolve("refs/heads/sync-pr1-master-to-branch1"); err != nil {
+		t.Errorf("sync branch not pushed: %v", err)
+	}
+	if len(client.pullRequests) != 1 || client.pullRequests[0].Base.Ref != "branch1" {
+		t.Errorf("created pull requests = %v", client.pullRequests)
+	}
+}
+
+func TestAutoMerge(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	remote.Push("refs/heads/master:refs/heads/master", "refs/heads/master:refs/heads/sync-pr1-master-to-branch1")
+	last := remote.Commit("a", "a\n")
+	remote.Push("refs/heads/master:refs/pull/2/head")
+
+	s := &Server{GitClient: gogit.NewClient("file://" + root), GiteeClient: &fakeClient{}}
+	e := gitee.PullRequestEvent{
+		Repository: gitee.Repository{Namespace: "owner", Path: "repo"},
+		PullRequest: gitee.PullRequest{
+			Number:    2,
+			Mergeable: true,
+			Base:      gitee.PullRequestBranch{Ref: "sync-pr1-master-to-branch1"},
+		},
+	}
+	s.AutoMerge(e)
+
+	got, err := remote.Resolve("refs/heads/sync-pr1-master-to-branch1")
+	if err != nil || got != last {
+		t.Errorf("sync branch = %v (%v), want %v", got, err, last)
+	}
+}
@@ -0,0 +1,237 @@
+package hook
+
+import (
+	"fmt"
+	"strings"
+
+	"sync-bot/gitee"
+	"sync-bot/util"
+
+	"github.com/sirupsen/logrus"
+)
+
+// permission is the permission required to run a command.
+type permission int
+
+// permission enum
+const (
+	// anyone may run the command
+	anyone permission = iota
+	// only the users allowed by authorized may run the command
+	collaborator
+)
+
+// command is a comment command, its description is in the catalogs.
+type command struct {
+	name  string
+	usage string
+	// parse returns the arguments of the command in line, ok is false if line
+	// is not the command.
+	parse      func(line string) (args []string, ok bool)
+	permission permission
+	// states are the states of pull requests the command is handled on, all
+	// states if empty.
+	states []gitee.State
+	// handle runs the command with the language of the replies.
+	handle func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string)
+}
+
+// commands are the comment commands in the order of /sync-help.
+var commands []command
+
+func init() {
+	// initialized here as the handlers refer to commands
+	commands = []command{
+		{
+			name:  "/sync-check",
+			usage: "/sync-check",
+			parse: matchOnly(util.MatchSyncCheck),
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				s.greeting(e.Repository.Namespace, e.Repository.Path, e.PullRequest.Number, e.PullRequest.Base.Ref, lang)
+			},
+		},
+		{
+			name:  "/sync",
+			usage: "/sync <branch>...",
+			parse: func(line string) ([]string, bool) {
+				if !util.MatchSync(line) {
+					return nil, false
+				}
+				return strings.Fields(line)[1:], true
+			},
+			permission: collaborator,
+			states:     []gitee.State{gitee.StateOpen, gitee.StateMerged},
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				if e.PullRequest.State == gitee.StateOpen {
+					logrus.Infoln("Pull request is open, just replay sync.")
+					s.replySync(e, lang)
+					return
+				}
+				logrus.Infoln("Pull request is merge, perform sync operation.")
+				_ = s.sync(e.Repository.Namespace, e.Repository.Path, e.PullRequest, e.Comment.User.Username,
+					e.Comment.HTMLURL, e.Comment.Body, lang)
+			},
+		},
+		{
+			name:       "/sync-cancel",
+			usage:      "/sync-cancel [<branch>...]",
+			parse:      branchArgs(util.ParseSyncCancel),
+			permission: collaborator,
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				s.replySyncCancel(e, lang, args)
+			},
+		},
+		{
+			name:  "/sync-status",
+			usage: "/sync-status",
+			parse: matchOnly(util.MatchSyncStatus),
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				s.replySyncStatus(e, lang)
+			},
+		},
+		{
+			name:       "/sync-retry",
+			usage:      "/sync-retry [<branch>...]",
+			parse:      branchArgs(util.ParseSyncRetry),
+			permission: collaborator,
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				s.syncRetry(e, lang, args)
+			},
+		},
+		{
+			name:  "/sync-lang",
+			usage: "/sync-lang <en|zh>",
+			parse: func(line string) ([]string, bool) {
+				if lang := util.ParseSyncLang(line); lang != "" {
+					return []string{lang}, true
+				}
+				return nil, false
+			},
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				s.replySyncLang(e.Repository.Namespace, e.Repository.Path, e.PullRequest.Number, lang, args[0])
+			},
+		},
+		{
+			name:  "/sync-help",
+			usage: "/sync-help",
+			parse: matchOnly(util.MatchSyncHelp),
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				s.replySyncHelp(e, lang)
+			},
+		},
+		{
+			name:       "/close",
+			usage:      "/close",
+			parse:      matchOnly(util.MatchClose),
+			permission: collaborator,
+			handle: func(s *Server, e gitee.CommentPullRequestEvent, lang Language, args []string) {
+				if !util.MatchTitle(e.PullRequest.Title) {
+					logrus.Infoln("Pull request not created by sync-bot, ignoring /close.")
+					return
+				}
+				s.ClosePullRequest(e.Repository.Namespace, e.Repository.Path, e.PullRequest)
+			},
+		},
+	}
+}
+
+// matchOnly returns the parser of commands without arguments.
+func matchOnly(match func(string) bool) func(string) ([]string, bool) {
+	return func(line string) ([]string, bool) {
+		return nil, match(line)
+	}
+}
+
+// branchArgs returns the parser of commands with optional branches, parse
+// returns nil if line is not the command.
+func branchArgs(parse func(string) []string) func(string) ([]string, bool) {
+	return func(line string) ([]string, bool) {
+		branches := parse(line)
+		return branches, branches != nil
+	}
+}
+
+// lookupCommand returns the command in line and its arguments, or nil if line
+// is not a command.
+func lookupCommand(line string) (*command, []string) {
+	for i := range commands {
+		if args, ok := commands[i].parse(line); ok {
+			return &commands[i], args
+		}
+	}
+	return nil, nil
+}
+
+// commandLines splits the comments of several lines into comments of single
+// lines, so that each command of a comment is replayed.
+func commandLines(comments []gitee.Comment) []gitee.Comment {
+	result := make([]gitee.Comment, 0, len(comments))
+	for _, c := range comments {
+		if !strings.Contains(c.Body, "\n") {
+			result = append(result, c)
+			continue
+		}
+		for _, line := range strings.Split(c.Body, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				l := c
+				l.Body = line
+				result = append(result, l)
+			}
+		}
+	}
+	return result
+}
+
+// isCommand returns whether line is a command, or starts with /sync like a
+// mistyped command.
+func isCommand(line string) bool {
+	c, _ := lookupCommand(line)
+	return c != nil || util.ParseSyncPrefix(line) != ""
+}
+
+// runCommand runs the command in line of the comment of e, replying in lang.
+func (s *Server) runCommand(e gitee.CommentPullRequestEvent, lang Language, line string) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	number := e.PullRequest.Number
+	e.Comment.Body = line
+	logger := logrus.WithFields(logrus.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+		"line":   line,
+	})
+
+	c, args := lookupCommand(line)
+	if c == nil {
+		logger.Infoln("Receive unknown command")
+		s.replyUnknownCommand(e, lang)
+		return
+	}
+	logger = logger.WithField("command", c.name)
+	logger.Infof("Receive %s command", c.name)
+
+	if len(c.states) > 0 && !containsState(c.states, e.PullRequest.State) {
+		logger.Infoln("Ignoring unhandled pull request state.")
+		return
+	}
+	user := e.Comment.User.Username
+	if c.permission == collaborator && !s.authorized(owner, repo, e.PullRequest.Base.Ref, user) {
+		logger.Infoln("User is not authorized to run the command")
+		reply := fmt.Sprintf(messages(lang).notAuthorized, user, ownersFile)
+		if err := s.GiteeClient.CreateComment(owner, repo, number, reply); err != nil {
+			logger.WithField("reply", reply).Errorln("Create comment failed:", err)
+		}
+		return
+	}
+	c.handle(s, e, lang, args)
+}
+
+func containsState(states []gitee.State, state gitee.State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/gitee"
+)
+
+func TestLookupCommand(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantName string
+		wantArgs []string
+	}{
+		{"/sync-check", "/sync-check", nil},
+		{"/sync branch1 branch2", "/sync", []string{"branch1", "branch2"}},
+		{"/sync-cancel", "/sync-cancel", []string{}},
+		{"/sync-cancel branch1", "/sync-cancel", []string{"branch1"}},
+		{"/sync-retry branch1", "/sync-retry", []string{"branch1"}},
+		{"/sync-lang en", "/sync-lang", []string{"en"}},
+		{"/close", "/close", nil},
+		{"/sync", "", nil},
+		{"lgtm", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			c, args := lookupCommand(tt.line)
+			name := ""
+			if c != nil {
+				name = c.name
+			}
+			if name != tt.wantName || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("lookupCommand() = %q, %q, want %q, %q", name, args, tt.wantName, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCommandsDescribed(t *testing.T) {
+	for lang, c := range catalogs {
+		for _, cmd := range commands {
+			if c.commandHelp[cmd.name] == "" {
+				t.Errorf("%s has no description in %s", cmd.name, lang)
+			}
+		}
+	}
+}
+
+func TestCommandLines(t *testing.T) {
+	comments := []gitee.Comment{
+		{ID: 1, Body: "lgtm"},
+		{ID: 2, Body: "/sync branch1\r\n\n  /sync-cancel branch2  ", User: gitee.User{Username: "alice"}},
+	}
+	want := []gitee.Comment{
+		{ID: 1, Body: "lgtm"},
+		{ID: 2, Body: "/sync branch1", User: gitee.User{Username: "alice"}},
+		{ID: 2, Body: "/sync-cancel branch2", User: gitee.User{Username: "alice"}},
+	}
+	if got := commandLines(comments); !reflect.DeepEqual(got, want) {
+		t.Errorf("commandLines() = %v, want %v", got, want)
+	}
+}
+
+func TestNotePullRequestCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		user    string
+		state   gitee.State
+		want    []string
+	}{
+		{
+			name:    "several commands",
+			comment: "/sync branch1\n/sync-status",
+			user:    "alice",
+			state:   gitee.StateOpen,
+			want:    []string{"|branch1|sync operation will be performed|", "it will be synchronized to: `branch1`"},
+		},
+		{
+			name:    "unauthorized line",
+			comment: "/sync-status\n/sync branch1",
+			user:    "bob",
+			state:   gitee.StateOpen,
+			want:    []string{"There is no synchronization to perform", "@bob is not authorized"},
+		},
+		{
+			name:    "unhandled state",
+			comment: "/sync branch1",
+			user:    "alice",
+			state:   gitee.StateClosed,
+		},
+		{
+			name:    "other lines",
+			comment: "thanks!\n/sync-chek",
+			user:    "alice",
+			state:   gitee.StateOpen,
+			want:    []string{"did you mean `/sync-check`?"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := gitee.Comment{ID: 1, Body: tt.comment, User: gitee.User{Username: tt.user}}
+			client := &fakeClient{
+				branches:    []gitee.Branch{{Name: "master"}, {Name: "branch1"}},
+				comments:    []gitee.Comment{comment},
+				permissions: map[string]string{"alice": "write", "bob": "read"},
+			}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GiteeClient: client, Config: cfg}
+			s.NotePullRequest(gitee.CommentPullRequestEvent{
+				Comment: comment,
+				PullRequest: gitee.PullRequest{
+					Number: 1,
+					State:  tt.state,
+					Base:   gitee.PullRequestBranch{Ref: "master"},
+				},
+				Repository: gitee.Repository{Namespace: "owner", Path: "repo"},
+			})
+			replies := client.comments[1:]
+			if len(replies) != len(tt.want) {
+				t.Fatalf("replies = %v, want %d", replies, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(replies[i].Body, want) {
+					t.Errorf("reply %d = %q, want containing %q", i, replies[i].Body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNotePullRequestLanguageOnce(t *testing.T) {
+	comment := gitee.Comment{ID: 1, Body: "/sync-lang en\n/sync-help\n/sync-chek\n/sync-lang fr"}
+	client := &fakeClient{comments: []gitee.Comment{comment}}
+	s := &Server{GiteeClient: client}
+	s.NotePullRequest(gitee.CommentPullRequestEvent{
+		Comment:     comment,
+		PullRequest: gitee.PullRequest{Number: 1, State: gitee.StateOpen},
+		Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+	})
+	if len(client.comments) != 5 {
+		t.Fatalf("comments = %v, want 4 replies", client.comments)
+	}
+	if want := `Unknown language "fr"`; !strings.Contains(client.comments[4].Body, want) {
+		t.Errorf("reply = %q, want containing %q", client.comments[4].Body, want)
+	}
+	if client.commentLists != 1 {
+		t.Errorf("comments listed %d times, want once for the language", client.commentLists)
+	}
+}
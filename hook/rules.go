@@ -0,0 +1,174 @@
+package hook
+
+import (
+	"path"
+	"strings"
+
+	"sync-bot/gitee"
+	"sync-bot/util"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// rulesFile is the file on the default branch of repositories declaring the
+// branches pull requests are synchronized to once merged, without /sync.
+const rulesFile = ".sync-bot.yaml"
+
+// defaultOptOutLabel is the label skipping the rules if not configured.
+const defaultOptOutLabel = "sync-bot/skip"
+
+// syncRules is the content of rulesFile, like
+//
+//	rules:
+//	- base: master
+//	  paths: ["*.spec", "*.patch"]
+//	  sync: [openEuler-24.03-LTS-Next]
+type syncRules struct {
+	// OptOutLabel skips the rules for the pull requests with the label,
+	// default is defaultOptOutLabel.
+	OptOutLabel string     `yaml:"opt_out_label"`
+	Rules       []syncRule `yaml:"rules"`
+}
+
+// syncRule synchronizes the pull requests matching all of its conditions to
+// the branches of Sync.
+type syncRule struct {
+	// Base is the pattern of the base branches, all branches if empty.
+	Base string `yaml:"base"`
+	// Paths are the patterns of the changed files, like "*.spec" or
+	// "patches/*", patterns without "/" match the base names. Any change
+	// matches if it is empty.
+	Paths []string `yaml:"paths"`
+	// Labels are the labels required on the pull requests.
+	Labels []string `yaml:"labels"`
+	// Sync are the branches, patterns or aliases to synchronize to.
+	Sync []string `yaml:"sync"`
+	// Chain applies the rule to the pull requests created by synchronization
+	// as well, which continues the synchronization along a chain of branches.
+	Chain bool `yaml:"chain"`
+}
+
+// match returns whether the pull request to base with labels changing files
+// matches r, files are listed only if r has paths.
+func (r syncRule) match(base string, labels []string, files func() []string) bool {
+	if r.Base != "" {
+		if ok, _ := path.Match(r.Base, base); !ok {
+			return false
+		}
+	}
+	for _, l := range r.Labels {
+		if !contains(labels, l) {
+			return false
+		}
+	}
+	if len(r.Paths) == 0 {
+		return true
+	}
+	for _, f := range files() {
+		for _, p := range r.Paths {
+			name := f
+			if !strings.Contains(p, "/") {
+				name = path.Base(f)
+			}
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rules returns the rules of owner/repo on ref, nil if there is no rules file.
+func (s *Server) rules(owner, repo, ref string) *syncRules {
+	logger := logrus.WithFields(logrus.Fields{
+		"owner": owner,
+		"repo":  repo,
+		"ref":   ref,
+	})
+	content, err := s.GiteeClient.GetTextFile(owner, repo, rulesFile, ref)
+	if err != nil {
+		logger.Infoln("No sync rules:", err)
+		return nil
+	}
+	var r syncRules
+	if err := yaml.Unmarshal([]byte(content), &r); err != nil {
+		logger.Warnln("Parse sync rules failed:", err)
+		return nil
+	}
+	if r.OptOutLabel == "" {
+		r.OptOutLabel = defaultOptOutLabel
+	}
+	return &r
+}
+
+// ruleBranches returns the branches the merged pull request of e is
+// synchronized to by the rules of the repository.
+func (s *Server) ruleBranches(e gitee.PullRequestEvent) []string {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	pr := e.PullRequest
+	logger := logrus.WithFields(logrus.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": pr.Number,
+	})
+	ref := e.Repository.DefaultBranch
+	if ref == "" {
+		ref = "master"
+	}
+	rules := s.rules(owner, repo, ref)
+	if rules == nil {
+		return nil
+	}
+
+	var labels []string
+	for _, l := range pr.Labels {
+		labels = append(labels, l.Name)
+	}
+	if contains(labels, rules.OptOutLabel) {
+		logger.Infof("Skip sync rules for label %s", rules.OptOutLabel)
+		return nil
+	}
+	var files []string
+	listed := false
+	changedFiles := func() []string {
+		if !listed {
+			listed = true
+			changes, err := s.GiteeClient.GetPullRequestChanges(owner, repo, pr.Number)
+			if err != nil {
+				logger.Errorln("Get pull request changes failed:", err)
+			}
+			for _, c := range changes {
+				files = append(files, c.Filename)
+				if c.PreviousFilename != "" {
+					files = append(files, c.PreviousFilename)
+				}
+			}
+		}
+		return files
+	}
+
+	// only chain rules apply to the pull requests created by synchronization
+	synced := util.MatchTitle(pr.Title)
+	var targets []string
+	for _, r := range rules.Rules {
+		if synced && !r.Chain {
+			continue
+		}
+		if r.match(pr.Base.Ref, labels, changedFiles) {
+			targets = append(targets, r.Sync...)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	var branches []string
+	for _, b := range s.expander(owner, repo)(targets) {
+		if b != pr.Base.Ref {
+			branches = append(branches, b)
+		}
+	}
+	logger.WithField("branches", branches).Infoln("Match sync rules")
+	return branches
+}
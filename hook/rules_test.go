@@ -0,0 +1,188 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/git/gogit"
+	"sync-bot/gitee"
+	"sync-bot/internal/gittest"
+)
+
+func TestSyncRuleMatch(t *testing.T) {
+	files := func() []string { return []string{"foo.spec", "patches/0001-fix.patch"} }
+	tests := []struct {
+		name   string
+		rule   syncRule
+		base   string
+		labels []string
+		want   bool
+	}{
+		{"any", syncRule{}, "master", nil, true},
+		{"base", syncRule{Base: "master"}, "master", nil, true},
+		{"base pattern", syncRule{Base: "openEuler-*"}, "master", nil, false},
+		{"labels", syncRule{Labels: []string{"lts", "cve"}}, "master", []string{"cve", "lts"}, true},
+		{"missing label", syncRule{Labels: []string{"lts", "cve"}}, "master", []string{"cve"}, false},
+		{"base name", syncRule{Paths: []string{"*.patch"}}, "master", nil, true},
+		{"path", syncRule{Paths: []string{"patches/*"}}, "master", nil, true},
+		{"no path", syncRule{Paths: []string{"*.c", "src/*"}}, "master", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.match(tt.base, tt.labels, files); got != tt.want {
+				t.Errorf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleBranches(t *testing.T) {
+	rules := `
+rules:
+- base: master
+  sync: [branch1]
+- paths: ["*.spec"]
+  sync: ["release-*"]
+- labels: [lts]
+  sync: [branch2, master]
+- base: master
+  sync: [release-1]
+  chain: true
+`
+	tests := []struct {
+		name    string
+		rules   string
+		title   string
+		labels  []string
+		changes []string
+		want    []string
+	}{
+		{"no rules", "", "fix", nil, nil, nil},
+		{"base", rules, "fix", nil, []string{"README"}, []string{"branch1", "release-1"}},
+		{"paths", rules, "fix", nil, []string{"foo.spec"}, []string{"branch1", "release-1", "release-2"}},
+		{"labels", rules, "fix", []string{"lts"}, nil, []string{"branch1", "branch2", "release-1"}},
+		{"opt out", rules, "fix", []string{"lts", "sync-bot/skip"}, []string{"foo.spec"}, nil},
+		{"custom opt out", "opt_out_label: no-sync\n" + rules, "fix", []string{"sync-bot/skip"}, nil,
+			[]string{"branch1", "release-1"}},
+		{"chain", rules, "[sync] PR-1: fix", []string{"lts"}, []string{"foo.spec"}, []string{"release-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{
+				branches: []gitee.Branch{{Name: "master"}, {Name: "branch1"}, {Name: "branch2"},
+					{Name: "release-1"}, {Name: "release-2"}},
+				files: map[string]string{},
+			}
+			if tt.rules != "" {
+				client.files["master:"+rulesFile] = tt.rules
+			}
+			for _, c := range tt.changes {
+				client.changes = append(client.changes, gitee.PullRequestChange{Filename: c})
+			}
+			pr := gitee.PullRequest{Number: 1, Title: tt.title, Base: gitee.PullRequestBranch{Ref: "master"}}
+			for _, l := range tt.labels {
+				pr.Labels = append(pr.Labels, gitee.Label{Name: l})
+			}
+			s := &Server{GiteeClient: client}
+			got := s.ruleBranches(gitee.PullRequestEvent{
+				PullRequest: pr,
+				Repository:  gitee.Repository{Namespace: "owner", Path: "repo", DefaultBranch: "master"},
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ruleBranches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePullRequestRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []gitee.Comment
+		want     []string
+		reply    []string
+	}{
+		{
+			name:     "rules",
+			comments: []gitee.Comment{{ID: 1, Body: "lgtm"}},
+			want:     []string{"branch1"},
+			reply:    []string{"@alice\n\nThe rules of .sync-bot.yaml synchronize the PR to: `branch1`\n"},
+		},
+		{
+			name:     "with /sync",
+			comments: []gitee.Comment{{ID: 1, Body: "/sync branch2", User: gitee.User{Username: "bob"}}},
+			want:     []string{"branch2", "branch1"},
+			reply: []string{"> /sync branch2\n\n@bob\n",
+				"The rules of .sync-bot.yaml synchronize the PR to: `branch1`\n"},
+		},
+		{
+			name: "/sync cancelled",
+			comments: []gitee.Comment{{ID: 1, Body: "/sync branch2", User: gitee.User{Username: "bob"}},
+				{ID: 2, Body: "/sync-cancel", User: gitee.User{Username: "bob"}}},
+			want:  []string{"branch1"},
+			reply: []string{"@alice\n\nThe rules of .sync-bot.yaml synchronize the PR to: `branch1`\n"},
+		},
+		{
+			name: "cancelled before /sync",
+			comments: []gitee.Comment{{ID: 1, Body: "/sync-cancel", User: gitee.User{Username: "bob"}},
+				{ID: 2, Body: "/sync branch2", User: gitee.User{Username: "bob"}}},
+			want:  []string{"branch2", "branch1"},
+			reply: []string{"> /sync branch2\n\n@bob\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			remote := gittest.NewFixture(t, root, "owner", "repo")
+			remote.Commit("README", "init\n")
+			remote.Branch("branch1")
+			remote.Branch("branch2")
+			remote.Push("refs/heads/master:refs/heads/master", "refs/heads/branch1:refs/heads/branch1",
+				"refs/heads/branch2:refs/heads/branch2")
+			sha := remote.Commit("a", "a\n")
+			remote.Push("refs/heads/master:refs/pull/1/head")
+
+			client := &fakeClient{
+				branches: []gitee.Branch{{Name: "master"}, {Name: "branch1"}, {Name: "branch2"}},
+				files:    map[string]string{"master:" + rulesFile: "rules:\n- base: master\n  sync: [branch1]\n"},
+				commits:  []gitee.PullRequestCommit{{Sha: sha}},
+				comments: tt.comments,
+			}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GitClient: gogit.NewClient("file://" + root), GiteeClient: client, Config: cfg}
+			s.MergePullRequest(gitee.PullRequestEvent{
+				PullRequest: gitee.PullRequest{
+					Number:  1,
+					State:   gitee.StateMerged,
+					HTMLURL: "https://gitee.com/owner/repo/pulls/1",
+					User:    gitee.User{Username: "alice"},
+					Head:    gitee.PullRequestBranch{Ref: "master"},
+					Base:    gitee.PullRequestBranch{Ref: "master"},
+				},
+				Repository: gitee.Repository{Namespace: "owner", Path: "repo", DefaultBranch: "master"},
+			})
+
+			var got []string
+			for _, pr := range client.pullRequests {
+				got = append(got, pr.Base.Ref)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("created pull requests to %v, want %v", got, tt.want)
+			}
+			if len(tt.want) == 0 {
+				return
+			}
+			reply := client.comments[len(client.comments)-1].Body
+			if strings.Contains(reply, rulesFile+")") {
+				t.Errorf("reply = %q, want no /sync command of the rules", reply)
+			}
+			for _, want := range tt.reply {
+				if !strings.Contains(reply, want) {
+					t.Errorf("reply = %q, want containing %q", reply, want)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,6 @@
 package hook
 
 import (
-	"crypto/hmac"
-	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -10,109 +8,98 @@ import (
 	"github.com/emicklei/go-restful/v3"
 	"github.com/sirupsen/logrus"
 
+	"sync-bot/config"
 	"sync-bot/git"
 	"sync-bot/gitee"
 )
 
+var errUnauthorized = errors.New("401: Not Authorized")
+
 type Server struct {
 	// Client for git operation
-	GitClient *git.Client
-	// Client for access Gitee OpenAPI
+	GitClient git.Backend
+	// Client for access the OpenAPI of the provider
 	GiteeClient gitee.Client
 	// function to get Gitee webhook secret
 	Secret func() []byte
+	// function to get the token of the administration API
+	AdminToken func() []byte
+	// Provider of the repositories, Gitee if nil
+	Provider Provider
+	// Config of repositories, may be nil
+	Config *config.Config
+	// Templates override the built-in templates, may be nil
+	Templates Templates
+	// Bot is the user name the bot comments as, only the sync records in its
+	// comments are trusted
+	Bot string
+}
+
+func (s *Server) provider() Provider {
+	if s.Provider == nil {
+		return &GiteeProvider{Secret: s.Secret}
+	}
+	return s.Provider
 }
 
-func (s *Server) demuxEvent(eventType gitee.EventType, payload []byte, h http.Header) error {
-	switch eventType {
-	case gitee.MergeRequestHook:
-		var e gitee.PullRequestEvent
-		if err := json.Unmarshal(payload, &e); err != nil {
-			return err
-		}
-		go s.HandlePullRequestEvent(e)
-	case gitee.NoteHook:
-		var e gitee.CommentPullRequestEvent
-		if err := json.Unmarshal(payload, &e); err != nil {
-			return err
-		}
-		go s.HandleNoteEvent(e)
-	default:
-		logrus.Infoln("Ignoring unhandled event type:", eventType)
+func (s *Server) demuxEvent(event interface{}) {
+	switch e := event.(type) {
+	case *gitee.PullRequestEvent:
+		go s.HandlePullRequestEvent(*e)
+	case *gitee.CommentPullRequestEvent:
+		go s.HandleNoteEvent(*e)
 	}
-	return nil
 }
 
 func (s *Server) hook(req *restful.Request, resp *restful.Response) {
-	eventType, isPingEvent, payload, err := ValidateWebhook(req, resp)
+	// read and authorized by Server.auth
+	payload, _ := req.Attribute("payload").([]byte)
+	event, err := s.provider().ParseWebhook(req.Request.Header, payload)
 	if err != nil {
-		logrus.Errorln(err)
+		logrus.Errorln("Parse webhook:", err)
+		_ = resp.WriteErrorString(http.StatusBadRequest, "400 Bad Request: "+err.Error())
 		return
 	}
 
-	_, err = resp.Write([]byte(eventType + ": event received."))
+	_, err = resp.Write([]byte("event received."))
 	if err != nil {
 		logrus.Errorln("Response to webhook:", err)
 		return
 	}
+	s.demuxEvent(event)
+}
 
-	if isPingEvent {
-		logrus.Infoln("Receive the Ping Event:", eventType)
-		return
-	}
-
-	err = s.demuxEvent(eventType, payload, req.Request.Header)
+func (s *Server) auth(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	payload, err := ioutil.ReadAll(req.Request.Body)
+	_ = req.Request.Body.Close()
 	if err != nil {
-		logrus.Errorln("demuxEvent:", err)
+		_ = resp.WriteErrorString(http.StatusBadRequest, "400 Bad Request: "+err.Error())
+		return
 	}
-}
-
-func auth(secret func() []byte) func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
-	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
-		xGiteeToken := req.Request.Header.Get("X-Gitee-Token")
-		if !hmac.Equal([]byte(xGiteeToken), secret()) {
-			logrus.Errorln("Authorized failed from:", req.Request.RemoteAddr)
-			resp.AddHeader("WWW-Authenticate", "Basic realm=Protected Area")
-			_ = resp.WriteErrorString(401, "401: Not Authorized")
-			return
-		}
-		chain.ProcessFilter(req, resp)
+	if err := s.provider().Authorize(req.Request.Header, payload); err != nil {
+		logrus.Errorln("Authorized failed from:", req.Request.RemoteAddr, err)
+		resp.AddHeader("WWW-Authenticate", "Basic realm=Protected Area")
+		_ = resp.WriteErrorString(401, "401: Not Authorized")
+		return
 	}
+	req.SetAttribute("payload", payload)
+	chain.ProcessFilter(req, resp)
 }
 
+// WebService serves the webhook of the provider, at /hook for Gitee and
+// at /hook/<name> for other providers.
 func (s *Server) WebService() *restful.WebService {
-	ws := new(restful.WebService)
-	ws.Path("/").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
-	ws.Route(ws.POST("/hook").To(s.hook))
-	ws.Filter(auth(s.Secret))
-	return ws
-}
-
-func ValidateWebhook(req *restful.Request, resp *restful.Response) (gitee.EventType, bool, []byte, error) {
-	defer req.Request.Body.Close()
-	giteePing := req.Request.Header.Get("X-Gitee-Ping")
-	isPingEvent := giteePing == "true"
-	eventType := gitee.EventType(req.Request.Header.Get("X-Gitee-Event"))
-	if eventType == "" {
-		_ = resp.WriteErrorString(http.StatusBadRequest, "400 Bad Request: Missing X-GitHub-Event Header")
-		return "", isPingEvent, []byte{}, errors.New("400 Bad Request: Missing X-GitHub-Event Header")
-	}
-	//
-	switch eventType {
-	case gitee.PushHook:
-	case gitee.TagPushHook:
-	case gitee.IssueHook:
-	case gitee.MergeRequestHook:
-	case gitee.NoteHook:
-	default:
-		_, _ = resp.Write([]byte("invalid event type"))
-		return "", isPingEvent, []byte{}, errors.New("invalid event type")
+	if s.Bot == "" {
+		logrus.Errorf("The bot user of %s is empty, the sync records in comments are ignored.", s.provider().Name())
 	}
-
-	payload, err := ioutil.ReadAll(req.Request.Body)
-	if err != nil {
-		return eventType, isPingEvent, nil, err
+	ws := new(restful.WebService)
+	if p := s.provider(); p.Name() != "gitee" {
+		ws.Path("/hook/" + p.Name()).Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+		ws.Route(ws.POST("").To(s.hook))
+	} else {
+		ws.Path("/").Consumes(restful.MIME_JSON).Produces(restful.MIME_JSON)
+		ws.Route(ws.POST("/hook").To(s.hook))
 	}
-
-	return eventType, isPingEvent, payload, nil
+	ws.Filter(s.auth)
+	return ws
 }
@@ -0,0 +1,66 @@
+package hook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+)
+
+// fakeProvider accepts requests with the header X-Fake-Token: token.
+type fakeProvider struct {
+	GiteeProvider
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) Authorize(h http.Header, payload []byte) error {
+	if h.Get("X-Fake-Token") != "token" {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func (p *fakeProvider) ParseWebhook(h http.Header, payload []byte) (interface{}, error) {
+	return nil, nil
+}
+
+func TestWebService(t *testing.T) {
+	container := restful.NewContainer()
+	gitee := &Server{Secret: func() []byte { return []byte("secret") }}
+	fake := &Server{Provider: &fakeProvider{}}
+	container.Add(gitee.WebService())
+	container.Add(fake.WebService())
+
+	tests := []struct {
+		name   string
+		path   string
+		header map[string]string
+		want   int
+	}{
+		{"gitee", "/hook", map[string]string{"X-Gitee-Token": "secret", "X-Gitee-Event": "Push Hook"}, http.StatusOK},
+		{"gitee unauthorized", "/hook", map[string]string{"X-Gitee-Token": "wrong", "X-Gitee-Event": "Push Hook"}, http.StatusUnauthorized},
+		{"gitee missing event", "/hook", map[string]string{"X-Gitee-Token": "secret"}, http.StatusBadRequest},
+		{"fake", "/hook/fake", map[string]string{"X-Fake-Token": "token"}, http.StatusOK},
+		{"fake unauthorized", "/hook/fake", map[string]string{"X-Gitee-Token": "secret"}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", restful.MIME_JSON)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			resp := httptest.NewRecorder()
+			container.ServeHTTP(resp, req)
+			if resp.Code != tt.want {
+				t.Errorf("POST %s = %d, want %d: %s", tt.path, resp.Code, tt.want, resp.Body.String())
+			}
+		})
+	}
+}
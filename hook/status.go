@@ -0,0 +1,209 @@
+package hook
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sync-bot/gitee"
+
+	"github.com/sirupsen/logrus"
+)
+
+// like "<!-- sync-bot:result [{"branch":"branch1","number":12}] -->", which
+// is appended to the reply of /sync
+var resultRegex = regexp.MustCompile(`<!-- sync-bot:result (.*?) -->`)
+
+// states of syncRecord
+const (
+	recordCreated  = "created"
+	recordFailed   = "failed"
+	recordNotFound = "not_found"
+)
+
+// syncRecord records the pull request created to synchronize to a branch.
+type syncRecord struct {
+	Branch string `json:"branch"`
+	// Number is 0 if no pull request is created
+	Number int `json:"number,omitempty"`
+	// State is one of the states of syncRecord, empty in the records written
+	// before it, whose Number is 0 if the synchronization failed
+	State string `json:"state,omitempty"`
+}
+
+// failed returns whether the synchronization of r failed and can be retried.
+func (r syncRecord) failed() bool {
+	if r.State == "" {
+		return r.Number == 0
+	}
+	return r.State == recordFailed
+}
+
+// resultRecord returns the hidden record of the pull requests in status.
+func resultRecord(status []syncStatus) string {
+	records := make([]syncRecord, 0, len(status))
+	for _, st := range status {
+		r := syncRecord{Branch: st.Name, Number: st.Number, State: recordCreated}
+		switch {
+		case st.NotFound:
+			r.State = recordNotFound
+		case st.Number == 0:
+			r.State = recordFailed
+		}
+		records = append(records, r)
+	}
+	b, err := json.Marshal(records)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("\n<!-- sync-bot:result %s -->\n", b)
+}
+
+// syncResults collects the records of the replies of /sync in comments, the
+// latest record of a branch wins. Only the comments of bot are read, records
+// in the comments of other users are forged.
+func syncResults(comments []gitee.Comment, bot string) []syncRecord {
+	var results []syncRecord
+	index := make(map[string]int)
+	for _, c := range comments {
+		if bot == "" || c.User.Username != bot {
+			continue
+		}
+		for _, m := range resultRegex.FindAllStringSubmatch(c.Body, -1) {
+			var records []syncRecord
+			if err := json.Unmarshal([]byte(m[1]), &records); err != nil {
+				logrus.WithField("record", m[1]).Warnln("Parse sync record failed:", err)
+				continue
+			}
+			for _, r := range records {
+				if i, ok := index[r.Branch]; ok {
+					results[i] = r
+					continue
+				}
+				index[r.Branch] = len(results)
+				results = append(results, r)
+			}
+		}
+	}
+	return results
+}
+
+// replySyncStatus replies the branches to be synchronized, and the pull
+// requests created by the synchronization once the pull request is merged.
+func (s *Server) replySyncStatus(e gitee.CommentPullRequestEvent, lang Language) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	number := e.PullRequest.Number
+	logger := logrus.WithFields(logrus.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	})
+	msg := messages(lang)
+
+	comments, err := s.listComments(e)
+	if err != nil {
+		logger.Errorln("List PullRequest comments failed:", err)
+		return
+	}
+	data := syncStatusData{
+		URL:     e.Comment.HTMLURL,
+		Command: strings.TrimSpace(e.Comment.Body),
+		User:    e.Comment.User.Username,
+		Merged:  e.PullRequest.State == gitee.StateMerged,
+	}
+	if _, opt := pendingSync(comments, s.unionSync(owner, repo), s.expander(owner, repo)); opt != nil {
+		data.Pending = opt.branches
+	}
+	if data.Merged {
+		for _, r := range syncResults(comments, s.Bot) {
+			result := syncPRStatus{Branch: r.Branch, Number: r.Number, State: msg.notCreated}
+			if r.State == recordNotFound {
+				result.State = msg.branchNonExist
+			}
+			if r.Number > 0 {
+				result.PR = s.provider().PullRequestURL(owner, repo, r.Number)
+				pr, err := s.GiteeClient.GetPullRequest(owner, repo, r.Number)
+				if err != nil {
+					logger.Errorf("Get pull request %d failed: %v", r.Number, err)
+					result.State = msg.stateUnknown
+				} else {
+					result.State = string(pr.State)
+				}
+			}
+			data.Results = append(data.Results, result)
+		}
+	}
+
+	tmpl := s.template(owner, repo, e.PullRequest.Base.Ref, lang, syncStatusTmpl)
+	comment, err := executeTemplate(tmpl, data)
+	if err != nil {
+		logger.WithField("data", data).Errorln("Execute template failed:", err)
+		return
+	}
+	if err := s.GiteeClient.CreateComment(owner, repo, number, comment); err != nil {
+		logger.WithField("comment", comment).Errorln("Create comment failed:", err)
+	}
+}
+
+// syncRetry re-runs the synchronization to the branches whose last results
+// are failures, only the given branches are retried if any, which may be
+// patterns or aliases like /sync.
+func (s *Server) syncRetry(e gitee.CommentPullRequestEvent, lang Language, branches []string) {
+	owner := e.Repository.Namespace
+	repo := e.Repository.Path
+	number := e.PullRequest.Number
+	logger := logrus.WithFields(logrus.Fields{
+		"owner":  owner,
+		"repo":   repo,
+		"number": number,
+	})
+	msg := messages(lang)
+
+	reply := func(comment string) {
+		if err := s.GiteeClient.CreateComment(owner, repo, number, comment); err != nil {
+			logger.WithField("comment", comment).Errorln("Create comment failed:", err)
+		}
+	}
+	if e.PullRequest.State != gitee.StateMerged {
+		reply(msg.retryNotMerged)
+		return
+	}
+	comments, err := s.listComments(e)
+	if err != nil {
+		logger.Errorln("List PullRequest comments failed:", err)
+		return
+	}
+	var failed []string
+	for _, r := range syncResults(comments, s.Bot) {
+		if r.failed() {
+			failed = append(failed, r.Branch)
+		}
+	}
+	opt := &SyncCmdOption{strategy: Pick}
+	if _, pending := pendingSync(comments, s.unionSync(owner, repo), nil); pending != nil {
+		opt.strategy = pending.strategy
+	}
+	if len(branches) > 0 {
+		// patterns and aliases are matched against the failed branches
+		for _, b := range expandBranches(branches, failed, s.aliases(owner, repo)) {
+			if contains(failed, b) {
+				opt.branches = append(opt.branches, b)
+			}
+		}
+	} else {
+		opt.branches = failed
+	}
+	switch {
+	case len(failed) == 0:
+		reply(msg.nothingToRetry)
+		return
+	case len(opt.branches) == 0:
+		reply(fmt.Sprintf(msg.retryFailedOnly, strings.Join(failed, ", ")))
+		return
+	}
+	logger.WithField("branches", opt.branches).Infoln("Retry sync")
+	_ = s.syncBranches(owner, repo, e.PullRequest, e.Comment.User.Username, e.Comment.HTMLURL, e.Comment.Body, opt,
+		lang)
+}
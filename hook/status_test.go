@@ -0,0 +1,207 @@
+package hook
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sync-bot/config"
+	"sync-bot/git/gogit"
+	"sync-bot/gitee"
+	"sync-bot/internal/gittest"
+	"sync-bot/util"
+)
+
+func TestSyncResults(t *testing.T) {
+	comments := []gitee.Comment{
+		{Body: "/sync branch1 branch2"},
+		{Body: "result" + resultRecord([]syncStatus{{Name: "branch1", Number: 10}, {Name: "branch2"}}), User: gitee.User{Username: "bot"}},
+		{Body: "<!-- sync-bot:result not json -->", User: gitee.User{Username: "bot"}},
+		{Body: "result" + resultRecord([]syncStatus{{Name: "branch2", Number: 11}}), User: gitee.User{Username: "bot"}},
+		{Body: "forged" + resultRecord([]syncStatus{{Name: "branch1", Number: 1}}), User: gitee.User{Username: "mallory"}},
+	}
+	want := []syncRecord{{Branch: "branch1", Number: 10, State: recordCreated},
+		{Branch: "branch2", Number: 11, State: recordCreated}}
+	if got := syncResults(comments, "bot"); !reflect.DeepEqual(got, want) {
+		t.Errorf("syncResults() = %v, want %v", got, want)
+	}
+	if got := syncResults(comments, ""); got != nil {
+		t.Errorf("syncResults() = %v, want nil without the bot", got)
+	}
+}
+
+func TestSyncRecordFailed(t *testing.T) {
+	tests := []struct {
+		record syncRecord
+		want   bool
+	}{
+		{syncRecord{Branch: "master", Number: 1}, false},
+		{syncRecord{Branch: "master"}, true},
+		{syncRecord{Branch: "master", State: recordFailed}, true},
+		{syncRecord{Branch: "master", State: recordNotFound}, false},
+		{syncRecord{Branch: "master", Number: 1, State: recordCreated}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.record.failed(); got != tt.want {
+			t.Errorf("%+v.failed() = %v, want %v", tt.record, got, tt.want)
+		}
+	}
+}
+
+func TestReplySyncStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    gitee.State
+		comments []gitee.Comment
+		want     []string
+	}{
+		{
+			name:     "pending",
+			state:    gitee.StateOpen,
+			comments: []gitee.Comment{{ID: 1, Body: "/sync branch1 branch2"}, {ID: 2, Body: "/sync-cancel branch2"}},
+			want:     []string{"Once the current PR is merged, it will be synchronized to: `branch1`\n"},
+		},
+		{
+			name:  "nothing pending",
+			state: gitee.StateOpen,
+			want:  []string{"There is no synchronization to perform"},
+		},
+		{
+			name:  "merged",
+			state: gitee.StateMerged,
+			comments: []gitee.Comment{
+				{ID: 1, Body: "/sync branch1 branch2 branch3"},
+				{ID: 2, Body: resultRecord([]syncStatus{{Name: "branch1", Number: 100}, {Name: "branch2", Number: 101},
+					{Name: "branch3"}}), User: gitee.User{Username: "bot"}},
+			},
+			want: []string{
+				"|branch1|[#100](https://gitee.com/owner/repo/pulls/100)|merged|\n",
+				"|branch2|[#101](https://gitee.com/owner/repo/pulls/101)|unknown|\n",
+				"|branch3|-|not created|\n",
+			},
+		},
+		{
+			name:  "forged",
+			state: gitee.StateMerged,
+			comments: []gitee.Comment{
+				{ID: 1, Body: resultRecord([]syncStatus{{Name: "branch1", Number: 100}}),
+					User: gitee.User{Username: "mallory"}},
+			},
+			want: []string{"The current PR has not been synchronized to other branches."},
+		},
+		{
+			name:     "merged without synchronization",
+			state:    gitee.StateMerged,
+			comments: []gitee.Comment{{ID: 1, Body: "lgtm"}},
+			want:     []string{"The current PR has not been synchronized to other branches."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{
+				comments:     tt.comments,
+				pullRequests: []gitee.PullRequest{{Number: 100, State: gitee.StateMerged}},
+			}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GiteeClient: client, Config: cfg, Bot: "bot"}
+			s.replySyncStatus(gitee.CommentPullRequestEvent{
+				Comment:     gitee.Comment{ID: 3, Body: "/sync-status"},
+				PullRequest: gitee.PullRequest{Number: 1, State: tt.state},
+				Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+			}, English)
+			n := len(client.comments)
+			if n != len(tt.comments)+1 {
+				t.Fatalf("comments = %v, want a reply", client.comments)
+			}
+			got := client.comments[n-1].Body
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reply = %s, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyncRetryReply(t *testing.T) {
+	failed := []gitee.Comment{
+		{ID: 1, Body: "/sync branch1 branch2"},
+		{ID: 2, Body: resultRecord([]syncStatus{{Name: "branch1"}, {Name: "branch2", Number: 100}}),
+			User: gitee.User{Username: "bot"}},
+	}
+	tests := []struct {
+		name     string
+		state    gitee.State
+		comments []gitee.Comment
+		command  string
+		want     string
+	}{
+		{"not merged", gitee.StateOpen, failed, "/sync-retry",
+			"The current PR has not been merged, the synchronization will be performed once it is merged."},
+		{"nothing failed", gitee.StateMerged, failed[:1], "/sync-retry", "There is no failed synchronization to retry."},
+		{"not failed", gitee.StateMerged, failed, "/sync-retry branch2",
+			"Only failed synchronization can be retried, failed branches: branch1."},
+		{"not failed pattern", gitee.StateMerged, failed, "/sync-retry branch2*",
+			"Only failed synchronization can be retried, failed branches: branch1."},
+		{"not found", gitee.StateMerged, []gitee.Comment{
+			{ID: 1, Body: "/sync branch3"},
+			{ID: 2, Body: resultRecord([]syncStatus{{Name: "branch3", NotFound: true}}), User: gitee.User{Username: "bot"}},
+		}, "/sync-retry", "There is no failed synchronization to retry."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeClient{comments: append([]gitee.Comment(nil), tt.comments...)}
+			cfg := &config.Config{Repos: map[string]config.Repo{"owner": {Language: "en"}}}
+			s := &Server{GiteeClient: client, Config: cfg, Bot: "bot"}
+			s.syncRetry(gitee.CommentPullRequestEvent{
+				Comment:     gitee.Comment{ID: 3, Body: tt.command},
+				PullRequest: gitee.PullRequest{Number: 1, State: tt.state},
+				Repository:  gitee.Repository{Namespace: "owner", Path: "repo"},
+			}, English, util.ParseSyncRetry(tt.command))
+			if n := len(client.comments); n != len(tt.comments)+1 || client.comments[n-1].Body != tt.want {
+				t.Errorf("comments = %v, want %q", client.comments, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncRetry(t *testing.T) {
+	root := t.TempDir()
+	remote := gittest.NewFixture(t, root, "owner", "repo")
+	remote.Commit("README", "init\n")
+	remote.Branch("branch1")
+	remote.Push("refs/heads/master:refs/heads/master", "refs/heads/branch1:refs/heads/branch1")
+	first := remote.Commit("a", "a\n")
+	last := remote.Commit("b", "b\n")
+	remote.Push("refs/heads/master:refs/pull/1/head")
+
+	client := &fakeClient{
+		branches: []gitee.Branch{{Name: "master"}, {Name: "branch1"}, {Name: "branch2"}},
+		commits:  []gitee.PullRequestCommit{{Sha: last}, {Sha: first}},
+		comments: []gitee.Comment{
+			{ID: 1, Body: "/sync branch1 branch2"},
+			{ID: 2, Body: resultRecord([]syncStatus{{Name: "branch1"}, {Name: "branch2", Number: 50}}),
+				User: gitee.User{Username: "bot"}},
+		},
+	}
+	s := &Server{GitClient: gogit.NewClient("file://" + root), GiteeClient: client, Bot: "bot"}
+	s.syncRetry(gitee.CommentPullRequestEvent{
+		Comment: gitee.Comment{ID: 3, Body: "/sync-retry branch*"},
+		PullRequest: gitee.PullRequest{
+			Number: 1,
+			State:  gitee.StateMerged,
+			Head:   gitee.PullRequestBranch{Ref: "master"},
+			Base:   gitee.PullRequestBranch{Ref: "master"},
+		},
+		Repository: gitee.Repository{Namespace: "owner", Path: "repo"},
+	}, defaultLanguage, []string{"branch*"})
+
+	if len(client.pullRequests) != 1 || client.pullRequests[0].Base.Ref != "branch1" {
+		t.Fatalf("created pull requests = %v, want one to branch1", client.pullRequests)
+	}
+	want := []syncRecord{{Branch: "branch1", Number: 100, State: recordCreated},
+		{Branch: "branch2", Number: 50, State: recordCreated}}
+	if got := syncResults(client.comments, "bot"); !reflect.DeepEqual(got, want) {
+		t.Errorf("syncResults() = %v, want %v", got, want)
+	}
+}
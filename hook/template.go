@@ -2,16 +2,48 @@ package hook
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"sync-bot/gitee"
+)
+
+// names of templates
+const (
+	greetingTmpl         = "greeting"
+	replySyncTmpl        = "replySync"
+	syncPRBodyTmpl       = "syncPRBody"
+	syncKernelPRBodyTmpl = "syncKernelPRBody"
+	syncResultTmpl       = "syncResult"
+	replyCloseTmpl       = "replyClose"
+	syncStatusTmpl       = "syncStatus"
+	syncHelpTmpl         = "syncHelp"
 )
 
+// repoTemplateDir is the directory of the templates in repositories.
+const repoTemplateDir = ".sync-bot/templates"
+
 const (
-	replySyncCheck = `
+	replySyncCheckZh = `
 当前仓库存在以下 __保护分支__ ：
-| Protected Branch | Version | Release |
-|---|---|---|
-{{- range .}}
-|{{.Name}}|{{.Version}}|{{.Release}}|
+| Protected Branch | Version | Release | EVR | Latest Changelog |
+|---|---|---|---|---|
+{{- range .Branches}}
+|{{.Name}}|{{if .NoSpec}}_无 spec 文件_|-|-|-{{else if .SpecError}}_获取 spec 文件失败_|-|-|-{{else}}{{.Version}}|{{.Release}}|{{.EVR}}{{if .Older}} (落后){{else if .Newer}} (__较新，同步将导致降级__){{end}}|{{.Changelog}}{{end}}|
+{{- end}}
+{{- range .Branches}}{{if .OnlyPatches}}
+
+> {{.Name}} 存在目标分支没有的补丁：{{range $i, $p := .OnlyPatches}}{{if $i}}, {{end}}` + "`{{$p}}`" + `{{end}}
+{{- end}}{{end}}
+{{- $older := ""}}{{range .Branches}}{{if .Older}}{{$older = print $older " " .Branch}}{{end}}{{end}}
+{{- if $older}}
+
+> 以下分支版本落后于目标分支，可评论 ` + "`/sync{{$older}}`" + ` 同步
 {{- end}}
 
 评论 ` + "`/sync <branch1> <branch2> ...`" + ` 可将当前 PR 修改同步到其它分支（创建同步 PR）：
@@ -19,11 +51,35 @@ a) 如果当前 PR 是 Open 状态，同步操作将延迟到 PR 被合并时执
 b) 如果当前 PR 已经 Merged，将立即执行同步操作
 
 > 注意：
-> 1. /sync 命令可以指定同步到多个分支，仅最后一个 /sync 命令生效
+> 1. /sync 命令可以指定同步到多个分支，{{if .UnionSync}}所有 /sync 命令指定的分支都会同步{{else}}仅最后一个 /sync 命令生效{{end}}
 > 2. 如果创建的同步 PR 不正确，可通过向同步 PR 的源分支提交轻量级 PR 完善，或使用 /close 命令关闭
+> 3. 当前 PR 合并前，可使用 ` + "`/sync-cancel [<branch> ...]`" + ` 命令取消全部或部分同步
 `
 
-	replySync = `
+	replySyncCheckEn = `
+This repository has the following protected branches:
+| Protected Branch | Version | Release | EVR | Latest Changelog |
+|---|---|---|---|---|
+{{- range .Branches}}
+|{{.Name}}|{{if .NoSpec}}_no spec_|-|-|-{{else if .SpecError}}_failed to get spec_|-|-|-{{else}}{{.Version}}|{{.Release}}|{{.EVR}}{{if .Older}} (older){{else if .Newer}} (__newer, syncing would downgrade it__){{end}}|{{.Changelog}}{{end}}|
+{{- end}}
+{{- range .Branches}}{{if .OnlyPatches}}
+
+> {{.Name}} has patches not on the target branch: {{range $i, $p := .OnlyPatches}}{{if $i}}, {{end}}` + "`{{$p}}`" + `{{end}}
+{{- end}}{{end}}
+{{- $older := ""}}{{range .Branches}}{{if .Older}}{{$older = print $older " " .Branch}}{{end}}{{end}}
+{{- if $older}}
+
+> Branches older than the target branch can be synchronized by ` + "`/sync{{$older}}`" + `
+{{- end}}
+
+Use ` + "`/sync <branch> ...`" + ` command to register the branch that the current PR changes will synchronize to.
+Once the current PR is merged, the synchronization operation will be performed.
+{{if .UnionSync}}(The branches of all comments which include valid /sync command will be synchronized.){{else}}(Only the last comment which include valid /sync command will be processed.){{end}}
+Use ` + "`/sync-cancel [<branch> ...]`" + ` command to cancel all or some of the synchronization before the current PR is merged.
+`
+
+	replySyncZh = `
 In response to [this]({{.URL}}):
 > {{.Command}}
 
@@ -35,9 +91,49 @@ In response to [this]({{.URL}}):
 {{- range .Branches}}
 |{{print .Name}}|{{print .Status}}|
 {{- end}}
+{{- if .Plan}}
+
+当前 PR 合并后，将同步到以下分支（所有 /sync 命令的合集）：{{range $i, $b := .Plan}}{{if $i}}, {{end}}` + "`{{$b}}`" + `{{end}}
+{{- end}}
 `
 
-	syncPRBody = `
+	replySyncEn = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+Receive the synchronization command.
+Sync operation will be applied to the following branch(es), if the current PR is merged:
+
+| Branch | Status |
+|---|---|
+{{- range .Branches}}
+|{{print .Name}}|{{print .Status}}|
+{{- end}}
+{{- if .Plan}}
+
+Once the current PR is merged, it will be synchronized to the branches of all /sync commands: {{range $i, $b := .Plan}}{{if $i}}, {{end}}` + "`{{$b}}`" + `{{end}}
+{{- end}}
+`
+
+	syncPRBodyZh = `
+### 1. 原始 PR：
+{{.PR}}
+
+### 2. 原始 PR 关联的 issue：
+{{- range .Issues}}
+{{.HTMLURL}}
+{{- end}}
+
+### 3. 原始 PR 包含的 commit：
+| Sha | Datetime | Message |
+|---|---|---|
+{{- range .Commits}}
+|[{{slice .Sha 0 8}}]({{.HTMLURL}})|{{.Commit.Author.Date}}|{{.Commit.Message}}|
+{{- end}}
+`
+
+	syncPRBodyEn = `
 ### 1. Origin pull request:
 {{.PR}}
 
@@ -53,7 +149,15 @@ In response to [this]({{.URL}}):
 |[{{slice .Sha 0 8}}]({{.HTMLURL}})|{{.Commit.Author.Date}}|{{.Commit.Message}}|
 {{- end}}
 `
-	syncKernelPRBody = `
+	syncKernelPRBodyZh = `
+### 1. 原始 PR：
+{{.PR}}
+
+### 2. 原始 PR 描述：
+{{.Body}}
+`
+
+	syncKernelPRBodyEn = `
 ### 1. Origin pull request:
 {{.PR}}
 
@@ -61,13 +165,34 @@ In response to [this]({{.URL}}):
 {{.Body}}
 `
 
-	syncResult = `
+	syncResultZh = `
+{{- if .Command}}
 In response to [this]({{.URL}}):
 > {{.Command}}
 
-@{{.User}}
+{{end}}@{{.User}}
+
+{{if .Rules}}根据 .sync-bot.yaml 中的同步规则，当前 PR 同步到：{{range $i, $b := .Rules}}{{if $i}}, {{end}}` + "`{{$b}}`" + `{{end}}
+
+{{end}}同步操作执行结果:
+
+| Branch | Status | Pull Request |
+|---|---|---|
+{{- range .SyncStatus}}
+|{{print .Name}}|{{print .Status}}|{{print .PR}}|
+{{- end}}
+`
+
+	syncResultEn = `
+{{- if .Command}}
+In response to [this]({{.URL}}):
+> {{.Command}}
 
-同步操作执行结果:
+{{end}}@{{.User}}
+
+{{if .Rules}}The rules of .sync-bot.yaml synchronize the PR to: {{range $i, $b := .Rules}}{{if $i}}, {{end}}` + "`{{$b}}`" + `{{end}}
+
+{{end}}The following sync operations have been performed:
 
 | Branch | Status | Pull Request |
 |---|---|---|
@@ -76,7 +201,94 @@ In response to [this]({{.URL}}):
 {{- end}}
 `
 
-	replyClose = `
+	syncStatusZh = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+{{if .Merged}}
+{{- if .Results}}
+同步创建的 PR 如下：
+
+| Branch | Pull Request | State |
+|---|---|---|
+{{- range .Results}}
+|{{.Branch}}|{{if .PR}}[#{{.Number}}]({{.PR}}){{else}}-{{end}}|{{.State}}|
+{{- end}}
+{{- else}}
+当前 PR 没有同步到其它分支。
+{{- end}}
+{{- else if .Pending}}
+当前 PR 合并后，将同步到：{{range $i, $b := .Pending}}{{if $i}}, {{end}}` + "`{{$b}}`" + `{{end}}
+{{- else}}
+当前没有待执行的同步，可评论 ` + "`/sync <branch> ...`" + ` 登记同步。
+{{- end}}
+`
+
+	syncStatusEn = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+{{if .Merged}}
+{{- if .Results}}
+The following pull requests have been created by synchronization:
+
+| Branch | Pull Request | State |
+|---|---|---|
+{{- range .Results}}
+|{{.Branch}}|{{if .PR}}[#{{.Number}}]({{.PR}}){{else}}-{{end}}|{{.State}}|
+{{- end}}
+{{- else}}
+The current PR has not been synchronized to other branches.
+{{- end}}
+{{- else if .Pending}}
+Once the current PR is merged, it will be synchronized to: {{range $i, $b := .Pending}}{{if $i}}, {{end}}` + "`{{$b}}`" + `{{end}}
+{{- else}}
+There is no synchronization to perform, use ` + "`/sync <branch> ...`" + ` command to register it.
+{{- end}}
+`
+
+	syncHelpZh = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+
+sync-bot 支持以下命令：
+
+| 命令 | 说明 |
+|---|---|
+{{- range .Commands}}
+|` + "`{{.Usage}}`" + `|{{.Description}}|
+{{- end}}
+`
+
+	syncHelpEn = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+
+sync-bot supports the following commands:
+
+| Command | Description |
+|---|---|
+{{- range .Commands}}
+|` + "`{{.Usage}}`" + `|{{.Description}}|
+{{- end}}
+`
+
+	replyCloseZh = `
+In response to [this]({{.URL}}):
+> {{.Command}}
+
+@{{.User}}
+
+关闭结果：{{.Status}}
+`
+
+	replyCloseEn = `
 In response to [this]({{.URL}}):
 > {{.Command}}
 
@@ -86,14 +298,137 @@ In response to [this]({{.URL}}):
 `
 )
 
-var (
-	replySyncCheckTmpl   = template.Must(template.New("greeting").Parse(replySyncCheck))
-	replySyncTmpl        = template.Must(template.New("replySync").Parse(replySync))
-	syncPRBodyTmpl       = template.Must(template.New("syncPRBody").Parse(syncPRBody))
-	syncPRBodyTmplKernel = template.Must(template.New("syncKernelPRBody").Parse(syncKernelPRBody))
-	syncResultTmpl       = template.Must(template.New("syncPRBody").Parse(syncResult))
-	replyCloseTmpl       = template.Must(template.New("syncPRBody").Parse(replyClose))
-)
+// greetingData is the data of the greeting, the reply of /sync-check.
+type greetingData struct {
+	Branches []branchInfo
+	// UnionSync is whether the branches of all /sync commands are synchronized
+	UnionSync bool
+}
+
+// branchInfo is a row of the greeting table.
+type branchInfo struct {
+	// Name is the link to the branch
+	Name      string
+	Branch    string
+	Version   string
+	Release   string
+	EVR       string
+	Changelog string
+	// OnlyPatches are the patches which are not on the target branch
+	OnlyPatches []string
+	// Older and Newer compare the EVR with the one of the target branch
+	Older bool
+	Newer bool
+	// NoSpec is whether no spec file is found on the branch
+	NoSpec bool
+	// SpecError is whether fetching the spec file of the branch failed
+	SpecError bool
+}
+
+type replySyncData struct {
+	URL      string
+	Command  string
+	User     string
+	Branches []branchStatus
+	// Plan is the branches of all /sync commands, if they are accumulated
+	Plan []string
+}
+
+type syncPRBodyData struct {
+	PR      string
+	Body    string
+	Issues  []gitee.Issue
+	Commits []gitee.PullRequestCommit
+}
+
+type syncKernelPRBodyData struct {
+	PR   string
+	Body string
+}
+
+type syncResultData struct {
+	URL  string
+	User string
+	// Command is empty if the synchronization is only of the rules
+	Command string
+	// Rules are the branches added by the rules of .sync-bot.yaml
+	Rules      []string
+	SyncStatus []syncStatus
+}
+
+type syncStatusData struct {
+	URL     string
+	Command string
+	User    string
+	Merged  bool
+	// Pending is the branches to be synchronized once merged
+	Pending []string
+	// Results are the pull requests created by synchronization
+	Results []syncPRStatus
+}
+
+// syncPRStatus is the state of a pull request created by synchronization.
+type syncPRStatus struct {
+	Branch string
+	Number int
+	PR     string
+	State  string
+}
+
+type syncHelpData struct {
+	URL      string
+	Command  string
+	User     string
+	Commands []commandHelp
+}
+
+// commandHelp is the usage and description of a command.
+type commandHelp struct {
+	Usage       string
+	Description string
+}
+
+type replyCloseData struct {
+	URL     string
+	Command string
+	User    string
+	Status  string
+}
+
+// sampleData is executed by templates to validate them, keyed by the names
+// of templates.
+var sampleData = map[string]interface{}{
+	greetingTmpl: greetingData{
+		Branches: []branchInfo{{Name: "master", Branch: "master", Version: "1.0", Release: "1", EVR: "1.0-1",
+			OnlyPatches: []string{"a.patch"}, Older: true}},
+		UnionSync: true,
+	},
+	replySyncTmpl: replySyncData{
+		Branches: []branchStatus{{Name: "master"}},
+		Plan:     []string{"master"},
+	},
+	syncPRBodyTmpl: syncPRBodyData{
+		Issues: []gitee.Issue{{HTMLURL: "https://example.com/issue"}},
+		Commits: []gitee.PullRequestCommit{{
+			Sha:    "0123456789abcdef0123456789abcdef01234567",
+			Commit: gitee.GitCommit{Author: gitee.GitUser{Date: time.Now()}},
+		}},
+	},
+	syncKernelPRBodyTmpl: syncKernelPRBodyData{},
+	syncResultTmpl: syncResultData{
+		Rules:      []string{"master"},
+		SyncStatus: []syncStatus{{Name: "master"}},
+	},
+	replyCloseTmpl: replyCloseData{},
+	syncStatusTmpl: syncStatusData{
+		Merged:  true,
+		Pending: []string{"master"},
+		Results: []syncPRStatus{{Branch: "master", Number: 1, PR: "https://example.com/pulls/1"}},
+	},
+	syncHelpTmpl: syncHelpData{
+		Commands: []commandHelp{{Usage: "/sync-help", Description: "help"}},
+	},
+}
 
 type branchStatus struct {
 	Name   string
@@ -104,6 +439,102 @@ type syncStatus struct {
 	Name   string
 	Status string
 	PR     string
+	// Number is the number of the created pull request, 0 if not created
+	Number int
+	// NotFound is whether the branch is not found, which is not a failure
+	NotFound bool
+}
+
+func mustParse(name, text string) *template.Template {
+	return template.Must(template.New(name).Parse(text))
+}
+
+// parseTemplate parses a user-provided template, and validates it by executing
+// it with sample data.
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(ioutil.Discard, sampleData[name]); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// Templates override the built-in templates, keyed by language and name of
+// templates. Templates of the empty language apply to all languages.
+type Templates map[Language]map[string]*template.Template
+
+// LoadTemplates loads the templates in dir, <dir>/<name>.tmpl applies to all
+// languages and <dir>/<lang>/<name>.tmpl to a language. Invalid templates are
+// logged and ignored, so that the built-in ones are used.
+func LoadTemplates(dir string) (Templates, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	langs := []Language{""}
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	t := make(Templates)
+	for _, lang := range langs {
+		for name := range sampleData {
+			path := filepath.Join(dir, string(lang), name+".tmpl")
+			text, err := ioutil.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err == nil {
+				var tmpl *template.Template
+				if tmpl, err = parseTemplate(name, string(text)); err == nil {
+					if t[lang] == nil {
+						t[lang] = make(map[string]*template.Template)
+					}
+					t[lang][name] = tmpl
+					logrus.Infof("Load template %s.", path)
+					continue
+				}
+			}
+			logrus.WithError(err).Errorf("Load template %s failed, using the built-in one.", path)
+		}
+	}
+	return t, nil
+}
+
+// lookup returns the template of lang and name, nil if not overridden.
+func (t Templates) lookup(lang Language, name string) *template.Template {
+	if tmpl := t[lang][name]; tmpl != nil {
+		return tmpl
+	}
+	return t[""][name]
+}
+
+// template returns the template of lang and name for owner/repo. Templates in
+// repoTemplateDir at ref of the repo override the templates of the server if
+// enabled by the configuration, which override the built-in ones.
+func (s *Server) template(owner, repo, ref string, lang Language, name string) *template.Template {
+	if s.Config != nil && s.Config.Repo(owner, repo).RepoTemplates {
+		for _, path := range []string{
+			repoTemplateDir + "/" + string(lang) + "/" + name + ".tmpl",
+			repoTemplateDir + "/" + name + ".tmpl",
+		} {
+			text, err := s.GiteeClient.GetTextFile(owner, repo, path, ref)
+			if err != nil {
+				continue
+			}
+			tmpl, err := parseTemplate(name, text)
+			if err != nil {
+				logrus.WithError(err).Errorf("Parse template %s in %s/%s failed, ignoring it.", path, owner, repo)
+				continue
+			}
+			return tmpl
+		}
+	}
+	if tmpl := s.Templates.lookup(lang, name); tmpl != nil {
+		return tmpl
+	}
+	return messages(lang).templates[name]
 }
 
 func executeTemplate(tmpl *template.Template, data interface{}) (string, error) {
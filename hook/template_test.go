@@ -1,10 +1,14 @@
 package hook
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"text/template"
 	"time"
 
+	"sync-bot/config"
 	"sync-bot/gitee"
 )
 
@@ -22,58 +26,75 @@ func Test_executeTemplate(t *testing.T) {
 		{
 			name: "greeting",
 			args: args{
-				tmpl: replySyncCheckTmpl,
-				data: []struct {
-					Name    string
-					Version string
-					Release string
-				}{
+				tmpl: catalogs[English].templates[greetingTmpl],
+				data: greetingData{Branches: []branchInfo{
 					{
-						Name:    "__* branch1__",
-						Version: "1.0",
-						Release: "2",
+						Name:      "__* branch1__",
+						Version:   "1.0",
+						Release:   "2",
+						EVR:       "1.0-2",
+						Changelog: "Mon Jan 01 2024 Packager - 1.0-2",
 					},
 					{
-						Name:    "branch2",
-						Version: "1.0",
-						Release: "2",
+						Name:        "branch2",
+						Version:     "1.0",
+						Release:     "2",
+						EVR:         "1:1.0-2",
+						OnlyPatches: []string{"a.patch", "b.patch"},
 					},
-				},
+				}},
 			},
 			want: `
 This repository has the following protected branches:
-| Protected Branch | Version | Release |
-|---|---|---|
-|__* branch1__| | |
-|branch2| | |
+| Protected Branch | Version | Release | EVR | Latest Changelog |
+|---|---|---|---|---|
+|__* branch1__|1.0|2|1.0-2|Mon Jan 01 2024 Packager - 1.0-2|
+|branch2|1.0|2|1:1.0-2||
+
+> branch2 has patches not on the target branch: ` + "`a.patch`, `b.patch`" + `
 
 Use ` + "`/sync <branch> ...`" + ` command to register the branch that the current PR changes will synchronize to.
 Once the current PR is merged, the synchronization operation will be performed.
 (Only the last comment which include valid /sync command will be processed.)
+Use ` + "`/sync-cancel [<branch> ...]`" + ` command to cancel all or some of the synchronization before the current PR is merged.
+`,
+			wantErr: false,
+		},
+		{
+			name: "greeting union_sync",
+			args: args{
+				tmpl: catalogs[English].templates[greetingTmpl],
+				data: greetingData{Branches: []branchInfo{{Name: "branch1", NoSpec: true}}, UnionSync: true},
+			},
+			want: `
+This repository has the following protected branches:
+| Protected Branch | Version | Release | EVR | Latest Changelog |
+|---|---|---|---|---|
+|branch1|_no spec_|-|-|-|
+
+Use ` + "`/sync <branch> ...`" + ` command to register the branch that the current PR changes will synchronize to.
+Once the current PR is merged, the synchronization operation will be performed.
+(The branches of all comments which include valid /sync command will be synchronized.)
+Use ` + "`/sync-cancel [<branch> ...]`" + ` command to cancel all or some of the synchronization before the current PR is merged.
 `,
 			wantErr: false,
 		},
 		{
 			name: "replySync",
 			args: args{
-				tmpl: replySyncTmpl,
-				data: struct {
-					URL      string
-					Command  string
-					User     string
-					Branches []branchStatus
-				}{
+				tmpl: catalogs[English].templates[replySyncTmpl],
+				data: replySyncData{
 					URL:     "https://example.com",
 					Command: "/sync hello",
 					User:    "me",
 					Branches: []branchStatus{
 						{
 							Name:   "branch1",
-							Status: branchExist,
+							Status: catalogs[English].branchExist,
 						},
 						{
 							Name:   "branch2",
-							Status: branchNonExist,
+							Status: catalogs[English].branchNonExist,
 						},
 					},
 				},
@@ -90,13 +111,47 @@ Sync operation will be applied to the following branch(es), if the current PR is
 |---|---|
 |branch1|sync operation will be performed|
 |branch2|branch not found, ignored|
+`,
+			wantErr: false,
+		},
+		{
+			name: "replySync zh",
+			args: args{
+				tmpl: catalogs[Chinese].templates[replySyncTmpl],
+				data: replySyncData{
+					URL:     "https://example.com",
+					Command: "/sync hello",
+					User:    "me",
+					Branches: []branchStatus{
+						{
+							Name:   "branch1",
+							Status: catalogs[Chinese].branchExist,
+						},
+						{
+							Name:   "branch2",
+							Status: catalogs[Chinese].branchNonExist,
+						},
+					},
+				},
+			},
+			want: `
+In response to [this](https://example.com):
+> /sync hello
+
+@me
+一旦当前 PR 被合入，以下同步操作将会执行:
+
+| Branch | Status |
+|---|---|
+|branch1|当前 PR 合并后，将创建同步 PR|
+|branch2|目标分支不存在，忽略处理|
 `,
 			wantErr: false,
 		},
 		{
 			name: "syncPRBody",
 			args: args{
-				tmpl: syncPRBodyTmpl,
+				tmpl: catalogs[English].templates[syncPRBodyTmpl],
 				data: struct {
 					PR      string
 					Issues  []gitee.Issue
@@ -153,31 +208,72 @@ http://example.com/issue3
 |---|---|---|
 |[12345678](http://example.com/commit1)|0000-01-02 03:04:05.000000006 +0000 UTC|commit1|
 |[12345678](http://example.com/commit1)|0000-01-02 03:04:05.000000006 +0000 UTC|commit1|
+`,
+			wantErr: false,
+		},
+		{
+			name: "syncPRBody zh",
+			args: args{
+				tmpl: catalogs[Chinese].templates[syncPRBodyTmpl],
+				data: syncPRBodyData{
+					PR:     "http://example.com",
+					Issues: []gitee.Issue{{HTMLURL: "http://example.com/issue1"}},
+					Commits: []gitee.PullRequestCommit{{
+						Sha:     "1234567890",
+						HTMLURL: "http://example.com/commit1",
+						Commit: gitee.GitCommit{
+							Author:  gitee.GitUser{Date: time.Date(0, 1, 2, 3, 4, 5, 6, time.UTC)},
+							Message: "commit1",
+						},
+					}},
+				},
+			},
+			want: `
+### 1. 原始 PR：
+http://example.com
+
+### 2. 原始 PR 关联的 issue：
+http://example.com/issue1
+
+### 3. 原始 PR 包含的 commit：
+| Sha | Datetime | Message |
+|---|---|---|
+|[12345678](http://example.com/commit1)|0000-01-02 03:04:05.000000006 +0000 UTC|commit1|
+`,
+			wantErr: false,
+		},
+		{
+			name: "syncKernelPRBody zh",
+			args: args{
+				tmpl: catalogs[Chinese].templates[syncKernelPRBodyTmpl],
+				data: syncKernelPRBodyData{PR: "http://example.com", Body: "fix"},
+			},
+			want: `
+### 1. 原始 PR：
+http://example.com
+
+### 2. 原始 PR 描述：
+fix
 `,
 			wantErr: false,
 		},
 		{
 			name: "syncResult",
 			args: args{
-				tmpl: syncResultTmpl,
-				data: struct {
-					URL        string
-					Command    string
-					User       string
-					SyncStatus []syncStatus
-				}{
+				tmpl: catalogs[English].templates[syncResultTmpl],
+				data: syncResultData{
 					URL:     "https://example.com",
 					Command: "/sync hello",
 					User:    "me",
 					SyncStatus: []syncStatus{
 						{
 							Name:   "branch1",
-							Status: branchNonExist,
+							Status: catalogs[English].branchNonExist,
 							PR:     "",
 						},
 						{
 							Name:   "hello",
-							Status: "Create pull request",
+							Status: catalogs[English].createdPR,
 							PR:     "https://example.com/pr/1",
 						},
 					},
@@ -195,13 +291,33 @@ The following sync operations have been performed:
 |---|---|---|
 |branch1|branch not found, ignored||
 |hello|Create pull request|https://example.com/pr/1|
+`,
+			wantErr: false,
+		},
+		{
+			name: "syncResult rules",
+			args: args{
+				tmpl: catalogs[English].templates[syncResultTmpl],
+				data: syncResultData{
+					URL:        "https://example.com",
+					User:       "me",
+					Rules:      []string{"branch1", "branch2"},
+					SyncStatus: []syncStatus{{Name: "branch1", Status: catalogs[English].createdPR}},
+				},
+			},
+			want: "@me\n\nThe rules of .sync-bot.yaml synchronize the PR to: `branch1`, `branch2`\n\n" +
+				`The following sync operations have been performed:
+
+| Branch | Status | Pull Request |
+|---|---|---|
+|branch1|Create pull request||
 `,
 			wantErr: false,
 		},
 		{
 			name: "replayClose",
 			args: args{
-				tmpl: replyCloseTmpl,
+				tmpl: catalogs[English].templates[replyCloseTmpl],
 				data: struct {
 					URL     string
 					Command string
@@ -238,3 +354,99 @@ Close the current pull request by removing the source branch.
 		})
 	}
 }
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"replyClose.tmpl":     "closed by {{.User}}",
+		"en/replyClose.tmpl":  "closed by @{{.User}}",
+		"syncResult.tmpl":     "{{range .SyncStatus}}",
+		"zh/syncPRBody.tmpl":  "{{.Unknown}}",
+		"zh/unknown.tmpl":     "unknown",
+		"replySync.tmpl.orig": "{{.User}}",
+	}
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatalf("LoadTemplates() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		lang Language
+		tmpl string
+		want string
+	}{
+		{"all languages", Chinese, replyCloseTmpl, "closed by me"},
+		{"language", English, replyCloseTmpl, "closed by @me"},
+		{"parse error", English, syncResultTmpl, ""},
+		{"execute error", Chinese, syncPRBodyTmpl, ""},
+		{"not overridden", English, replySyncTmpl, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := templates.lookup(tt.lang, tt.tmpl)
+			if tt.want == "" {
+				if tmpl != nil {
+					t.Errorf("lookup() = %v, want nil", tmpl.Name())
+				}
+				return
+			}
+			if tmpl == nil {
+				t.Fatal("lookup() = nil")
+			}
+			got, err := executeTemplate(tmpl, replyCloseData{User: "me"})
+			if err != nil || got != tt.want {
+				t.Errorf("executeTemplate() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadTemplates(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("LoadTemplates() of missing directory succeeded")
+	}
+}
+
+func TestServerTemplate(t *testing.T) {
+	client := &fakeClient{files: map[string]string{
+		"master:.sync-bot/templates/en/replyClose.tmpl": "repo en {{.User}}",
+		"master:.sync-bot/templates/replyClose.tmpl":    "repo {{.User}}",
+		"broken:.sync-bot/templates/replyClose.tmpl":    "repo {{.Broken}}",
+	}}
+	templates := Templates{"": {replyCloseTmpl: mustParse(replyCloseTmpl, "server {{.User}}")}}
+	enabled := &config.Config{Repos: map[string]config.Repo{"owner": {RepoTemplates: true}}}
+	tests := []struct {
+		name   string
+		config *config.Config
+		ref    string
+		lang   Language
+		want   string
+	}{
+		{"repo language", enabled, "master", English, "repo en me"},
+		{"repo", enabled, "master", Chinese, "repo me"},
+		{"invalid repo template", enabled, "broken", English, "server me"},
+		{"repo templates disabled", nil, "master", English, "server me"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{GiteeClient: client, Config: tt.config, Templates: templates}
+			got, err := executeTemplate(s.template("owner", "repo", tt.ref, tt.lang, replyCloseTmpl), replyCloseData{User: "me"})
+			if err != nil || got != tt.want {
+				t.Errorf("executeTemplate() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	s := &Server{GiteeClient: client}
+	if got := s.template("owner", "repo", "master", English, syncResultTmpl); got != catalogs[English].templates[syncResultTmpl] {
+		t.Errorf("template() = %v, want the built-in template", got.Name())
+	}
+}
@@ -0,0 +1,114 @@
+// Package gittest builds remote repositories for tests, which are cloned with
+// gogit.NewClient("file://<root>").
+package gittest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func init() {
+	// push to file:// remotes in-process instead of running git-receive-pack
+	client.InstallProtocol("file", server.DefaultServer)
+}
+
+// Fixture is an in-memory work tree which pushes to a local bare repo
+// "<root>/<owner>/<repo>.git". Failures end the test.
+type Fixture struct {
+	t    testing.TB
+	repo *gogit.Repository
+}
+
+// NewFixture creates the bare repo and a work tree pushing to it.
+func NewFixture(t testing.TB, root, owner, repo string) *Fixture {
+	t.Helper()
+	bare := filepath.Join(root, owner, repo+".git")
+	if _, err := gogit.PlainInit(bare, true); err != nil {
+		t.Fatalf("Init %s failed: %v", bare, err)
+	}
+	r, err := gogit.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("Init work tree failed: %v", err)
+	}
+	_, err = r.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"file://" + bare}})
+	if err != nil {
+		t.Fatalf("Create remote failed: %v", err)
+	}
+	return &Fixture{t: t, repo: r}
+}
+
+// Commit writes content to file and commits it, it returns the commit sha.
+func (f *Fixture) Commit(file, content string) string {
+	f.t.Helper()
+	w, err := f.repo.Worktree()
+	if err != nil {
+		f.t.Fatalf("Commit failed: %v", err)
+	}
+	if err := util.WriteFile(w.Filesystem, file, []byte(content), 0644); err != nil {
+		f.t.Fatalf("Commit failed: %v", err)
+	}
+	if _, err := w.Add(file); err != nil {
+		f.t.Fatalf("Commit failed: %v", err)
+	}
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	h, err := w.Commit("update "+file, &gogit.CommitOptions{Author: sig})
+	if err != nil {
+		f.t.Fatalf("Commit failed: %v", err)
+	}
+	return h.String()
+}
+
+// Branch creates a branch at HEAD.
+func (f *Fixture) Branch(name string) {
+	f.t.Helper()
+	head, err := f.repo.Head()
+	if err != nil {
+		f.t.Fatalf("Branch %s failed: %v", name, err)
+	}
+	ref := plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), head.Hash())
+	if err := f.repo.Storer.SetReference(ref); err != nil {
+		f.t.Fatalf("Branch %s failed: %v", name, err)
+	}
+}
+
+// Push pushes refspecs like "refs/heads/master:refs/pull/1/head" to the bare repo.
+func (f *Fixture) Push(specs ...string) {
+	f.t.Helper()
+	var refSpecs []config.RefSpec
+	for _, s := range specs {
+		refSpecs = append(refSpecs, config.RefSpec(s))
+	}
+	err := f.repo.Push(&gogit.PushOptions{RefSpecs: refSpecs, Force: true})
+	if err != nil && err != gogit.NoErrAlreadyUpToDate {
+		f.t.Fatalf("Push %v failed: %v", specs, err)
+	}
+}
+
+// Resolve returns the sha of a reference of the bare repo, like "refs/heads/master".
+func (f *Fixture) Resolve(ref string) (string, error) {
+	remote, err := f.repo.Remote("origin")
+	if err != nil {
+		return "", err
+	}
+	refs, err := remote.List(&gogit.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	for _, r := range refs {
+		if r.Name().String() == ref {
+			return r.Hash().String(), nil
+		}
+	}
+	return "", plumbing.ErrReferenceNotFound
+}
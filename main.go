@@ -1,13 +1,22 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
+	"sync-bot/config"
 	"sync-bot/git"
+	"sync-bot/gitea"
 	"sync-bot/gitee"
+	"sync-bot/github"
+	"sync-bot/gitlab"
 	"sync-bot/hook"
 	"sync-bot/secret"
 
@@ -26,21 +35,71 @@ func init() {
 
 type options struct {
 	//dryRun        bool   //
-	giteeToken    string //
-	port          int    //
-	webhookSecret string //
+	giteeURL      string        //
+	giteeToken    string        //
+	giteeUser     string        //
+	port          int           //
+	webhookSecret string        //
+	adminToken    string        //
+	cacheSize     int64         //
+	gcInterval    time.Duration //
+	configFile    string        //
+	templates     string        //
+	githubToken   string        //
+	githubUser    string        //
+	githubSecret  string        //
+	gitlabURL     string        //
+	gitlabToken   string        //
+	gitlabUser    string        //
+	gitlabSecret  string        //
+	giteaURL      string        //
+	giteaUser     string        //
+	giteaToken    string        //
+	giteaSecret   string        //
 }
 
 func (o *options) Validate() error {
+	if o.githubToken != "" && o.githubUser == "" {
+		return errors.New("--github-user is required with --github-token")
+	}
+	if o.gitlabToken != "" && o.gitlabUser == "" {
+		return errors.New("--gitlab-user is required with --gitlab-token")
+	}
+	if o.giteaToken != "" && (o.giteaURL == "" || o.giteaUser == "") {
+		return errors.New("--gitea-url and --gitea-user are required with --gitea-token")
+	}
+	for _, u := range []string{o.giteeURL, o.gitlabURL, o.giteaURL} {
+		if _, err := url.Parse(u); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func gatherOptions(fs *flag.FlagSet, args ...string) options {
 	var o options
 	//fs.BoolVar(&o.dryRun, "dry-run", true, "Dry run for testing. Uses API tokens but does not mutate.")
+	fs.StringVar(&o.giteeURL, "gitee-url", gitee.DefaultURL, "URL of the Gitee instance, the API, git and web links are derived from it.")
 	fs.StringVar(&o.giteeToken, "gitee-token", "token.conf", "Path to the file containing the Gitee token.")
+	fs.StringVar(&o.giteeUser, "gitee-user", "openeuler-sync-bot", "User of the Gitee token, only the sync records in its comments are trusted.")
 	fs.IntVar(&o.port, "port", 8765, "Port to listen on.")
 	fs.StringVar(&o.webhookSecret, "webhook-secret", "secret.conf", "Path to the file containing the Gitee Webhook secret.")
+	fs.StringVar(&o.adminToken, "admin-token", "", "Path to the file containing the token of the admin API, the API is disabled if empty.")
+	fs.Int64Var(&o.cacheSize, "cache-size", 0, "Disk budget of the repo cache in MiB, 0 means no limit.")
+	fs.StringVar(&o.configFile, "config", "sync-bot.yaml", "Path to the configuration file.")
+	fs.StringVar(&o.templates, "templates", "", "Directory of the templates overriding the built-in ones.")
+	fs.StringVar(&o.githubToken, "github-token", "", "Path to the file containing the GitHub token, GitHub is disabled if empty.")
+	fs.StringVar(&o.githubUser, "github-user", "", "User of the GitHub token, only the sync records in its comments are trusted.")
+	fs.StringVar(&o.githubSecret, "github-webhook-secret", "", "Path to the file containing the GitHub Webhook secret.")
+	fs.StringVar(&o.gitlabURL, "gitlab-url", "https://gitlab.com", "URL of the GitLab instance.")
+	fs.StringVar(&o.gitlabToken, "gitlab-token", "", "Path to the file containing the GitLab token, GitLab is disabled if empty.")
+	fs.StringVar(&o.gitlabUser, "gitlab-user", "", "User of the GitLab token, only the sync records in its comments are trusted.")
+	fs.StringVar(&o.gitlabSecret, "gitlab-webhook-secret", "", "Path to the file containing the GitLab Webhook secret.")
+	fs.StringVar(&o.giteaURL, "gitea-url", "", "URL of the Gitea or Forgejo instance.")
+	fs.StringVar(&o.giteaUser, "gitea-user", "", "User of the Gitea token.")
+	fs.StringVar(&o.giteaToken, "gitea-token", "", "Path to the file containing the Gitea token, Gitea is disabled if empty.")
+	fs.StringVar(&o.giteaSecret, "gitea-webhook-secret", "", "Path to the file containing the Gitea Webhook secret.")
+	fs.DurationVar(&o.gcInterval, "gc-interval", 24*time.Hour, "Interval of the repo cache maintenance, 0 disables it.")
 	_ = fs.Parse(args)
 	return o
 }
@@ -51,27 +110,145 @@ func main() {
 		logrus.WithError(err).Fatal("Invalid options")
 	}
 
-	err := secret.LoadSecrets([]string{o.giteeToken, o.webhookSecret})
+	cfg, err := config.Load(o.configFile)
 	if err != nil {
-		logrus.WithError(err).Fatal("Load secret failed.")
+		logrus.WithError(err).Fatal("Load config failed.")
 	}
 
-	gitClient, err := git.NewClient()
+	secrets := []string{o.giteeToken, o.webhookSecret}
+	if o.adminToken != "" {
+		secrets = append(secrets, o.adminToken)
+	}
+	if o.githubToken != "" {
+		secrets = append(secrets, o.githubToken, o.githubSecret)
+	}
+	if o.gitlabToken != "" {
+		secrets = append(secrets, o.gitlabToken, o.gitlabSecret)
+	}
+	if o.giteaToken != "" {
+		secrets = append(secrets, o.giteaToken, o.giteaSecret)
+	}
+	secrets = append(secrets, cfg.Secrets()...)
+	err = secret.LoadSecrets(secrets)
 	if err != nil {
-		logrus.WithError(err).Fatalf("New git client failed: %v", err)
+		logrus.WithError(err).Fatal("Load secret failed.")
 	}
-	// TODO: user must be configurable
-	gitClient.SetCredentials("openeuler-sync-bot", secret.GetGenerator(o.giteeToken))
+
+	var templates hook.Templates
+	if o.templates != "" {
+		templates, err = hook.LoadTemplates(o.templates)
+		if err != nil {
+			logrus.WithError(err).Errorln("Load templates failed, using the built-in ones.")
+		}
+	}
+
+	provider := &hook.GiteeProvider{Secret: secret.GetGenerator(o.webhookSecret), Web: o.giteeURL}
+	gitClient := newGitClient(cfg, o, provider, o.giteeURL, o.giteeUser, secret.GetGenerator(o.giteeToken))
 
 	server := hook.Server{
 		GitClient:   gitClient,
-		GiteeClient: gitee.NewClient(secret.GetGenerator(o.giteeToken)),
+		GiteeClient: gitee.NewClientWithURL(secret.GetGenerator(o.giteeToken), o.giteeURL),
 		Secret:      secret.GetGenerator(o.webhookSecret),
+		AdminToken:  secret.GetGenerator(o.adminToken),
+		Provider:    provider,
+		Config:      cfg,
+		Templates:   templates,
+		Bot:         o.giteeUser,
 	}
 	restful.Add(server.WebService())
+	restful.Add(server.AdminService())
+	if o.githubToken != "" {
+		token := secret.GetGenerator(o.githubToken)
+		client := github.NewClient(token)
+		provider := github.NewProvider(client, secret.GetGenerator(o.githubSecret))
+		// any user name works with GitHub tokens
+		serveProvider(cfg, templates, newGitClient(cfg, o, provider, "https://github.com", "x-access-token", token), client,
+			provider, o.githubUser)
+	}
+	if o.gitlabToken != "" {
+		token := secret.GetGenerator(o.gitlabToken)
+		provider := gitlab.NewProvider(secret.GetGenerator(o.gitlabSecret), o.gitlabURL)
+		// GitLab accepts tokens with the user oauth2
+		serveProvider(cfg, templates, newGitClient(cfg, o, provider, o.gitlabURL, "oauth2", token),
+			gitlab.NewClient(token, o.gitlabURL), provider, o.gitlabUser)
+	}
+	if o.giteaToken != "" {
+		token := secret.GetGenerator(o.giteaToken)
+		provider := gitea.NewProvider(secret.GetGenerator(o.giteaSecret), o.giteaURL)
+		serveProvider(cfg, templates, newGitClient(cfg, o, provider, o.giteaURL, o.giteaUser, token),
+			gitea.NewClient(token, o.giteaURL), provider, o.giteaUser)
+	}
+	closeOnSignal()
 	port := ":" + strconv.Itoa(o.port)
 	logrus.WithFields(logrus.Fields{
 		"Option": o,
 	}).Infoln("Listen...")
 	logrus.Fatal(http.ListenAndServe(port, nil))
 }
+
+// gitClients are the git clients created by newGitClient, which are closed on
+// shutdown.
+var gitClients []*git.Client
+
+// closeOnSignal closes the git clients and exits on SIGINT or SIGTERM, which
+// removes the SSH keys they have written.
+func closeOnSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-ch
+		logrus.Infof("Received %v, shutting down.", sig)
+		for _, c := range gitClients {
+			if err := c.Close(); err != nil {
+				logrus.WithError(err).Warnln("Close git client failed.")
+			}
+		}
+		os.Exit(0)
+	}()
+}
+
+// newGitClient creates the git client of the server at base of provider,
+// configured by cfg.
+func newGitClient(cfg *config.Config, o options, provider hook.Provider, base, user string, token func() []byte) *git.Client {
+	host := hostOf(base)
+	gitClient, err := git.NewClientWithURL(base)
+	if err != nil {
+		logrus.WithError(err).Fatalf("New git client failed: %v", err)
+	}
+	gitClient.SetCredentials(user, token)
+	gitClient.SetPullRequestRef(provider.PullRequestRef())
+	gitClient.SetCacheSize(o.cacheSize << 20)
+	gitClient.StartMaintenance(o.gcInterval, nil)
+	gitClient.SetCloneOptions(func(owner, repo string) git.CloneOptions {
+		c := cfg.Repo(owner, repo).Clone
+		return git.CloneOptions{Mode: git.CloneMode(c.Mode), Depth: c.Depth}
+	})
+	gitClient.SetTransport(func(owner, repo string) git.Transport {
+		t := cfg.Transport(host, owner, repo)
+		return git.Transport{Protocol: git.Protocol(t.Protocol), Key: secret.GetGenerator(t.Key), KnownHosts: t.KnownHosts,
+			TrustOnFirstUse: t.TrustOnFirstUse}
+	})
+	gitClients = append(gitClients, gitClient)
+	return gitClient
+}
+
+// serveProvider serves the webhook of a provider other than Gitee, bot is the
+// user of the token.
+func serveProvider(cfg *config.Config, templates hook.Templates, gitClient git.Backend, client gitee.Client,
+	provider hook.Provider, bot string) {
+	server := hook.Server{
+		GitClient:   gitClient,
+		GiteeClient: client,
+		Provider:    provider,
+		Config:      cfg,
+		Templates:   templates,
+		Bot:         bot,
+	}
+	restful.Add(server.WebService())
+}
+
+// hostOf returns the host of a validated URL.
+func hostOf(rawURL string) string {
+	u, _ := url.Parse(rawURL)
+	return u.Host
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_gatherOptions(t *testing.T) {
@@ -25,6 +26,15 @@ func Test_gatherOptions(t *testing.T) {
 				o.giteeToken = "/random/value"
 			},
 		},
+		{
+			name: "explicitly set --gitee-user",
+			args: map[string]string{
+				"--gitee-user": "sync-bot",
+			},
+			expected: func(o *options) {
+				o.giteeUser = "sync-bot"
+			},
+		},
 		{
 			name: "explicitly set --webhook-secret",
 			args: map[string]string{
@@ -34,13 +44,25 @@ func Test_gatherOptions(t *testing.T) {
 				o.webhookSecret = "/random/value"
 			},
 		},
+		{
+			name: "--github-token requires --github-user",
+			args: map[string]string{
+				"--github-token": "/random/value",
+			},
+			err: true,
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			expected := &options{
 				port:          8765,
+				giteeURL:      "https://gitee.com",
 				giteeToken:    "token.conf",
+				giteeUser:     "openeuler-sync-bot",
 				webhookSecret: "secret.conf",
+				gcInterval:    24 * time.Hour,
+				configFile:    "sync-bot.yaml",
+				gitlabURL:     "https://gitlab.com",
 			}
 			if tc.expected != nil {
 				tc.expected(expected)
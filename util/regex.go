@@ -2,6 +2,7 @@ package util
 
 import (
 	"regexp"
+	"strings"
 )
 
 var (
@@ -9,8 +10,22 @@ var (
 	titleRegex = regexp.MustCompile(`^(\[sync-bot\]|\[sync\])`)
 	// just /sync-check
 	syncCheckRegex = regexp.MustCompile(`^\s*/sync-check\s*$`)
-	// like "/sync new_branch branch-1.0 foo/bar"
-	syncRegex = regexp.MustCompile(`^\s*/sync([ \t]+[\w\./_-]+)+\s*$`)
+	// like "/sync new_branch branch-1.0 foo/bar", or with patterns and aliases
+	// like "/sync openEuler-22.03-LTS* @maintained-lts"
+	syncRegex = regexp.MustCompile(`^\s*/sync([ \t]+[\w\./_*?\[\]@-]+)+\s*$`)
+	// like "/sync-cancel" or "/sync-cancel branch-1.0 foo/bar"
+	syncCancelRegex = regexp.MustCompile(`^\s*/sync-cancel((?:[ \t]+[\w\./_*?\[\]@-]+)*)\s*$`)
+	// like "/sync-retry" or "/sync-retry branch-1.0 foo/bar", with patterns
+	// and aliases like /sync
+	syncRetryRegex = regexp.MustCompile(`^\s*/sync-retry((?:[ \t]+[\w\./_*?\[\]@-]+)*)\s*$`)
+	// just /sync-status
+	syncStatusRegex = regexp.MustCompile(`^\s*/sync-status\s*$`)
+	// just /sync-help
+	syncHelpRegex = regexp.MustCompile(`^\s*/sync-help\s*$`)
+	// the first word of comments starting with /sync, like "/sync-chek"
+	syncPrefixRegex = regexp.MustCompile(`^\s*(/sync[^\s]*)`)
+	// like "/sync-lang en"
+	syncLangRegex = regexp.MustCompile(`^\s*/sync-lang\s+([\w-]+)\s*$`)
 	// /close
 	closeRegex = regexp.MustCompile(`^\s*/close\s*$`)
 	// sync branch name like "sync-pr103-master-to-openEuler-20.03-LTS"
@@ -34,6 +49,72 @@ func MatchSyncCheck(content string) bool {
 	return syncCheckRegex.MatchString(content)
 }
 
+// MatchSyncRetry match SyncRetry command
+func MatchSyncRetry(content string) bool {
+	return syncRetryRegex.MatchString(content)
+}
+
+// ParseSyncRetry returns the branches of SyncRetry command, which is empty
+// if all failed branches are retried, or nil if content is not a SyncRetry
+// command
+func ParseSyncRetry(content string) []string {
+	m := syncRetryRegex.FindStringSubmatch(content)
+	if m == nil {
+		return nil
+	}
+	return strings.Fields(m[1])
+}
+
+// MatchSyncStatus match SyncStatus command
+func MatchSyncStatus(content string) bool {
+	return syncStatusRegex.MatchString(content)
+}
+
+// MatchSyncHelp match SyncHelp command
+func MatchSyncHelp(content string) bool {
+	return syncHelpRegex.MatchString(content)
+}
+
+// ParseSyncPrefix returns the first word of content if it starts with /sync,
+// like "/sync-chek", or empty otherwise
+func ParseSyncPrefix(content string) string {
+	m := syncPrefixRegex.FindStringSubmatch(content)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// MatchSyncLang match SyncLang command
+func MatchSyncLang(content string) bool {
+	return syncLangRegex.MatchString(content)
+}
+
+// ParseSyncLang returns the language of SyncLang command, or empty if content
+// is not a SyncLang command
+func ParseSyncLang(content string) string {
+	m := syncLangRegex.FindStringSubmatch(content)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// MatchSyncCancel match SyncCancel command
+func MatchSyncCancel(content string) bool {
+	return syncCancelRegex.MatchString(content)
+}
+
+// ParseSyncCancel returns the branches of SyncCancel command, which is empty
+// if all branches are cancelled, or nil if content is not a SyncCancel command
+func ParseSyncCancel(content string) []string {
+	m := syncCancelRegex.FindStringSubmatch(content)
+	if m == nil {
+		return nil
+	}
+	return strings.Fields(m[1])
+}
+
 // MatchClose match close command
 func MatchClose(content string) bool {
 	return closeRegex.MatchString(content)
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -67,6 +68,13 @@ func TestMatchSync(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"patterns and aliases",
+			args{
+				"/sync openEuler-22.03-LTS* openEuler-2?.03 branch[12] @maintained-lts",
+			},
+			true,
+		},
 		{
 			"no branch",
 			args{
@@ -145,6 +153,141 @@ func TestMatchSyncCheck(t *testing.T) {
 	}
 }
 
+func TestParseSyncRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		match    bool
+		branches []string
+	}{
+		{"all failed branches", "/sync-retry", true, []string{}},
+		{"some branches", " /sync-retry branch-1.0\tfoo/bar\n", true, []string{"branch-1.0", "foo/bar"}},
+		{"patterns and aliases", "/sync-retry openEuler-2?.03* @lts", true, []string{"openEuler-2?.03*", "@lts"}},
+		{"sync command", "/sync retry", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSyncRetry(tt.content); got != tt.match {
+				t.Errorf("MatchSyncRetry() = %v, want %v", got, tt.match)
+			}
+			if got := ParseSyncRetry(tt.content); !reflect.DeepEqual(got, tt.branches) {
+				t.Errorf("ParseSyncRetry() = %v, want %v", got, tt.branches)
+			}
+		})
+	}
+}
+
+func TestMatchSyncStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"exact match", "/sync-status", true},
+		{"include whitespace", " \t/sync-status \n ", true},
+		{"with arguments", "/sync-status branch1", false},
+		{"other words", "what is /sync-status", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSyncStatus(tt.content); got != tt.want {
+				t.Errorf("MatchSyncStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSyncHelp(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"exact match", "/sync-help", true},
+		{"include whitespace", " \t/sync-help \n ", true},
+		{"with arguments", "/sync-help sync", false},
+		{"other words", "what is /sync-help", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSyncHelp(tt.content); got != tt.want {
+				t.Errorf("MatchSyncHelp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyncPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"misspelled", "/sync-chek", "/sync-chek"},
+		{"separator", " /sync:branch1", "/sync:branch1"},
+		{"arguments", "/sync-retyr branch1", "/sync-retyr"},
+		{"other command", "/close", ""},
+		{"other words", "please /sync", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSyncPrefix(tt.content); got != tt.want {
+				t.Errorf("ParseSyncPrefix() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSyncLang(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"exact match", "/sync-lang en", "en"},
+		{"include whitespace", " \t/sync-lang \tzh \n ", "zh"},
+		{"region", "/sync-lang zh-CN", "zh-CN"},
+		{"no language", "/sync-lang", ""},
+		{"multiple languages", "/sync-lang en zh", ""},
+		{"sync command", "/sync lang", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseSyncLang(tt.content); got != tt.want {
+				t.Errorf("ParseSyncLang() = %v, want %v", got, tt.want)
+			}
+			if got := MatchSyncLang(tt.content); got != (tt.want != "") {
+				t.Errorf("MatchSyncLang() = %v, want %v", got, tt.want != "")
+			}
+		})
+	}
+}
+
+func TestParseSyncCancel(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		match    bool
+		branches []string
+	}{
+		{"all branches", "/sync-cancel", true, []string{}},
+		{"include whitespace", " \t/sync-cancel \n ", true, []string{}},
+		{"some branches", "/sync-cancel branch-1.0 foo/bar", true, []string{"branch-1.0", "foo/bar"}},
+		{"sync command", "/sync cancel", false, nil},
+		{"other words", "please /sync-cancel", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchSyncCancel(tt.content); got != tt.match {
+				t.Errorf("MatchSyncCancel() = %v, want %v", got, tt.match)
+			}
+			if got := ParseSyncCancel(tt.content); !reflect.DeepEqual(got, tt.branches) {
+				t.Errorf("ParseSyncCancel() = %v, want %v", got, tt.branches)
+			}
+		})
+	}
+}
+
 func TestMatchSyncBranch(t *testing.T) {
 	type args struct {
 		content string
@@ -0,0 +1,259 @@
+package rpm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// value is the value of an expression, either an integer or a string.
+type value struct {
+	isStr bool
+	str   string
+	num   int
+}
+
+func (v value) String() string {
+	if v.isStr {
+		return v.str
+	}
+	return strconv.Itoa(v.num)
+}
+
+func (v value) isTrue() bool {
+	if v.isStr {
+		return v.str != ""
+	}
+	return v.num != 0
+}
+
+// exprParser evaluates the expressions of %if and %[...], the macros in
+// the expression must have been expanded.
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+// evalExpr evaluates an expression like `0%{?openEuler} && "%{_arch}" == "x86_64"`
+// after expansion.
+func evalExpr(expr string) (value, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return value{}, err
+	}
+	if len(tokens) == 0 {
+		return value{}, fmt.Errorf("empty expression")
+	}
+	p := &exprParser{tokens: tokens}
+	v, err := p.or()
+	if err != nil {
+		return value{}, err
+	}
+	if p.pos != len(p.tokens) {
+		return value{}, fmt.Errorf("unexpected %q in expression %q", p.tokens[p.pos], expr)
+	}
+	return v, nil
+}
+
+func tokenize(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			j := strings.IndexByte(expr[i+1:], '"')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string in expression %q", expr)
+			}
+			tokens = append(tokens, expr[i:i+j+2])
+			i += j + 2
+		case c >= '0' && c <= '9':
+			j := i
+			for j < len(expr) && expr[j] >= '0' && expr[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		case strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||") ||
+			strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!=") ||
+			strings.HasPrefix(expr[i:], "<=") || strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case strings.IndexByte("()!<>+-*/", c) >= 0:
+			tokens = append(tokens, expr[i:i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("bad character %q in expression %q", c, expr)
+		}
+	}
+	return tokens, nil
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) or() (value, error) {
+	v, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var r value
+		if r, err = p.and(); err == nil {
+			v = boolValue(v.isTrue() || r.isTrue())
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) and() (value, error) {
+	v, err := p.compare()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var r value
+		if r, err = p.compare(); err == nil {
+			v = boolValue(v.isTrue() && r.isTrue())
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) compare() (value, error) {
+	l, err := p.add()
+	if err != nil {
+		return l, err
+	}
+	op := p.peek()
+	switch op {
+	case "==", "!=", "<", ">", "<=", ">=":
+	default:
+		return l, nil
+	}
+	p.pos++
+	r, err := p.add()
+	if err != nil {
+		return r, err
+	}
+	if l.isStr != r.isStr {
+		return value{}, fmt.Errorf("types of %v and %v mismatch", l, r)
+	}
+	var c int
+	if l.isStr {
+		c = strings.Compare(l.str, r.str)
+	} else if l.num < r.num {
+		c = -1
+	} else if l.num > r.num {
+		c = 1
+	}
+	switch op {
+	case "==":
+		return boolValue(c == 0), nil
+	case "!=":
+		return boolValue(c != 0), nil
+	case "<":
+		return boolValue(c < 0), nil
+	case ">":
+		return boolValue(c > 0), nil
+	case "<=":
+		return boolValue(c <= 0), nil
+	default:
+		return boolValue(c >= 0), nil
+	}
+}
+
+func (p *exprParser) add() (value, error) {
+	v, err := p.mul()
+	for err == nil && (p.peek() == "+" || p.peek() == "-") {
+		op := p.tokens[p.pos]
+		p.pos++
+		var r value
+		if r, err = p.mul(); err != nil {
+			break
+		}
+		switch {
+		case op == "+" && v.isStr && r.isStr:
+			v.str += r.str
+		case v.isStr || r.isStr:
+			err = fmt.Errorf("bad operand of %s", op)
+		case op == "+":
+			v.num += r.num
+		default:
+			v.num -= r.num
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) mul() (value, error) {
+	v, err := p.unary()
+	for err == nil && (p.peek() == "*" || p.peek() == "/") {
+		op := p.tokens[p.pos]
+		p.pos++
+		var r value
+		if r, err = p.unary(); err != nil {
+			break
+		}
+		switch {
+		case v.isStr || r.isStr:
+			err = fmt.Errorf("bad operand of %s", op)
+		case op == "*":
+			v.num *= r.num
+		case r.num == 0:
+			err = fmt.Errorf("division by zero")
+		default:
+			v.num /= r.num
+		}
+	}
+	return v, err
+}
+
+func (p *exprParser) unary() (value, error) {
+	switch p.peek() {
+	case "!":
+		p.pos++
+		v, err := p.unary()
+		return boolValue(!v.isTrue()), err
+	case "-":
+		p.pos++
+		v, err := p.unary()
+		if err == nil && v.isStr {
+			err = fmt.Errorf("bad operand of -")
+		}
+		v.num = -v.num
+		return v, err
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (value, error) {
+	t := p.peek()
+	p.pos++
+	switch {
+	case t == "":
+		return value{}, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		v, err := p.or()
+		if err == nil && p.peek() != ")" {
+			err = fmt.Errorf("missing )")
+		}
+		p.pos++
+		return v, err
+	case t[0] == '"':
+		return value{isStr: true, str: t[1 : len(t)-1]}, nil
+	case t[0] >= '0' && t[0] <= '9':
+		n, err := strconv.Atoi(t)
+		return value{num: n}, err
+	}
+	return value{}, fmt.Errorf("unexpected %q", t)
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{num: 1}
+	}
+	return value{}
+}
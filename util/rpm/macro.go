@@ -0,0 +1,212 @@
+package rpm
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxDepth limits the recursion of macro expansion.
+const maxDepth = 64
+
+// defaultMacros are defined before the macros of a spec.
+var defaultMacros = map[string]string{
+	"nil":   "",
+	"_arch": "x86_64",
+	"_os":   "linux",
+}
+
+// luaPrint matches the print calls supported in %{lua:...}, which print a
+// string literal or the expansion of a string literal.
+var luaPrint = regexp.MustCompile(`print\(\s*(?:"((?:[^"\\]|\\.)*)"|rpm\.expand\(\s*"((?:[^"\\]|\\.)*)"\s*\))\s*\)`)
+
+// macros are rpm macros keyed by name.
+type macros map[string]string
+
+// expand expands the macros in s, undefined macros are kept as is.
+func (m macros) expand(s string) string {
+	return m.expandDepth(s, 0)
+}
+
+func (m macros) expandDepth(s string, depth int) string {
+	if depth > maxDepth || !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '%' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		switch c := s[i+1]; {
+		case c == '%':
+			b.WriteByte('%')
+			i += 2
+		case c == '{' || c == '[' || c == '(':
+			end := closing(s, i+1)
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			body := s[i+2 : end]
+			switch c {
+			case '{':
+				b.WriteString(m.expandBraced(body, s[i:end+1], depth))
+			case '[':
+				if v, err := evalExpr(m.expandDepth(body, depth+1)); err == nil {
+					b.WriteString(v.String())
+				} else {
+					b.WriteString(s[i : end+1])
+				}
+			default:
+				// shell commands are not run
+				b.WriteString(s[i : end+1])
+			}
+			i = end + 1
+		case isNameChar(c) && !(c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			if v, ok := m[s[i+1:j]]; ok {
+				b.WriteString(m.expandDepth(v, depth+1))
+			} else {
+				b.WriteString(s[i:j])
+			}
+			i = j
+		default:
+			b.WriteByte('%')
+			i++
+		}
+	}
+	return b.String()
+}
+
+// expandBraced expands the macro %{body}, raw is returned if the macro is
+// undefined.
+func (m macros) expandBraced(body, raw string, depth int) string {
+	negate, conditional := false, false
+	for len(body) > 0 && (body[0] == '!' || body[0] == '?') {
+		if body[0] == '!' {
+			negate = !negate
+		} else {
+			conditional = true
+		}
+		body = body[1:]
+	}
+	name, arg, hasArg := body, "", false
+	if i := strings.IndexAny(body, ": \t"); i >= 0 {
+		name, arg, hasArg = body[:i], strings.TrimLeft(body[i+1:], " \t"), true
+	}
+
+	if conditional {
+		v, defined := m[name]
+		switch {
+		case hasArg && defined != negate:
+			return m.expandDepth(arg, depth+1)
+		case hasArg || negate || !defined:
+			return ""
+		default:
+			return m.expandDepth(v, depth+1)
+		}
+	}
+	if hasArg {
+		if v, ok := m.builtin(name, arg, depth); ok {
+			return v
+		}
+	}
+	if v, ok := m[name]; ok && !hasArg {
+		return m.expandDepth(v, depth+1)
+	}
+	return raw
+}
+
+// builtin expands the built-in macro name with arg.
+func (m macros) builtin(name, arg string, depth int) (string, bool) {
+	switch name {
+	case "expand":
+		return m.expandDepth(m.expandDepth(arg, depth+1), depth+1), true
+	case "quote", "shrink":
+		return strings.Join(strings.Fields(m.expandDepth(arg, depth+1)), " "), true
+	case "lower":
+		return strings.ToLower(m.expandDepth(arg, depth+1)), true
+	case "upper":
+		return strings.ToUpper(m.expandDepth(arg, depth+1)), true
+	case "len":
+		return strconv.Itoa(utf8.RuneCountInString(m.expandDepth(arg, depth+1))), true
+	case "basename":
+		return path.Base(m.expandDepth(arg, depth+1)), true
+	case "dirname":
+		return path.Dir(m.expandDepth(arg, depth+1)), true
+	case "suffix":
+		return strings.TrimPrefix(path.Ext(m.expandDepth(arg, depth+1)), "."), true
+	case "defined", "undefined":
+		_, ok := m[strings.TrimSpace(arg)]
+		return strconv.Itoa(boolValue(ok == (name == "defined")).num), true
+	case "with", "without":
+		_, ok := m["with_"+strings.TrimSpace(arg)]
+		return strconv.Itoa(boolValue(ok == (name == "with")).num), true
+	case "echo", "warn", "error":
+		return "", true
+	case "lua":
+		return m.lua(arg, depth)
+	}
+	return "", false
+}
+
+// lua evaluates the subset of lua which prints string literals.
+func (m macros) lua(script string, depth int) (string, bool) {
+	var b strings.Builder
+	for _, match := range luaPrint.FindAllStringSubmatch(script, -1) {
+		if match[2] != "" {
+			b.WriteString(m.expandDepth(unquote(match[2]), depth+1))
+		} else {
+			b.WriteString(unquote(match[1]))
+		}
+	}
+	rest := strings.Trim(luaPrint.ReplaceAllString(script, ""), " \t\n;")
+	if rest != "" {
+		return "", false
+	}
+	return b.String(), true
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return u
+	}
+	return s
+}
+
+// closing returns the index of the bracket closing the one at s[open].
+func closing(s string, open int) int {
+	var pair byte
+	switch s[open] {
+	case '{':
+		pair = '}'
+	case '[':
+		pair = ']'
+	default:
+		pair = ')'
+	}
+	level := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case s[open]:
+			level++
+		case pair:
+			level--
+			if level == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,82 @@
+package rpm
+
+import (
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	m := macros{
+		"name":      "foo",
+		"version":   "1.0",
+		"nested":    "%{name}-%{version}",
+		"recursive": "%{recursive}",
+		"with_docs": "1",
+	}
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"plain", "foo", "foo"},
+		{"braced", "%{name}", "foo"},
+		{"bare", "%name-%version", "foo-1.0"},
+		{"nested", "%{nested}", "foo-1.0"},
+		{"undefined", "%{undefined} %undefined", "%{undefined} %undefined"},
+		{"conditional", "%{?name}%{?undefined}", "foo"},
+		{"conditional text", "%{?name:yes}%{?undefined:no}", "yes"},
+		{"negated conditional", "%{!?name:no}%{!?undefined:yes}%{!?undefined}", "yes"},
+		{"nested conditional", "%{?name:%{?version:%{version}}}", "1.0"},
+		{"escaped", "%%{name} 100%", "%{name} 100%"},
+		{"recursive", "%{recursive}", "%{recursive}"},
+		{"expression", "%[1 + 2 * 3] %[\"a\" == \"a\"] %[bad]", "7 1 %[bad]"},
+		{"shell", "%(date)", "%(date)"},
+		{"with", "%{with docs}%{without docs}%{with tests}", "100"},
+		{"defined", "%{defined name}%{undefined name}", "10"},
+		{"expand", "%{expand:%%{name}}", "foo"},
+		{"functions", "%{upper:%{name}} %{basename:/a/b.tar.gz} %{suffix:b.tar.gz} %{dirname:/a/b}", "FOO b.tar.gz gz /a"},
+		{"lua", `%{lua: print("a"); print(rpm.expand("%{name}"))}`, "afoo"},
+		{"unsupported lua", "%{lua: print(os.date())}", "%{lua: print(os.date())}"},
+		{"unclosed", "%{name", "%{name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.expand(tt.s); got != tt.want {
+				t.Errorf("expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalExpr(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{"01", "1", false},
+		{"0", "0", false},
+		{"1 && 0 || 1", "1", false},
+		{"!(1 && 0)", "1", false},
+		{"2 + 3 * 4 - 10 / 5", "12", false},
+		{"-1 < 0", "1", false},
+		{`"oe" == "oe" && "a" < "b"`, "1", false},
+		{`"a" + "b"`, "ab", false},
+		{`"a" == 1`, "", true},
+		{"1 / 0", "", true},
+		{"(1", "", true},
+		{"x86_64", "", true},
+		{"", "", true},
+		{"1 2", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := evalExpr(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("evalExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("evalExpr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
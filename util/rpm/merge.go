@@ -0,0 +1,301 @@
+package rpm
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// like "Release:   3%{?dist}"
+var releaseRegex = regexp.MustCompile(`(?i)^(\s*release\s*:\s*)(.*?)\s*$`)
+
+// specFile is a spec split into the lines outside %changelog and the
+// entries of %changelog.
+type specFile struct {
+	// body has the lines outside %changelog, the %changelog line included
+	body []string
+	// entries of %changelog, the latest first
+	entries []string
+	// release is the index of the Release line in body, -1 if not found
+	release int
+}
+
+func splitSpec(content string) *specFile {
+	f := &specFile{release: -1}
+	var entry []string
+	addEntry := func() {
+		if len(entry) > 0 {
+			f.entries = append(f.entries, strings.TrimRight(strings.Join(entry, "\n"), "\n"))
+			entry = nil
+		}
+	}
+	inChangelog := false
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if m := sectionRegex.FindStringSubmatch(line); m != nil {
+			addEntry()
+			inChangelog = m[1] == "changelog"
+			f.body = append(f.body, line)
+			continue
+		}
+		if !inChangelog {
+			if f.release < 0 && releaseRegex.MatchString(line) {
+				f.release = len(f.body)
+			}
+			f.body = append(f.body, line)
+			continue
+		}
+		if strings.HasPrefix(line, "*") {
+			addEntry()
+		}
+		if len(entry) > 0 || strings.TrimSpace(line) != "" {
+			entry = append(entry, line)
+		}
+	}
+	addEntry()
+	return f
+}
+
+func (f *specFile) releaseValue() string {
+	if f.release < 0 {
+		return ""
+	}
+	return releaseRegex.FindStringSubmatch(f.body[f.release])[2]
+}
+
+func (f *specFile) setRelease(release string) {
+	if f.release >= 0 {
+		m := releaseRegex.FindStringSubmatch(f.body[f.release])
+		f.body[f.release] = m[1] + release
+	}
+}
+
+func (f *specFile) String() string {
+	var b strings.Builder
+	for _, line := range f.body {
+		b.WriteString(line)
+		b.WriteString("\n")
+		if m := sectionRegex.FindStringSubmatch(line); m == nil || m[1] != "changelog" {
+			continue
+		}
+		for i, e := range f.entries {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(e)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// BumpRelease increases the leading number of release, like "3%{?dist}" to
+// "4%{?dist}".
+func BumpRelease(release string) (string, error) {
+	i := 0
+	for i < len(release) && isDigit(release[i]) {
+		i++
+	}
+	n, err := strconv.Atoi(release[:i])
+	if err != nil {
+		return "", fmt.Errorf("release %q does not start with a number", release)
+	}
+	return strconv.Itoa(n+1) + release[i:], nil
+}
+
+// entryDate returns the date in the header of a changelog entry.
+func entryDate(entry string) time.Time {
+	fields := strings.Fields(entry)
+	if len(fields) < 5 {
+		return time.Time{}
+	}
+	t, _ := time.Parse("Jan 2 2006", strings.Join(fields[2:5], " "))
+	return t
+}
+
+// MergeSpec merges the changes from base to theirs into ours. Conflicts of
+// Release are resolved by bumping the Release of ours, and changelog entries
+// are merged by date. Other conflicts are returned as an error.
+func MergeSpec(base, ours, theirs string) (string, error) {
+	b, o, t := splitSpec(base), splitSpec(ours), splitSpec(theirs)
+	releaseBase, releaseOurs, releaseTheirs := b.releaseValue(), o.releaseValue(), t.releaseValue()
+	oldTheirs := NewSpec(theirs)
+
+	// Release is resolved after merging the other lines
+	b.setRelease(releaseOurs)
+	t.setRelease(releaseOurs)
+	body, err := merge3(b.body, o.body, t.body)
+	if err != nil {
+		return "", err
+	}
+	merged := &specFile{body: body, release: -1}
+	for i, line := range body {
+		if releaseRegex.MatchString(line) {
+			merged.release = i
+			break
+		}
+	}
+	release := releaseOurs
+	switch {
+	case NewSpec(base).Version() != oldTheirs.Version():
+		// the release starts over with a new version
+		release = releaseTheirs
+	case releaseTheirs != releaseBase:
+		if release, err = BumpRelease(releaseOurs); err != nil {
+			return "", err
+		}
+	}
+	merged.setRelease(release)
+
+	// entries added by theirs are merged by date, the headers of them are
+	// updated to the merged version and release
+	newSpec := NewSpec(merged.String())
+	oldVR := oldTheirs.Version() + "-" + oldTheirs.Release()
+	newVR := newSpec.Version() + "-" + newSpec.Release()
+	baseEntries := make(map[string]bool)
+	for _, e := range b.entries {
+		baseEntries[e] = true
+	}
+	for _, e := range t.entries {
+		if baseEntries[e] || contains(o.entries, e) {
+			continue
+		}
+		lines := strings.SplitN(e, "\n", 2)
+		if header := strings.TrimRight(lines[0], " "); strings.HasSuffix(header, oldVR) {
+			lines[0] = strings.TrimSuffix(header, oldVR) + newVR
+		}
+		merged.entries = append(merged.entries, strings.Join(lines, "\n"))
+	}
+	merged.entries = append(merged.entries, o.entries...)
+	sort.SliceStable(merged.entries, func(i, j int) bool {
+		return entryDate(merged.entries[i]).After(entryDate(merged.entries[j]))
+	})
+	return merged.String(), nil
+}
+
+// hunk replaces lines [start, end) of base with lines.
+type hunk struct {
+	start, end int
+	lines      []string
+}
+
+// diff returns the hunks changing a to b, based on the longest common
+// subsequence of lines. The common prefix and suffix are trimmed first, so the
+// LCS table only covers the changed region of the usually similar files.
+func diff(a, b []string) []hunk {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	hunks := lcsDiff(a[pre:len(a)-suf], b[pre:len(b)-suf])
+	for i := range hunks {
+		hunks[i].start += pre
+		hunks[i].end += pre
+	}
+	return hunks
+}
+
+// lcsDiff returns the hunks changing a to b with a table of the longest
+// common subsequences.
+func lcsDiff(a, b []string) []hunk {
+	// lcs[i*w+j] is the length of the LCS of a[i:] and b[j:]
+	w := len(b) + 1
+	lcs := make([]int, (len(a)+1)*w)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*w+j] = lcs[(i+1)*w+j+1] + 1
+			} else if lcs[(i+1)*w+j] >= lcs[i*w+j+1] {
+				lcs[i*w+j] = lcs[(i+1)*w+j]
+			} else {
+				lcs[i*w+j] = lcs[i*w+j+1]
+			}
+		}
+	}
+	var hunks []hunk
+	i, j, i0, j0 := 0, 0, 0, 0
+	flush := func() {
+		if i0 < i || j0 < j {
+			hunks = append(hunks, hunk{start: i0, end: i, lines: b[j0:j]})
+		}
+	}
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			flush()
+			i, j = i+1, j+1
+			i0, j0 = i, j
+		case lcs[(i+1)*w+j] >= lcs[i*w+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	i, j = len(a), len(b)
+	flush()
+	return hunks
+}
+
+// apply applies the hunks within base[start:end] to it.
+func apply(base []string, start, end int, hunks []hunk) []string {
+	var lines []string
+	pos := start
+	for _, h := range hunks {
+		lines = append(lines, base[pos:h.start]...)
+		lines = append(lines, h.lines...)
+		pos = h.end
+	}
+	return append(lines, base[pos:end]...)
+}
+
+// merge3 merges the changes from base to theirs into ours, changes of both
+// sides to the same lines conflict unless they are the same.
+func merge3(base, ours, theirs []string) ([]string, error) {
+	type sided struct {
+		hunk
+		ours bool
+	}
+	var all []sided
+	for _, h := range diff(base, ours) {
+		all = append(all, sided{h, true})
+	}
+	for _, h := range diff(base, theirs) {
+		all = append(all, sided{h, false})
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].start < all[j].start })
+
+	var merged []string
+	pos := 0
+	for i := 0; i < len(all); {
+		start, end := all[i].start, all[i].end
+		var o, t []hunk
+		for ; i < len(all) && all[i].start <= end && (all[i].start < end || start == end || all[i].start == all[i].end); i++ {
+			if all[i].end > end {
+				end = all[i].end
+			}
+			if all[i].ours {
+				o = append(o, all[i].hunk)
+			} else {
+				t = append(t, all[i].hunk)
+			}
+		}
+		merged = append(merged, base[pos:start]...)
+		oursLines, theirsLines := apply(base, start, end, o), apply(base, start, end, t)
+		switch {
+		case len(t) == 0:
+			merged = append(merged, oursLines...)
+		case len(o) == 0 || strings.Join(oursLines, "\n") == strings.Join(theirsLines, "\n"):
+			merged = append(merged, theirsLines...)
+		default:
+			return nil, fmt.Errorf("conflict at line %d", start+1)
+		}
+		pos = end
+	}
+	return append(merged, base[pos:]...), nil
+}
@@ -0,0 +1,209 @@
+package rpm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mergeBase = `Name:    foo
+Version: 1.0
+Release: 1%{?dist}
+Patch0:  0001-a.patch
+
+%description
+foo
+
+%changelog
+* Mon Jan 01 2024 Packager <p@example.com> - 1.0-1
+- init
+`
+
+func TestMergeSpec(t *testing.T) {
+	// the target branch backported a patch
+	ours := strings.NewReplacer(
+		"Release: 1%{?dist}", "Release: 2%{?dist}",
+		"Patch0:  0001-a.patch", "Patch0:  0001-a.patch\nPatch1:  0002-b.patch",
+		"%changelog\n", "%changelog\n* Wed Jan 10 2024 Other <o@example.com> - 1.0-2\n- backport b\n\n",
+	).Replace(mergeBase)
+
+	tests := []struct {
+		name    string
+		theirs  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "release and changelog",
+			theirs: strings.NewReplacer(
+				"Release: 1%{?dist}", "Release: 2%{?dist}",
+				"%description\nfoo", "%description\nfoo bar",
+				"%changelog\n", "%changelog\n* Fri Jan 05 2024 Packager <p@example.com> - 1.0-2\n- fix CVE\n  in foo\n\n",
+			).Replace(mergeBase),
+			want: `Name:    foo
+Version: 1.0
+Release: 3%{?dist}
+Patch0:  0001-a.patch
+Patch1:  0002-b.patch
+
+%description
+foo bar
+
+%changelog
+* Wed Jan 10 2024 Other <o@example.com> - 1.0-2
+- backport b
+
+* Fri Jan 05 2024 Packager <p@example.com> - 1.0-3
+- fix CVE
+  in foo
+
+* Mon Jan 01 2024 Packager <p@example.com> - 1.0-1
+- init
+`,
+		},
+		{
+			name: "new version",
+			theirs: strings.NewReplacer(
+				"Version: 1.0", "Version: 2.0",
+				"%changelog\n", "%changelog\n* Mon Feb 05 2024 Packager <p@example.com> - 2.0-1\n- update to 2.0\n\n",
+			).Replace(mergeBase),
+			want: `Name:    foo
+Version: 2.0
+Release: 1%{?dist}
+Patch0:  0001-a.patch
+Patch1:  0002-b.patch
+
+%description
+foo
+
+%changelog
+* Mon Feb 05 2024 Packager <p@example.com> - 2.0-1
+- update to 2.0
+
+* Wed Jan 10 2024 Other <o@example.com> - 1.0-2
+- backport b
+
+* Mon Jan 01 2024 Packager <p@example.com> - 1.0-1
+- init
+`,
+		},
+		{
+			name:    "conflict outside release and changelog",
+			theirs:  strings.Replace(mergeBase, "Patch0:  0001-a.patch", "Patch0:  0001-a.patch\nPatch1:  0002-c.patch", 1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeSpec(mergeBase, ours, tt.theirs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MergeSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("MergeSpec() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		wantErr            bool
+	}{
+		{"unchanged", "a b c", "a b c", "a b c", "a b c", false},
+		{"ours", "a b c", "a x c", "a b c", "a x c", false},
+		{"theirs", "a b c", "a b c", "a b y", "a b y", false},
+		{"both", "a b c d", "x b c d", "a b c y", "x b c y", false},
+		{"same change", "a b c", "a x c", "a x c", "a x c", false},
+		{"insertions", "a b", "a x b", "a b y", "a x b y", false},
+		{"deletions", "a b c", "b c", "a b", "b", false},
+		{"conflict", "a b c", "a x c", "a y c", "", true},
+		{"insertions at the same line", "a b", "a x b", "a y b", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := merge3(split(tt.base), split(tt.ours), split(tt.theirs))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("merge3() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("merge3() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+	tests := []struct {
+		name string
+		a, b string
+		want []hunk
+	}{
+		{"unchanged", "a b c", "a b c", nil},
+		{"change", "a b c", "a x c", []hunk{{start: 1, end: 2, lines: []string{"x"}}}},
+		{"insertion", "a b", "a x b", []hunk{{start: 1, end: 1, lines: []string{"x"}}}},
+		{"deletion", "a b c", "a c", []hunk{{start: 1, end: 2, lines: []string{}}}},
+		{"prefix and suffix", "a b c d e", "a x c y e", []hunk{
+			{start: 1, end: 2, lines: []string{"x"}},
+			{start: 3, end: 4, lines: []string{"y"}},
+		}},
+		{"empty", "", "a b", []hunk{{start: 0, end: 0, lines: []string{"a", "b"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diff(split(tt.a), split(tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the table of long files only covers the changed lines
+	a := make([]string, 100000)
+	for i := range a {
+		a[i] = fmt.Sprint(i)
+	}
+	b := append([]string(nil), a...)
+	b[50000] = "changed"
+	want := []hunk{{start: 50000, end: 50001, lines: []string{"changed"}}}
+	if got := diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("diff() of long files = %v, want %v", got, want)
+	}
+}
+
+func TestBumpRelease(t *testing.T) {
+	tests := []struct {
+		release string
+		want    string
+		wantErr bool
+	}{
+		{"1", "2", false},
+		{"9%{?dist}", "10%{?dist}", false},
+		{"3.oe1", "4.oe1", false},
+		{"%{release}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.release, func(t *testing.T) {
+			got, err := BumpRelease(tt.release)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("BumpRelease() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -5,65 +5,247 @@ import (
 	"strings"
 )
 
-// referred fields's key
-var keys = []string{"Version", "Release"}
+// referred fields's key, Name, Epoch, Version and Release are also defined
+// as macros like %{name}
+var keys = []string{"Name", "Epoch", "Version", "Release"}
+
+var (
+	// like "%define name value" or "%global name(o:) value"
+	defineRegex = regexp.MustCompile(`^\s*%(define|global)\s+(\w+)(?:\([^)]*\))?\s+(.*?)\s*$`)
+	// like "%undefine name"
+	undefineRegex = regexp.MustCompile(`^\s*%undefine\s+(\w+)`)
+	// like "%bcond_without docs" or "%bcond docs 1"
+	bcondRegex = regexp.MustCompile(`^\s*%(bcond_with|bcond_without|bcond)\s+(\w+)\s*(.*?)\s*$`)
+	// like "%if 0%{?openEuler}" or "%ifarch x86_64 aarch64"
+	conditionalRegex = regexp.MustCompile(`^\s*%(if|ifarch|ifnarch|ifos|ifnos|elif|elifarch|elifos|else|endif)(?:\s+(.*?))?\s*$`)
+	// like "%description -n foo"
+	sectionRegex = regexp.MustCompile(`^\s*%(package|description|prep|build|install|check|clean|files|changelog|pre|post|preun|postun|pretrans|posttrans|triggerin|triggerun|triggerpostun|verifyscripts|generate_buildrequires|conf|sourcelist|patchlist)\b`)
+	// like "Version: 1.0"
+	tagRegex = regexp.MustCompile(`^\s*(\w+)\s*:\s*(.*?)\s*$`)
+	// like "Source0" or "Patch12"
+	sourceTagRegex = regexp.MustCompile(`(?i)^(source|patch)\d*$`)
+)
 
 // Spec spec information
 type Spec struct {
 	// marcos define in spec
-	macros map[string]string
+	macros macros
 	// referred fields
 	values map[string]string
 	lines  []string
+	// files of Source and Patch tags, in order of declaration
+	sources []string
+	patches []string
+	// lines of the latest %changelog entry
+	changelog     []string
+	changelogDone bool
+}
+
+// branch is a branch of %if, %elif and %else.
+type branch struct {
+	// parent is whether the enclosing branch is active
+	parent bool
+	// active is whether the lines of the branch are parsed
+	active bool
+	// taken is whether a branch of the conditional has been active
+	taken bool
 }
 
 // NewSpec new a spec instance include information about a spec file
 func NewSpec(content string) *Spec {
+	return NewSpecWithMacros(content, nil)
+}
+
+// NewSpecWithMacros new a spec instance with macros defined before parsing,
+// such as "dist" or "_arch".
+func NewSpecWithMacros(content string, defined map[string]string) *Spec {
 	s := &Spec{
-		macros: make(map[string]string),
+		macros: make(macros),
 		values: make(map[string]string),
 	}
+	for k, v := range defaultMacros {
+		s.macros[k] = v
+	}
+	for k, v := range defined {
+		s.macros[k] = v
+	}
 	s.lines = strings.Split(content, "\n")
 	s.parse()
 	return s
 }
 
-// identify identify the macros define and load into map
-func (s *Spec) identify(line string) {
-	re := regexp.MustCompile(`^\s*(?:%define|%global)\s+(?P<key>\w+)\s+(?P<value>[^\s]+)`)
-	match := re.FindStringSubmatch(line)
-	if match != nil {
-		s.macros[match[1]] = match[2]
+// Expand expands the macros in str with the macros defined in the spec.
+func (s *Spec) Expand(str string) string {
+	return s.macros.expand(str)
+}
+
+// condition evaluates the condition of a %if, %ifarch or %ifos line.
+func (s *Spec) condition(keyword, arg string) bool {
+	arg = s.macros.expand(arg)
+	switch strings.TrimPrefix(strings.TrimPrefix(keyword, "el"), "if") {
+	case "arch", "narch":
+		return contains(strings.Fields(arg), s.macros["_arch"]) == (keyword != "ifnarch")
+	case "os", "nos":
+		return contains(strings.Fields(arg), s.macros["_os"]) == (keyword != "ifnos")
 	}
+	v, err := evalExpr(arg)
+	return err == nil && v.isTrue()
 }
 
-// expand iter spec lines and expand macros
-func (s *Spec) expand() {
-	for i := range s.lines {
-		for k, v := range s.macros {
-			re := regexp.MustCompile(`%{\??` + k + `}`)
-			s.lines[i] = re.ReplaceAllString(s.lines[i], v)
+// conditional updates the stack of branches with a conditional line.
+func (s *Spec) conditional(stack []branch, keyword, arg string) []branch {
+	active := len(stack) == 0 || stack[len(stack)-1].active
+	switch keyword {
+	case "endif":
+		if len(stack) > 0 {
+			stack = stack[:len(stack)-1]
+		}
+	case "else":
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			top.active = top.parent && !top.taken
+			top.taken = true
+		}
+	case "elif", "elifarch", "elifos":
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			top.active = top.parent && !top.taken && s.condition(keyword, arg)
+			top.taken = top.taken || top.active
 		}
-		s.identify(s.lines[i])
+	default:
+		v := active && s.condition(keyword, arg)
+		stack = append(stack, branch{parent: active, active: v, taken: v})
 	}
+	return stack
 }
 
-// extract
-func (s *Spec) extract() {
-	for _, key := range keys {
-		re := regexp.MustCompile(`^\s*` + key + `\s*:\s+([^\s]+)`)
-		for _, line := range s.lines {
-			match := re.FindStringSubmatch(line)
-			if match != nil {
-				s.values[key] = match[1]
+// define handles the macro definitions in line, lines continued by a
+// backslash are joined. It returns the number of lines consumed.
+func (s *Spec) define(i int) int {
+	line := s.lines[i]
+	if m := undefineRegex.FindStringSubmatch(line); m != nil {
+		delete(s.macros, m[1])
+		return 1
+	}
+	if m := bcondRegex.FindStringSubmatch(line); m != nil {
+		switch {
+		case m[1] == "bcond_without":
+			s.macros["with_"+m[2]] = "1"
+		case m[1] == "bcond":
+			if v, err := evalExpr(s.macros.expand(m[3])); err == nil && v.isTrue() {
+				s.macros["with_"+m[2]] = "1"
 			}
 		}
+		return 1
+	}
+	m := defineRegex.FindStringSubmatch(line)
+	if m == nil && strings.HasPrefix(strings.TrimSpace(line), "%{") {
+		// like "%{!?python3_pkgversion: %global python3_pkgversion 3}"
+		m = defineRegex.FindStringSubmatch(s.macros.expand(line))
 	}
+	if m == nil {
+		return 0
+	}
+	n := 1
+	value := m[3]
+	for strings.HasSuffix(value, "\\") && i+n < len(s.lines) {
+		value = strings.TrimSuffix(value, "\\") + "\n" + strings.TrimSpace(s.lines[i+n])
+		n++
+	}
+	if m[1] == "global" {
+		value = s.macros.expand(value)
+	}
+	s.macros[m[2]] = value
+	return n
 }
 
 func (s *Spec) parse() {
-	s.expand()
-	s.extract()
+	var stack []branch
+	section := ""
+	for i := 0; i < len(s.lines); i++ {
+		line := s.lines[i]
+		if m := conditionalRegex.FindStringSubmatch(line); m != nil {
+			stack = s.conditional(stack, m[1], m[2])
+			continue
+		}
+		if len(stack) > 0 && !stack[len(stack)-1].active {
+			continue
+		}
+		if m := sectionRegex.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			continue
+		}
+		if n := s.define(i); n > 0 {
+			i += n - 1
+			continue
+		}
+		switch section {
+		case "":
+			s.extract(line)
+		case "sourcelist", "patchlist":
+			if f := strings.TrimSpace(s.macros.expand(line)); f != "" && !strings.HasPrefix(f, "#") {
+				if section == "sourcelist" {
+					s.sources = append(s.sources, f)
+				} else {
+					s.patches = append(s.patches, f)
+				}
+			}
+		case "changelog":
+			s.changelogLine(line)
+		}
+	}
+}
+
+// extract the referred fields in a line of the preamble
+func (s *Spec) extract(line string) {
+	m := tagRegex.FindStringSubmatch(line)
+	if m == nil {
+		return
+	}
+	if m := sourceTagRegex.FindStringSubmatch(m[1]); m != nil {
+		f := strings.TrimSpace(s.macros.expand(line[strings.Index(line, ":")+1:]))
+		if strings.EqualFold(m[1], "source") {
+			s.sources = append(s.sources, f)
+		} else {
+			s.patches = append(s.patches, f)
+		}
+		return
+	}
+	for _, key := range keys {
+		if strings.EqualFold(m[1], key) {
+			v := strings.TrimSpace(s.macros.expand(m[2]))
+			s.values[key] = v
+			s.macros[strings.ToLower(key)] = v
+			return
+		}
+	}
+}
+
+// changelogLine collects the lines of the latest %changelog entry, which
+// starts with a line like "* Mon Jan 01 2024 Packager <mail> - 1.0-1".
+func (s *Spec) changelogLine(line string) {
+	line = strings.TrimSpace(line)
+	switch {
+	case s.changelogDone || line == "":
+	case strings.HasPrefix(line, "*"):
+		if len(s.changelog) > 0 {
+			// the entries following the latest one are ignored
+			s.changelogDone = true
+			return
+		}
+		s.changelog = []string{strings.TrimSpace(strings.TrimPrefix(s.macros.expand(line), "*"))}
+	case len(s.changelog) > 0:
+		s.changelog = append(s.changelog, s.macros.expand(line))
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
 }
 
 // Version get Version from spec
@@ -75,3 +257,60 @@ func (s *Spec) Version() string {
 func (s *Spec) Release() string {
 	return s.values["Release"]
 }
+
+// Name get Name from spec
+func (s *Spec) Name() string {
+	return s.values["Name"]
+}
+
+// Epoch get Epoch from spec
+func (s *Spec) Epoch() string {
+	return s.values["Epoch"]
+}
+
+// EVR get epoch:version-release from spec, the epoch is omitted if not set
+func (s *Spec) EVR() string {
+	if s.Version() == "" {
+		return ""
+	}
+	evr := s.Version() + "-" + s.Release()
+	if s.Epoch() != "" {
+		evr = s.Epoch() + ":" + evr
+	}
+	return evr
+}
+
+// Sources get the files of Source tags and %sourcelist
+func (s *Spec) Sources() []string {
+	return s.sources
+}
+
+// Patches get the files of Patch tags and %patchlist
+func (s *Spec) Patches() []string {
+	return s.patches
+}
+
+// Changelog get the header of the latest %changelog entry, like
+// "Mon Jan 01 2024 Packager <mail> - 1.0-1"
+func (s *Spec) Changelog() string {
+	if len(s.changelog) == 0 {
+		return ""
+	}
+	return s.changelog[0]
+}
+
+// ChangelogItems get the items of the latest %changelog entry, an item
+// starts with "-" and may be continued by the following lines
+func (s *Spec) ChangelogItems() []string {
+	var items []string
+	for i, line := range s.changelog {
+		switch {
+		case i == 0:
+		case strings.HasPrefix(line, "-") || len(items) == 0:
+			items = append(items, strings.TrimSpace(strings.TrimPrefix(line, "-")))
+		default:
+			items[len(items)-1] += " " + line
+		}
+	}
+	return items
+}
@@ -1,6 +1,7 @@
 package rpm
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -48,7 +49,7 @@ Version: %{upstream_version}.%{upstream_sublevel}
 Release: %{devel_release}%{?maintenance_release}%{?pkg_release}%{?extra_release}
 `,
 			},
-			want: []string{"5.10.0", "4.0.0.13%{?extra_release}"},
+			want: []string{"5.10.0", "4.0.0.13"},
 		},
 		{
 			name: "Nested macros",
@@ -68,10 +69,56 @@ Release: %{nodejs_release}
 			},
 			want: []string{"12.18.4", "4"},
 		},
+		{
+			name: "Conditionals",
+			args: args{
+				data: `
+%bcond_without docs
+%if 0%{?openEuler}
+%global vendor_release .oe
+%elif %{with docs}
+%global vendor_release .docs
+%else
+%global vendor_release .other
+%endif
+%ifarch aarch64
+Version: 2.0
+%else
+Version: 1.0
+%endif
+%if "%{?dist}" == ""
+%if 1
+Release: 0
+%endif
+%else
+Release: 1%{vendor_release}%{?dist}
+%endif
+
+%description
+Version: 3.0
+`,
+			},
+			want: []string{"1.0", "1.docs.oe2203"},
+		},
+		{
+			name: "Built-in macros",
+			args: args{
+				data: `
+Name: foo
+Version: 1.2.3
+%define major %(echo %{version} | cut -d. -f1)
+%global short %{lua: print(rpm.expand("%{version}"):sub(1, 3))}
+%global pkg %{upper:%{name}}-%{len:%{version}}
+%{!?with_python3: %global with_python3 1}
+Release: %{pkg}.%{?with_python3:py3}%{!?with_python2:py2}%[2 * 3]%{lua: print("lua")}%%
+`,
+			},
+			want: []string{"1.2.3", "FOO-5.py3py26lua%"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := NewSpec(tt.args.data)
+			s := NewSpecWithMacros(tt.args.data, map[string]string{"dist": ".oe2203"})
 			if got := s.Version(); got != tt.want[0] {
 				t.Errorf("Version() = %v, want %v", got, tt.want[0])
 			}
@@ -81,3 +128,62 @@ Release: %{nodejs_release}
 		})
 	}
 }
+
+func TestSpecMetadata(t *testing.T) {
+	data := `
+%global major 2
+Name:    foo
+Epoch:   1
+Version: %{major}.0
+Release: 3%{?dist}
+Source0: https://example.com/%{name}-%{version}.tar.gz
+source1: %{name}.service
+Patch0:  0001-fix-build.patch
+%if 0
+Patch1:  0002-disabled.patch
+%endif
+Patch2:  0003-%{name}-cve.patch
+
+%description
+Patch3:  not-a-patch.patch
+
+%patchlist
+0004-from-list.patch
+
+%changelog
+* Mon Jan 01 2024 Packager <packager@example.com> - 1:2.0-3
+- fix CVE-2024-0001
+  and CVE-2024-0002
+- update to %{version}
+
+* Sun Dec 31 2023 Packager <packager@example.com> - 1:1.0-1
+- init
+`
+	s := NewSpecWithMacros(data, map[string]string{"dist": ".oe2203"})
+	if got := s.Name(); got != "foo" {
+		t.Errorf("Name() = %v, want foo", got)
+	}
+	if got := s.Epoch(); got != "1" {
+		t.Errorf("Epoch() = %v, want 1", got)
+	}
+	if got := s.EVR(); got != "1:2.0-3.oe2203" {
+		t.Errorf("EVR() = %v, want 1:2.0-3.oe2203", got)
+	}
+	if got, want := s.Sources(), []string{"https://example.com/foo-2.0.tar.gz", "foo.service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sources() = %v, want %v", got, want)
+	}
+	if got, want := s.Patches(), []string{"0001-fix-build.patch", "0003-foo-cve.patch", "0004-from-list.patch"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Patches() = %v, want %v", got, want)
+	}
+	if got, want := s.Changelog(), "Mon Jan 01 2024 Packager <packager@example.com> - 1:2.0-3"; got != want {
+		t.Errorf("Changelog() = %v, want %v", got, want)
+	}
+	if got, want := s.ChangelogItems(), []string{"fix CVE-2024-0001 and CVE-2024-0002", "update to 2.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangelogItems() = %v, want %v", got, want)
+	}
+
+	empty := NewSpec("")
+	if empty.EVR() != "" || empty.Changelog() != "" || empty.ChangelogItems() != nil {
+		t.Errorf("metadata of empty spec = %q %q %v", empty.EVR(), empty.Changelog(), empty.ChangelogItems())
+	}
+}
@@ -0,0 +1,138 @@
+package rpm
+
+import (
+	"strconv"
+	"strings"
+)
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// Vercmp compares two versions or releases like rpmvercmp, it returns 1 if a
+// is newer, -1 if b is newer and 0 if they are equal.
+func Vercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for len(a) > 0 || len(b) > 0 {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		// "~" sorts before everything, even the end of a version
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		// "^" sorts after the end of a version, but before everything else
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			if a == "" {
+				return -1
+			}
+			if b == "" {
+				return 1
+			}
+			if !strings.HasPrefix(a, "^") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "^") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		isNum := isDigit(a[0])
+		match := isAlpha
+		if isNum {
+			match = isDigit
+		}
+		i, j := 0, 0
+		for i < len(a) && match(a[i]) {
+			i++
+		}
+		for j < len(b) && match(b[j]) {
+			j++
+		}
+		segA, segB := a[:i], b[:j]
+		a, b = a[i:], b[j:]
+		// segments of different types, numeric ones are newer
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				if len(segA) > len(segB) {
+					return 1
+				}
+				return -1
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a != "":
+		return 1
+	default:
+		return -1
+	}
+}
+
+func isSeparator(r rune) bool {
+	return r < 128 && !isDigit(byte(r)) && !isAlpha(byte(r)) && r != '~' && r != '^'
+}
+
+// splitEVR splits "epoch:version-release" into its parts, the epoch is 0 if
+// omitted.
+func splitEVR(evr string) (int, string, string) {
+	epoch := 0
+	if i := strings.Index(evr, ":"); i >= 0 {
+		epoch, _ = strconv.Atoi(evr[:i])
+		evr = evr[i+1:]
+	}
+	version, release := evr, ""
+	if i := strings.LastIndex(evr, "-"); i >= 0 {
+		version, release = evr[:i], evr[i+1:]
+	}
+	return epoch, version, release
+}
+
+// CompareEVR compares two "epoch:version-release" like rpm, it returns 1 if
+// a is newer, -1 if b is newer and 0 if they are equal. Releases are only
+// compared if both are given.
+func CompareEVR(a, b string) int {
+	epochA, versionA, releaseA := splitEVR(a)
+	epochB, versionB, releaseB := splitEVR(b)
+	switch {
+	case epochA > epochB:
+		return 1
+	case epochA < epochB:
+		return -1
+	}
+	if c := Vercmp(versionA, versionB); c != 0 || releaseA == "" || releaseB == "" {
+		return c
+	}
+	return Vercmp(releaseA, releaseB)
+}